@@ -20,8 +20,13 @@ export function useSocket({ gameId }: UseSocketProps) {
 		if (!shouldConnect.current) return
 		setIsConnecting(true)
 		setIsConnected(false)
-		// Generate a unique client ID for this session
-		const clientId = crypto.randomUUID()
+		// Keep the client ID stable per game so a reconnect resumes the same seat
+		const storageKey = `chess-client-id:${gameId}`
+		let clientId = sessionStorage.getItem(storageKey)
+		if (!clientId) {
+			clientId = crypto.randomUUID()
+			sessionStorage.setItem(storageKey, clientId)
+		}
 		const userName = "Player" // TODO: Get from user auth/profile
 		const type = "player"
 
@@ -168,4 +168,131 @@ func TestService_WithTimeout(t *testing.T) {
 	assert.True(t, strings.Contains(err.Error(), "context deadline exceeded") || 
 		strings.Contains(err.Error(), "canceling query due to user request") ||
 		strings.Contains(err.Error(), "timeout"))
-}
\ No newline at end of file
+}
+func TestService_SaveGamePly(t *testing.T) {
+	service, mock, cleanup := createMockService(t)
+	defer cleanup()
+
+	timeTaken := 1500
+	mock.ExpectExec("INSERT INTO game_moves .*black_move, black_move_san.*ON CONFLICT \\(game_id, move_number\\)").
+		WithArgs("game-1", 1, "e7e5", "e5", "fen-after", &timeTaken).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := service.SaveGamePly(context.Background(), "game-1", 1, "black", "e7e5", "e5", "fen-after", &timeTaken)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_TruncateGameMoves(t *testing.T) {
+	service, mock, cleanup := createMockService(t)
+	defer cleanup()
+
+	// Keeping up to white's second move clears black's half of move 2
+	mock.ExpectExec("DELETE FROM game_moves").
+		WithArgs("game-1", 2).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("UPDATE game_moves SET\\s+black_move = NULL").
+		WithArgs("game-1", 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := service.TruncateGameMoves(context.Background(), "game-1", 2, "white")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_GameExists(t *testing.T) {
+	service, mock, cleanup := createMockService(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM games WHERE game_id = \\$1\\)").
+		WithArgs("game-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := service.GameExists(context.Background(), "game-1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_GetOpeningByID(t *testing.T) {
+	service, mock, cleanup := createMockService(t)
+	defer cleanup()
+
+	id := uuid.New()
+	mock.ExpectQuery("SELECT \\* FROM openings\\s+WHERE id = \\$1").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "eco", "name", "pgn"}).
+			AddRow(id, "C60", "Ruy Lopez", "1. e4 e5 2. Nf3 Nc6 3. Bb5"))
+
+	opening, err := service.GetOpeningByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "Ruy Lopez", opening.Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_GetGamesByStatus(t *testing.T) {
+	service, mock, cleanup := createMockService(t)
+	defer cleanup()
+
+	now := time.Now()
+	name := "Alice"
+	tc := `{"baseMs":300000,"incrementMs":2000}`
+	mock.ExpectQuery("FROM games\\s+WHERE status = \\$1\\s+ORDER BY created_at DESC\\s+LIMIT \\$2").
+		WithArgs("waiting", 20).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "game_id", "owner_id", "game_type", "variant", "status", "fen", "start_fen", "pgn",
+			"white_player_id", "white_player_name", "black_player_id", "black_player_name",
+			"current_turn", "ai_difficulty", "winner", "outcome", "variant_state", "move_count", "time_control", "rematch_of",
+			"days_per_move", "move_deadline", "ai_settings", "white_token", "black_token", "setup_plies",
+			"spectator_delay_ms", "auto_accept_takebacks", "tournament_id",
+			"created_at", "updated_at", "completed_at",
+		}).AddRow(
+			uuid.New(), "game-1", "alice", "human_vs_human", "standard", "waiting", "fen", nil, nil,
+			"alice", &name, nil, nil,
+			"white", 0, nil, nil, nil, 0, &tc, nil,
+			nil, nil, nil, nil, nil, 0,
+			0, false, nil,
+			now, now, nil,
+		))
+
+	games, err := service.GetGamesByStatus(context.Background(), "waiting", 20)
+	require.NoError(t, err)
+	require.Len(t, games, 1)
+	assert.Equal(t, "game-1", games[0].GameID)
+	assert.Equal(t, "Alice", *games[0].WhitePlayerName)
+	assert.Nil(t, games[0].BlackPlayerID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_GetExpiredCorrespondenceGames(t *testing.T) {
+	service, mock, cleanup := createMockService(t)
+	defer cleanup()
+
+	now := time.Now()
+	days := 3
+	deadline := now.Add(-time.Hour)
+	mock.ExpectQuery("FROM games\\s+WHERE days_per_move IS NOT NULL AND status = 'in_progress' AND move_deadline < \\$1").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "game_id", "owner_id", "game_type", "variant", "status", "fen", "start_fen", "pgn",
+			"white_player_id", "white_player_name", "black_player_id", "black_player_name",
+			"current_turn", "ai_difficulty", "winner", "outcome", "variant_state", "move_count", "time_control", "rematch_of",
+			"days_per_move", "move_deadline", "ai_settings", "white_token", "black_token", "setup_plies",
+			"spectator_delay_ms", "auto_accept_takebacks", "tournament_id",
+			"created_at", "updated_at", "completed_at",
+		}).AddRow(
+			uuid.New(), "game-1", "alice", "human_vs_human", "standard", "in_progress", "fen", nil, nil,
+			"alice", nil, "bob", nil,
+			"black", 0, nil, nil, nil, 1, nil, nil,
+			&days, &deadline, nil, nil, nil, 0,
+			0, false, nil,
+			now, now, nil,
+		))
+
+	games, err := service.GetExpiredCorrespondenceGames(context.Background(), now)
+	require.NoError(t, err)
+	require.Len(t, games, 1)
+	assert.Equal(t, 3, *games[0].DaysPerMove)
+	assert.Equal(t, deadline, *games[0].MoveDeadline)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,9 +11,14 @@ import (
 type GameRow struct {
 	ID              uuid.UUID  `db:"id"`
 	GameID          string     `db:"game_id"`
+	OwnerID         *string    `db:"owner_id"`   // Player who created the game
+	RematchOf       *string    `db:"rematch_of"` // Game this one is a rematch of
 	GameType        string     `db:"game_type"`
 	Status          string     `db:"status"`
 	FEN             string     `db:"fen"`
+	Variant         string     `db:"variant"`       // "standard", "chess960", ...
+	StartFEN        *string    `db:"start_fen"`     // nil for the standard starting position
+	VariantState    *string    `db:"variant_state"` // JSON string, e.g. three-check counters
 	PGN             *string    `db:"pgn"`
 	WhitePlayerID   *string    `db:"white_player_id"`
 	WhitePlayerName *string    `db:"white_player_name"`
@@ -23,7 +29,16 @@ type GameRow struct {
 	Winner          *string    `db:"winner"`
 	Outcome         *string    `db:"outcome"`
 	MoveCount       int        `db:"move_count"`
-	TimeControl     *string    `db:"time_control"` // JSON string
+	TimeControl     *string    `db:"time_control"`  // JSON string
+	DaysPerMove     *int       `db:"days_per_move"` // Correspondence games only
+	MoveDeadline    *time.Time `db:"move_deadline"` // When the side to move runs out of time
+	AISettings      *string    `db:"ai_settings"`   // JSON string, each side's engine in an AI match
+	WhiteToken      *string    `db:"white_token"`   // Seat secrets for moving over HTTP, correspondence games only
+	BlackToken      *string    `db:"black_token"`
+	SetupPlies      int        `db:"setup_plies"` // Moves the game was set up with from a PGN or opening
+	SpectatorDelayMs    int64  `db:"spectator_delay_ms"`    // How far spectators lag behind
+	AutoAcceptTakebacks bool   `db:"auto_accept_takebacks"` // The AI grants every takeback
+	TournamentID        *string `db:"tournament_id"`        // Tournament the game was paired in
 	CreatedAt       time.Time  `db:"created_at"`
 	UpdatedAt       time.Time  `db:"updated_at"`
 	CompletedAt     *time.Time `db:"completed_at"`
@@ -60,16 +75,17 @@ type GameAnalysisRow struct {
 	CreatedAt         time.Time `db:"created_at"`
 }
 
-func (s *Service) CreateGame(ctx context.Context, gameID, gameType, status, fen string, whitePlayerID, whitePlayerName, blackPlayerID, blackPlayerName *string, currentTurn string, aiDifficulty int) error {
+func (s *Service) CreateGame(ctx context.Context, gameID, ownerID, gameType, status, fen string, whitePlayerID, whitePlayerName, blackPlayerID, blackPlayerName *string, currentTurn string, aiDifficulty int) error {
 	query := `
 		INSERT INTO games (
-			game_id, game_type, status, fen, white_player_id, white_player_name,
+			game_id, owner_id, game_type, status, fen, white_player_id, white_player_name,
 			black_player_id, black_player_name, current_turn, ai_difficulty
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		) VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 	
 	_, err := s.db.ExecContext(ctx, query,
 		gameID,
+		ownerID,
 		gameType,
 		status,
 		fen,
@@ -84,16 +100,18 @@ func (s *Service) CreateGame(ctx context.Context, gameID, gameType, status, fen
 	return err
 }
 
-func (s *Service) UpdateGame(ctx context.Context, gameID, status, fen, currentTurn string, moveCount int, winner, outcome *string, completedAt *time.Time) error {
+func (s *Service) UpdateGame(ctx context.Context, gameID, status, fen, pgn, currentTurn string, moveCount int, winner, outcome, variantState *string, completedAt *time.Time) error {
 	query := `
 		UPDATE games SET
 			status = $2,
 			fen = $3,
-			current_turn = $4,
-			move_count = $5,
-			winner = $6,
-			outcome = $7,
-			completed_at = $8,
+			pgn = $4,
+			current_turn = $5,
+			move_count = $6,
+			winner = $7,
+			outcome = $8,
+			variant_state = $9,
+			completed_at = $10,
 			updated_at = NOW()
 		WHERE game_id = $1
 	`
@@ -102,21 +120,124 @@ func (s *Service) UpdateGame(ctx context.Context, gameID, status, fen, currentTu
 		gameID,
 		status,
 		fen,
+		pgn,
 		currentTurn,
 		moveCount,
 		winner,
 		outcome,
+		variantState,
 		completedAt,
 	)
 	
 	return err
 }
 
+// UpdateGameStartPosition stores a game's variant and starting position,
+// along with how many of its moves it was set up with.
+func (s *Service) UpdateGameStartPosition(ctx context.Context, gameID, variant string, startFEN *string, setupPlies int) error {
+	query := `UPDATE games SET variant = $2, start_fen = $3, setup_plies = $4, updated_at = NOW() WHERE game_id = $1`
+	
+	_, err := s.db.ExecContext(ctx, query, gameID, variant, startFEN, setupPlies)
+	return err
+}
+
+func (s *Service) UpdateGamePlayers(ctx context.Context, gameID string, whitePlayerID, whitePlayerName, blackPlayerID, blackPlayerName *string) error {
+	query := `
+		UPDATE games SET
+			white_player_id = $2,
+			white_player_name = $3,
+			black_player_id = $4,
+			black_player_name = $5,
+			updated_at = NOW()
+		WHERE game_id = $1
+	`
+	
+	_, err := s.db.ExecContext(ctx, query,
+		gameID,
+		whitePlayerID,
+		whitePlayerName,
+		blackPlayerID,
+		blackPlayerName,
+	)
+	
+	return err
+}
+
+// UpdateGameRematchOf links a rematch to the game it follows.
+func (s *Service) UpdateGameRematchOf(ctx context.Context, gameID, rematchOf string) error {
+	query := `UPDATE games SET rematch_of = $2, updated_at = NOW() WHERE game_id = $1`
+	
+	_, err := s.db.ExecContext(ctx, query, gameID, rematchOf)
+	return err
+}
+
+func (s *Service) UpdateGameTimeControl(ctx context.Context, gameID, timeControl string) error {
+	query := `UPDATE games SET time_control = $2, updated_at = NOW() WHERE game_id = $1`
+	
+	_, err := s.db.ExecContext(ctx, query, gameID, timeControl)
+	return err
+}
+
+// UpdateGameAISettings stores the engine settings of each side of an AI
+// match, as JSON.
+func (s *Service) UpdateGameAISettings(ctx context.Context, gameID, aiSettings string) error {
+	query := `UPDATE games SET ai_settings = $2, updated_at = NOW() WHERE game_id = $1`
+	
+	_, err := s.db.ExecContext(ctx, query, gameID, aiSettings)
+	return err
+}
+
+// UpdateGameSeatTokens stores the secrets the players of a correspondence
+// game move over HTTP with.
+func (s *Service) UpdateGameSeatTokens(ctx context.Context, gameID string, whiteToken, blackToken *string) error {
+	query := `UPDATE games SET white_token = $2, black_token = $3, updated_at = NOW() WHERE game_id = $1`
+	
+	_, err := s.db.ExecContext(ctx, query, gameID, whiteToken, blackToken)
+	return err
+}
+
+// UpdateGameOptions stores how far spectators lag behind a game and whether
+// its AI grants every takeback.
+func (s *Service) UpdateGameOptions(ctx context.Context, gameID string, spectatorDelayMs int64, autoAcceptTakebacks bool) error {
+	query := `UPDATE games SET spectator_delay_ms = $2, auto_accept_takebacks = $3, updated_at = NOW() WHERE game_id = $1`
+	
+	_, err := s.db.ExecContext(ctx, query, gameID, spectatorDelayMs, autoAcceptTakebacks)
+	return err
+}
+
+// UpdateGameTournament records the tournament that paired a game.
+func (s *Service) UpdateGameTournament(ctx context.Context, gameID, tournamentID string) error {
+	query := `UPDATE games SET tournament_id = $2, updated_at = NOW() WHERE game_id = $1`
+	
+	_, err := s.db.ExecContext(ctx, query, gameID, tournamentID)
+	return err
+}
+
+// UpdateGameCorrespondence makes a game a correspondence game with
+// daysPerMove to make each move, and sets the current deadline.
+func (s *Service) UpdateGameCorrespondence(ctx context.Context, gameID string, daysPerMove int, moveDeadline *time.Time) error {
+	query := `UPDATE games SET days_per_move = $2, move_deadline = $3, updated_at = NOW() WHERE game_id = $1`
+	
+	_, err := s.db.ExecContext(ctx, query, gameID, daysPerMove, moveDeadline)
+	return err
+}
+
+// GameExists reports whether a game is stored under gameID, finished or not.
+func (s *Service) GameExists(ctx context.Context, gameID string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM games WHERE game_id = $1)`
+	
+	var exists bool
+	err := s.db.QueryRowContext(ctx, query, gameID).Scan(&exists)
+	return exists, err
+}
+
 func (s *Service) GetGame(ctx context.Context, gameID string) (*GameRow, error) {
 	query := `
-		SELECT id, game_id, game_type, status, fen, pgn,
+		SELECT id, game_id, owner_id, game_type, variant, status, fen, start_fen, pgn,
 		       white_player_id, white_player_name, black_player_id, black_player_name,
-		       current_turn, ai_difficulty, winner, outcome, move_count, time_control,
+		       current_turn, ai_difficulty, winner, outcome, variant_state, move_count, time_control, rematch_of,
+		       days_per_move, move_deadline, ai_settings, white_token, black_token, setup_plies,
+		       spectator_delay_ms, auto_accept_takebacks, tournament_id,
 		       created_at, updated_at, completed_at
 		FROM games 
 		WHERE game_id = $1
@@ -124,10 +245,11 @@ func (s *Service) GetGame(ctx context.Context, gameID string) (*GameRow, error)
 	
 	var game GameRow
 	err := s.db.QueryRowContext(ctx, query, gameID).Scan(
-		&game.ID, &game.GameID, &game.GameType, &game.Status, &game.FEN, &game.PGN,
+		&game.ID, &game.GameID, &game.OwnerID, &game.GameType, &game.Variant, &game.Status, &game.FEN, &game.StartFEN, &game.PGN,
 		&game.WhitePlayerID, &game.WhitePlayerName, &game.BlackPlayerID, &game.BlackPlayerName,
-		&game.CurrentTurn, &game.AIDifficulty, &game.Winner, &game.Outcome, &game.MoveCount,
-		&game.TimeControl, &game.CreatedAt, &game.UpdatedAt, &game.CompletedAt,
+		&game.CurrentTurn, &game.AIDifficulty, &game.Winner, &game.Outcome, &game.VariantState, &game.MoveCount,
+		&game.TimeControl, &game.RematchOf, &game.DaysPerMove, &game.MoveDeadline, &game.AISettings, &game.WhiteToken, &game.BlackToken, &game.SetupPlies,
+		&game.SpectatorDelayMs, &game.AutoAcceptTakebacks, &game.TournamentID, &game.CreatedAt, &game.UpdatedAt, &game.CompletedAt,
 	)
 	
 	if err != nil {
@@ -137,11 +259,175 @@ func (s *Service) GetGame(ctx context.Context, gameID string) (*GameRow, error)
 	return &game, nil
 }
 
+// GetActiveGames returns every game that hasn't finished yet.
+func (s *Service) GetActiveGames(ctx context.Context) ([]GameRow, error) {
+	query := `
+		SELECT id, game_id, owner_id, game_type, variant, status, fen, start_fen, pgn,
+		       white_player_id, white_player_name, black_player_id, black_player_name,
+		       current_turn, ai_difficulty, winner, outcome, variant_state, move_count, time_control, rematch_of,
+		       days_per_move, move_deadline, ai_settings, white_token, black_token, setup_plies,
+		       spectator_delay_ms, auto_accept_takebacks, tournament_id,
+		       created_at, updated_at, completed_at
+		FROM games 
+		WHERE status IN ('waiting', 'in_progress')
+		ORDER BY created_at ASC
+	`
+	
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	var games []GameRow
+	for rows.Next() {
+		var game GameRow
+		err := rows.Scan(
+			&game.ID, &game.GameID, &game.OwnerID, &game.GameType, &game.Variant, &game.Status, &game.FEN, &game.StartFEN, &game.PGN,
+			&game.WhitePlayerID, &game.WhitePlayerName, &game.BlackPlayerID, &game.BlackPlayerName,
+			&game.CurrentTurn, &game.AIDifficulty, &game.Winner, &game.Outcome, &game.VariantState, &game.MoveCount,
+			&game.TimeControl, &game.RematchOf, &game.DaysPerMove, &game.MoveDeadline, &game.AISettings, &game.WhiteToken, &game.BlackToken, &game.SetupPlies,
+		&game.SpectatorDelayMs, &game.AutoAcceptTakebacks, &game.TournamentID, &game.CreatedAt, &game.UpdatedAt, &game.CompletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	
+	return games, rows.Err()
+}
+
+// GetGamesByStatus returns the newest games with the given status, e.g.
+// "waiting" for the open games listed in the lobby.
+func (s *Service) GetGamesByStatus(ctx context.Context, status string, limit int) ([]GameRow, error) {
+	query := `
+		SELECT id, game_id, owner_id, game_type, variant, status, fen, start_fen, pgn,
+		       white_player_id, white_player_name, black_player_id, black_player_name,
+		       current_turn, ai_difficulty, winner, outcome, variant_state, move_count, time_control, rematch_of,
+		       days_per_move, move_deadline, ai_settings, white_token, black_token, setup_plies,
+		       spectator_delay_ms, auto_accept_takebacks, tournament_id,
+		       created_at, updated_at, completed_at
+		FROM games 
+		WHERE status = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	
+	rows, err := s.db.QueryContext(ctx, query, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	var games []GameRow
+	for rows.Next() {
+		var game GameRow
+		err := rows.Scan(
+			&game.ID, &game.GameID, &game.OwnerID, &game.GameType, &game.Variant, &game.Status, &game.FEN, &game.StartFEN, &game.PGN,
+			&game.WhitePlayerID, &game.WhitePlayerName, &game.BlackPlayerID, &game.BlackPlayerName,
+			&game.CurrentTurn, &game.AIDifficulty, &game.Winner, &game.Outcome, &game.VariantState, &game.MoveCount,
+			&game.TimeControl, &game.RematchOf, &game.DaysPerMove, &game.MoveDeadline, &game.AISettings, &game.WhiteToken, &game.BlackToken, &game.SetupPlies,
+		&game.SpectatorDelayMs, &game.AutoAcceptTakebacks, &game.TournamentID, &game.CreatedAt, &game.UpdatedAt, &game.CompletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	
+	return games, rows.Err()
+}
+
+// GetCorrespondenceTurns returns the correspondence games waiting on
+// playerID to move, the most urgent first.
+func (s *Service) GetCorrespondenceTurns(ctx context.Context, playerID string) ([]GameRow, error) {
+	query := `
+		SELECT id, game_id, owner_id, game_type, variant, status, fen, start_fen, pgn,
+		       white_player_id, white_player_name, black_player_id, black_player_name,
+		       current_turn, ai_difficulty, winner, outcome, variant_state, move_count, time_control, rematch_of,
+		       days_per_move, move_deadline, ai_settings, white_token, black_token, setup_plies,
+		       spectator_delay_ms, auto_accept_takebacks, tournament_id,
+		       created_at, updated_at, completed_at
+		FROM games 
+		WHERE days_per_move IS NOT NULL AND status = 'in_progress'
+		  AND ((current_turn = 'white' AND white_player_id = $1)
+		    OR (current_turn = 'black' AND black_player_id = $1))
+		ORDER BY move_deadline ASC
+	`
+	
+	rows, err := s.db.QueryContext(ctx, query, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	var games []GameRow
+	for rows.Next() {
+		var game GameRow
+		err := rows.Scan(
+			&game.ID, &game.GameID, &game.OwnerID, &game.GameType, &game.Variant, &game.Status, &game.FEN, &game.StartFEN, &game.PGN,
+			&game.WhitePlayerID, &game.WhitePlayerName, &game.BlackPlayerID, &game.BlackPlayerName,
+			&game.CurrentTurn, &game.AIDifficulty, &game.Winner, &game.Outcome, &game.VariantState, &game.MoveCount,
+			&game.TimeControl, &game.RematchOf, &game.DaysPerMove, &game.MoveDeadline, &game.AISettings, &game.WhiteToken, &game.BlackToken, &game.SetupPlies,
+		&game.SpectatorDelayMs, &game.AutoAcceptTakebacks, &game.TournamentID, &game.CreatedAt, &game.UpdatedAt, &game.CompletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	
+	return games, rows.Err()
+}
+
+// GetExpiredCorrespondenceGames returns the correspondence games whose
+// side to move let its deadline pass before now.
+func (s *Service) GetExpiredCorrespondenceGames(ctx context.Context, now time.Time) ([]GameRow, error) {
+	query := `
+		SELECT id, game_id, owner_id, game_type, variant, status, fen, start_fen, pgn,
+		       white_player_id, white_player_name, black_player_id, black_player_name,
+		       current_turn, ai_difficulty, winner, outcome, variant_state, move_count, time_control, rematch_of,
+		       days_per_move, move_deadline, ai_settings, white_token, black_token, setup_plies,
+		       spectator_delay_ms, auto_accept_takebacks, tournament_id,
+		       created_at, updated_at, completed_at
+		FROM games 
+		WHERE days_per_move IS NOT NULL AND status = 'in_progress' AND move_deadline < $1
+		ORDER BY move_deadline ASC
+	`
+	
+	rows, err := s.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	var games []GameRow
+	for rows.Next() {
+		var game GameRow
+		err := rows.Scan(
+			&game.ID, &game.GameID, &game.OwnerID, &game.GameType, &game.Variant, &game.Status, &game.FEN, &game.StartFEN, &game.PGN,
+			&game.WhitePlayerID, &game.WhitePlayerName, &game.BlackPlayerID, &game.BlackPlayerName,
+			&game.CurrentTurn, &game.AIDifficulty, &game.Winner, &game.Outcome, &game.VariantState, &game.MoveCount,
+			&game.TimeControl, &game.RematchOf, &game.DaysPerMove, &game.MoveDeadline, &game.AISettings, &game.WhiteToken, &game.BlackToken, &game.SetupPlies,
+		&game.SpectatorDelayMs, &game.AutoAcceptTakebacks, &game.TournamentID, &game.CreatedAt, &game.UpdatedAt, &game.CompletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	
+	return games, rows.Err()
+}
+
 func (s *Service) GetGamesByPlayer(ctx context.Context, playerID string, limit, offset int) ([]GameRow, error) {
 	query := `
-		SELECT id, game_id, game_type, status, fen, pgn,
+		SELECT id, game_id, owner_id, game_type, variant, status, fen, start_fen, pgn,
 		       white_player_id, white_player_name, black_player_id, black_player_name,
-		       current_turn, ai_difficulty, winner, outcome, move_count, time_control,
+		       current_turn, ai_difficulty, winner, outcome, variant_state, move_count, time_control, rematch_of,
+		       days_per_move, move_deadline, ai_settings, white_token, black_token, setup_plies,
+		       spectator_delay_ms, auto_accept_takebacks, tournament_id,
 		       created_at, updated_at, completed_at
 		FROM games 
 		WHERE white_player_id = $1 OR black_player_id = $1
@@ -159,10 +445,11 @@ func (s *Service) GetGamesByPlayer(ctx context.Context, playerID string, limit,
 	for rows.Next() {
 		var game GameRow
 		err := rows.Scan(
-			&game.ID, &game.GameID, &game.GameType, &game.Status, &game.FEN, &game.PGN,
+			&game.ID, &game.GameID, &game.OwnerID, &game.GameType, &game.Variant, &game.Status, &game.FEN, &game.StartFEN, &game.PGN,
 			&game.WhitePlayerID, &game.WhitePlayerName, &game.BlackPlayerID, &game.BlackPlayerName,
-			&game.CurrentTurn, &game.AIDifficulty, &game.Winner, &game.Outcome, &game.MoveCount,
-			&game.TimeControl, &game.CreatedAt, &game.UpdatedAt, &game.CompletedAt,
+			&game.CurrentTurn, &game.AIDifficulty, &game.Winner, &game.Outcome, &game.VariantState, &game.MoveCount,
+			&game.TimeControl, &game.RematchOf, &game.DaysPerMove, &game.MoveDeadline, &game.AISettings, &game.WhiteToken, &game.BlackToken, &game.SetupPlies,
+		&game.SpectatorDelayMs, &game.AutoAcceptTakebacks, &game.TournamentID, &game.CreatedAt, &game.UpdatedAt, &game.CompletedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -194,6 +481,59 @@ func (s *Service) SaveGameMove(ctx context.Context, gameID uuid.UUID, moveNumber
 	return err
 }
 
+// SaveGamePly records one side's half of a full move in game_moves.
+// color is "white" or "black".
+func (s *Service) SaveGamePly(ctx context.Context, gameID string, moveNumber int, color, move, moveSAN, positionAfter string, timeTaken *int) error {
+	if color != "white" && color != "black" {
+		return fmt.Errorf("invalid move color %q", color)
+	}
+	query := fmt.Sprintf(`
+		INSERT INTO game_moves (
+			game_id, move_number, %[1]s_move, %[1]s_move_san,
+			position_after_%[1]s, time_taken_%[1]s
+		)
+		SELECT id, $2, $3, $4, $5, $6 FROM games WHERE game_id = $1
+		ON CONFLICT (game_id, move_number) DO UPDATE SET
+			%[1]s_move = EXCLUDED.%[1]s_move,
+			%[1]s_move_san = EXCLUDED.%[1]s_move_san,
+			position_after_%[1]s = EXCLUDED.position_after_%[1]s,
+			time_taken_%[1]s = EXCLUDED.time_taken_%[1]s
+	`, color)
+	
+	_, err := s.db.ExecContext(ctx, query,
+		gameID, moveNumber, move, moveSAN, positionAfter, timeTaken,
+	)
+	
+	return err
+}
+
+// TruncateGameMoves drops every ply played after color's half of
+// moveNumber, e.g. after a takeback.
+func (s *Service) TruncateGameMoves(ctx context.Context, gameID string, moveNumber int, color string) error {
+	query := `
+		DELETE FROM game_moves
+		WHERE game_id = (SELECT id FROM games WHERE game_id = $1) AND move_number > $2
+	`
+	if _, err := s.db.ExecContext(ctx, query, gameID, moveNumber); err != nil {
+		return err
+	}
+	if color != "white" {
+		return nil
+	}
+	
+	// The last kept move only has white's half
+	query = `
+		UPDATE game_moves SET
+			black_move = NULL,
+			black_move_san = NULL,
+			position_after_black = NULL,
+			time_taken_black = NULL
+		WHERE game_id = (SELECT id FROM games WHERE game_id = $1) AND move_number = $2
+	`
+	_, err := s.db.ExecContext(ctx, query, gameID, moveNumber)
+	return err
+}
+
 func (s *Service) GetGameMoves(ctx context.Context, gameID uuid.UUID) ([]GameMoveRow, error) {
 	query := `
 		SELECT id, game_id, move_number, white_move, black_move,
@@ -40,6 +40,11 @@ const getOpeningsByVolume = `
 	OFFSET $2;
 	`
 
+const getOpeningByID = `
+	SELECT * FROM openings
+	WHERE id = $1;
+	`
+
 const getRandomOpening = `
 	SELECT * FROM openings
 	ORDER BY RANDOM()
@@ -84,3 +89,12 @@ func (s *Service) GetRandomOpening(ctx context.Context) (*Opening, error) {
 	}
 	return &opening, nil
 }
+
+func (s *Service) GetOpeningByID(ctx context.Context, id uuid.UUID) (*Opening, error) {
+	var opening Opening
+	err := s.db.QueryRowContext(ctx, getOpeningByID, id).Scan(&opening.Id, &opening.Eco, &opening.Name, &opening.Pgn)
+	if err != nil {
+		return nil, err
+	}
+	return &opening, nil
+}
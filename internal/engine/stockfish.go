@@ -16,6 +16,9 @@ type StockfishEngine struct {
 	stdin  io.WriteCloser
 	stdout io.ReadCloser
 	reader *bufio.Scanner
+	// variants are the UCI_Variant values the engine supports; official
+	// Stockfish builds have none, multi-variant forks list theirs
+	variants map[string]bool
 }
 
 type MoveResponse struct {
@@ -74,6 +77,9 @@ func NewStockfish() (*StockfishEngine, error) {
 		if line == "uciok" {
 			break
 		}
+		if strings.HasPrefix(line, "option name UCI_Variant ") {
+			engine.variants = parseComboVars(line)
+		}
 	}
 	
 	// Set up engine
@@ -182,6 +188,35 @@ func (e *StockfishEngine) SetDifficulty(level int) error {
 	return e.sendCommand(fmt.Sprintf("setoption name Depth value %d", depth))
 }
 
+// SetChess960 switches the engine to Chess960 castling rules. Positions
+// must then be given as Shredder-FEN, and castling comes back as the king
+// taking its own rook, e.g. "b1a1".
+func (e *StockfishEngine) SetChess960(enabled bool) error {
+	return e.sendCommand(fmt.Sprintf("setoption name UCI_Chess960 value %t", enabled))
+}
+
+// SetVariant switches the engine to a rule variant by its UCI_Variant
+// name, e.g. "3check". It fails if the engine doesn't support the variant.
+func (e *StockfishEngine) SetVariant(variant string) error {
+	if !e.variants[variant] {
+		return fmt.Errorf("engine does not support variant %s", variant)
+	}
+	return e.sendCommand(fmt.Sprintf("setoption name UCI_Variant value %s", variant))
+}
+
+// parseComboVars returns the values of a combo option line, e.g.
+// "option name UCI_Variant type combo default chess var chess var 3check".
+func parseComboVars(line string) map[string]bool {
+	vars := make(map[string]bool)
+	fields := strings.Fields(line)
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "var" {
+			vars[fields[i+1]] = true
+		}
+	}
+	return vars
+}
+
 func (e *StockfishEngine) AnalyzePosition(fen string, lines int, depth int) ([]MoveResponse, error) {
 	if err := e.sendCommand(fmt.Sprintf("position fen %s", fen)); err != nil {
 		return nil, err
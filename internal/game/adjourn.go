@@ -0,0 +1,149 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/hunterMotko/chess-game/internal/database"
+)
+
+// RequestAdjournment asks to pause a game until its players come back to
+// it. Against the AI the game is adjourned straight away; against a human
+// it is once both players have asked. adjourned reports whether it was.
+func (gs *GameService) RequestAdjournment(gameID, playerID string) (adjourned bool, err error) {
+	game, err := gs.lockInProgress(gameID)
+	if err != nil {
+		return false, err
+	}
+	defer game.mutex.Unlock()
+
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		return false, err
+	}
+	opponent := game.Players[color.Other()]
+	if !opponent.IsAI && game.AdjournRequestedBy != color.Other() {
+		game.AdjournRequestedBy = color
+		log.Printf("Game %s: %s asked to adjourn", gameID, color.Name())
+		return false, nil
+	}
+
+	gs.adjourn(game, time.Now())
+	return true, nil
+}
+
+// adjourn freezes the game: its clock stops, its engines are shut down and
+// pending offers lapse. The caller must hold game.mutex.
+func (gs *GameService) adjourn(game *GameState, now time.Time) {
+	if game.Clock != nil {
+		game.Clock.Stop(game.ChessGame.Position().Turn(), now)
+	}
+	game.stopClockTimer()
+	game.closeEngines()
+	game.Status = StatusAdjourned
+	game.AdjournedAt = now
+	game.AdjournRequestedBy = chess.NoColor
+	game.DrawOfferedBy = chess.NoColor
+	game.TakebackRequestedBy = chess.NoColor
+	game.Premoves = nil
+	gs.persistGame(game)
+	log.Printf("Game %s adjourned", game.ID)
+}
+
+// RequestResume asks to carry on with an adjourned game, loading it from
+// the database when it is no longer in memory. Against the AI the game
+// resumes straight away; against a human it does once both players have
+// asked. resumed reports whether it did.
+func (gs *GameService) RequestResume(gameID, playerID string) (resumed bool, err error) {
+	game, err := gs.loadGame(context.Background(), gameID)
+	if err != nil {
+		return false, err
+	}
+
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+
+	if game.Status != StatusAdjourned {
+		return false, fmt.Errorf("game %s is not adjourned (status: %s)", gameID, game.Status)
+	}
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		return false, err
+	}
+	opponent := game.Players[color.Other()]
+	if !opponent.IsAI && game.ResumeRequestedBy != color.Other() {
+		game.ResumeRequestedBy = color
+		log.Printf("Game %s: %s asked to resume", gameID, color.Name())
+		return false, nil
+	}
+
+	gs.resume(game, time.Now())
+	return true, nil
+}
+
+// resume puts an adjourned game back in play. The side to move starts its
+// turn afresh, as after a restart. The caller must hold game.mutex.
+func (gs *GameService) resume(game *GameState, now time.Time) {
+	if game.Type == HumanVsAI {
+		game.AIEngine = newAIEngine(game.ID, game.AIDifficulty)
+		game.configureEngines()
+	}
+	game.Status = StatusInProgress
+	game.AdjournedAt = time.Time{}
+	game.resumedAt = now
+	game.ResumeRequestedBy = chess.NoColor
+	// A clock only runs once the first move has been played
+	if game.Clock != nil && len(game.MoveHistory) > game.setupPlies {
+		game.Clock.Start(now)
+		gs.armClockTimer(game)
+	}
+	if game.DaysPerMove > 0 {
+		gs.setMoveDeadline(game, now)
+	}
+	gs.persistGame(game)
+	log.Printf("Game %s resumed", game.ID)
+}
+
+// loadGame returns the game from memory, or rebuilds it from the database,
+// e.g. an adjourned game evicted by the sweeper or left over from before a
+// restart.
+func (gs *GameService) loadGame(ctx context.Context, gameID string) (*GameState, error) {
+	if game, exists := gs.GetGame(gameID); exists {
+		return game, nil
+	}
+	if gs.db == nil {
+		return nil, fmt.Errorf("game %s not found", gameID)
+	}
+
+	row, err := gs.db.GetGame(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("game %s not found", gameID)
+	}
+	return gs.loadGameRow(ctx, row)
+}
+
+// loadGameRow rebuilds the game stored in row and puts it in memory,
+// unless it is there already.
+func (gs *GameService) loadGameRow(ctx context.Context, row *database.GameRow) (*GameState, error) {
+	gameID := row.GameID
+	moves, err := gs.db.GetGameMoves(ctx, row.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load moves of game %s: %v", gameID, err)
+	}
+	game, err := restoreGame(*row, moves)
+	if err != nil {
+		return nil, fmt.Errorf("could not rebuild game %s: %v", gameID, err)
+	}
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	if existing, exists := gs.games[gameID]; exists {
+		return existing, nil // Loaded by someone else in the meantime
+	}
+	gs.games[gameID] = game
+	log.Printf("Loaded game %s from the database", gameID)
+	return game, nil
+}
@@ -0,0 +1,92 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/hunterMotko/chess-game/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameService_Adjourn(t *testing.T) {
+	gs := NewGameService(nil)
+	_, err := gs.CreateGame("long-game", "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, gs.SetTimeControl("long-game", TimeControl{BaseMs: int64(time.Hour / time.Millisecond)}))
+	require.NoError(t, gs.JoinGame("long-game", "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame("long-game", "black-client", "Bob", chess.Black))
+	playMoves(t, gs, "long-game", "e2e4", "e7e5")
+
+	adjourned, err := gs.RequestAdjournment("long-game", "white-client")
+	require.NoError(t, err)
+	assert.False(t, adjourned, "black has to agree")
+	adjourned, err = gs.RequestAdjournment("long-game", "black-client")
+	require.NoError(t, err)
+	assert.True(t, adjourned)
+
+	state, err := gs.GetGameState("long-game")
+	require.NoError(t, err)
+	assert.Equal(t, StatusAdjourned, state.Status)
+	assert.False(t, state.Clock.Running, "the clock is frozen")
+	_, err = gs.MakeMove("long-game", "white-client", "g1f3")
+	assert.Error(t, err)
+
+	// Nobody moving for hours doesn't make an adjourned game abandoned
+	abandoned, evicted := gs.Sweep(time.Now().Add(24*time.Hour), DefaultSweepConfig)
+	assert.Empty(t, abandoned)
+	assert.Empty(t, evicted, "without a database it has nowhere else to live")
+
+	resumed, err := gs.RequestResume("long-game", "black-client")
+	require.NoError(t, err)
+	assert.False(t, resumed)
+	resumed, err = gs.RequestResume("long-game", "white-client")
+	require.NoError(t, err)
+	assert.True(t, resumed)
+
+	state, err = gs.GetGameState("long-game")
+	require.NoError(t, err)
+	assert.Equal(t, StatusInProgress, state.Status)
+	assert.True(t, state.Clock.Running)
+	_, err = gs.MakeMove("long-game", "white-client", "g1f3")
+	assert.NoError(t, err)
+}
+
+func TestGameService_AdjournAgainstAI(t *testing.T) {
+	gs := NewGameService(nil)
+	_, err := gs.CreateGame("ai-game", "student", HumanVsAI, 1)
+	require.NoError(t, err)
+	require.NoError(t, gs.JoinGame("ai-game", "student", "Student", chess.White))
+
+	adjourned, err := gs.RequestAdjournment("ai-game", "student")
+	require.NoError(t, err)
+	assert.True(t, adjourned, "the AI doesn't get a say")
+
+	_, err = gs.RequestResume("ai-game", "someone-else")
+	assert.Error(t, err)
+	resumed, err := gs.RequestResume("ai-game", "student")
+	require.NoError(t, err)
+	assert.True(t, resumed)
+
+	_, err = gs.RequestResume("ai-game", "student")
+	assert.Error(t, err, "the game is no longer adjourned")
+}
+
+func TestRestoreGame_Adjourned(t *testing.T) {
+	white, black := "white-client", "black-client"
+	adjournedAt := time.Now().Add(-time.Hour)
+	row := database.GameRow{
+		GameID:        "adjourned-game",
+		GameType:      string(HumanVsHuman),
+		Status:        string(StatusAdjourned),
+		WhitePlayerID: &white,
+		BlackPlayerID: &black,
+		UpdatedAt:     adjournedAt,
+	}
+
+	game, err := restoreGame(row, nil)
+	require.NoError(t, err)
+	assert.Equal(t, StatusAdjourned, game.Status)
+	assert.Equal(t, adjournedAt, game.AdjournedAt)
+}
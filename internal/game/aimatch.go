@@ -0,0 +1,146 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/hunterMotko/chess-game/internal/engine"
+)
+
+// AISettings configures the engine playing one side of a game. Zero Depth
+// and MoveTimeMs fall back to values derived from Difficulty.
+type AISettings struct {
+	Difficulty int   `json:"difficulty"`           // Stockfish skill level, 0-20
+	Depth      int   `json:"depth,omitempty"`      // Search depth per move
+	MoveTimeMs int64 `json:"moveTimeMs,omitempty"` // Thinking time per move
+}
+
+func (s AISettings) Validate() error {
+	if s.Difficulty < 0 || s.Difficulty > 20 {
+		return fmt.Errorf("AI difficulty must be between 0 and 20")
+	}
+	if s.Depth < 0 || s.MoveTimeMs < 0 {
+		return fmt.Errorf("AI depth and move time cannot be negative")
+	}
+	return nil
+}
+
+// CreateAIMatch creates an engine vs engine game, owned by ownerID, with its
+// own settings for each side, to be played a move every pace. The match
+// waits for StartGame so a time control can be set first.
+func (gs *GameService) CreateAIMatch(gameID, ownerID string, white, black AISettings, pace time.Duration) (*GameState, error) {
+	if err := white.Validate(); err != nil {
+		return nil, fmt.Errorf("white: %v", err)
+	}
+	if err := black.Validate(); err != nil {
+		return nil, fmt.Errorf("black: %v", err)
+	}
+
+	game, err := gs.CreateGame(gameID, ownerID, AIVsAI, white.Difficulty)
+	if err != nil {
+		return nil, err
+	}
+
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+
+	game.AISettings = map[chess.Color]AISettings{
+		chess.White: white,
+		chess.Black: black,
+	}
+	game.AIMatchPace = pace
+	game.openAIEngines()
+	for color, settings := range game.AISettings {
+		game.Players[color] = Player{
+			ID:    aiPlayerID(gameID),
+			Name:  fmt.Sprintf("Stockfish (Level %d)", settings.Difficulty),
+			IsAI:  true,
+			Color: color,
+		}
+	}
+	gs.persistPlayers(game)
+	gs.persistAISettings(game)
+
+	log.Printf("Created AI match %s (white level %d, black level %d)", gameID, white.Difficulty, black.Difficulty)
+	return game, nil
+}
+
+// AIMatchPace returns the pause between the moves of an AI match, zero if
+// the game isn't one or its pace wasn't stored.
+func (gs *GameService) AIMatchPace(gameID string) time.Duration {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return 0
+	}
+
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.AIMatchPace
+}
+
+// openAIEngines starts an engine for each side of an AI match, with that
+// side's difficulty. The caller must hold game.mutex.
+func (game *GameState) openAIEngines() {
+	game.AIEngines = make(map[chess.Color]*engine.StockfishEngine)
+	for color, settings := range game.AISettings {
+		if aiEngine := newAIEngine(game.ID, settings.Difficulty); aiEngine != nil {
+			game.AIEngines[color] = aiEngine
+		}
+	}
+}
+
+// StartGame starts a fully seated game that is still waiting, e.g. an AI
+// match once it has been configured.
+func (gs *GameService) StartGame(gameID string) error {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+
+	if game.Status != StatusWaiting {
+		return fmt.Errorf("game %s has already started", gameID)
+	}
+	if len(game.Players) < 2 {
+		return fmt.Errorf("game %s is still waiting for players", gameID)
+	}
+
+	gs.startGame(game)
+	gs.persistGame(game)
+	return nil
+}
+
+// engineFor returns the engine playing color. The caller must hold
+// game.mutex.
+func (game *GameState) engineFor(color chess.Color) *engine.StockfishEngine {
+	if aiEngine, ok := game.AIEngines[color]; ok {
+		return aiEngine
+	}
+	return game.AIEngine
+}
+
+// aiSettingsFor returns the engine settings for color. The caller must
+// hold game.mutex.
+func (game *GameState) aiSettingsFor(color chess.Color) AISettings {
+	if settings, ok := game.AISettings[color]; ok {
+		return settings
+	}
+	return AISettings{Difficulty: game.AIDifficulty}
+}
+
+// closeEngines shuts down every engine of the game. The caller must hold
+// game.mutex.
+func (game *GameState) closeEngines() {
+	if game.AIEngine != nil {
+		game.AIEngine.Close()
+		game.AIEngine = nil
+	}
+	for color, aiEngine := range game.AIEngines {
+		aiEngine.Close()
+		delete(game.AIEngines, color)
+	}
+}
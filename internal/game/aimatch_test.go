@@ -0,0 +1,40 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAISettings_Validate(t *testing.T) {
+	assert.NoError(t, AISettings{Difficulty: 20, Depth: 12, MoveTimeMs: 500}.Validate())
+	assert.Error(t, AISettings{Difficulty: 21}.Validate())
+	assert.Error(t, AISettings{Difficulty: 5, Depth: -1}.Validate())
+}
+
+func TestGameService_CreateAIMatch(t *testing.T) {
+	gs := NewGameService(nil)
+
+	_, err := gs.CreateAIMatch("match", "organiser", AISettings{Difficulty: 3}, AISettings{Difficulty: 25}, time.Second)
+	assert.Error(t, err, "black difficulty out of range")
+
+	game, err := gs.CreateAIMatch("match", "organiser", AISettings{Difficulty: 3}, AISettings{Difficulty: 18, MoveTimeMs: 200}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, StatusWaiting, game.Status)
+	assert.True(t, game.Players[chess.White].IsAI)
+	assert.True(t, game.Players[chess.Black].IsAI)
+	assert.Equal(t, 3, game.aiSettingsFor(chess.White).Difficulty)
+	assert.Equal(t, int64(200), game.aiSettingsFor(chess.Black).MoveTimeMs)
+	assert.Equal(t, time.Second, gs.AIMatchPace("match"))
+
+	assert.Error(t, gs.JoinGame("match", "human", "Alice", chess.NoColor), "no seats left")
+	_, err = gs.MakeMove("match", "human", "e2e4")
+	assert.Error(t, err)
+
+	require.NoError(t, gs.StartGame("match"))
+	assert.Equal(t, StatusInProgress, game.Status)
+	assert.Error(t, gs.StartGame("match"), "already started")
+}
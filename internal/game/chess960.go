@@ -0,0 +1,381 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+)
+
+// The chess library only knows castling from e1/e8 with rooks in the
+// corners, so Chess960 games keep the library's castling rights empty and
+// track their own, in Shredder-FEN notation: the files of the rooks each
+// side may still castle with, e.g. "HAha" for the classical setup.
+
+// chess960Knights lists where the two knights go among the five squares
+// left once the bishops and queen are placed, indexed by position number.
+var chess960Knights = [10][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}
+
+// Chess960StartPosition returns the Shredder-FEN of Chess960 start position
+// n, 0-959, in the standard numbering where 518 is the classical setup.
+func Chess960StartPosition(n int) (string, error) {
+	if n < 0 || n > 959 {
+		return "", fmt.Errorf("chess960 position must be between 0 and 959, got %d", n)
+	}
+
+	var rank [8]byte
+	// free returns the file of the i-th square still empty
+	free := func(i int) int {
+		for file, piece := range rank {
+			if piece != 0 {
+				continue
+			}
+			if i == 0 {
+				return file
+			}
+			i--
+		}
+		return -1
+	}
+
+	rank[2*(n%4)+1] = 'b' // light-squared bishop
+	n /= 4
+	rank[2*(n%4)] = 'b' // dark-squared bishop
+	n /= 4
+	rank[free(n%6)] = 'q'
+	n /= 6
+	knights := chess960Knights[n]
+	rank[free(knights[1])] = 'n' // the later knight first keeps the earlier's index valid
+	rank[free(knights[0])] = 'n'
+	for _, piece := range []byte{'r', 'k', 'r'} {
+		rank[free(0)] = piece
+	}
+
+	var rooks []chess.Square
+	for file, piece := range rank {
+		if piece == 'r' {
+			rooks = append(rooks, chess.NewSquare(chess.File(file), chess.Rank1), chess.NewSquare(chess.File(file), chess.Rank8))
+		}
+	}
+	black := string(rank[:])
+	return fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w %s - 0 1", black, strings.ToUpper(black), formatCastling(rooks)), nil
+}
+
+// castle is a castling move: the king and rook start on their own squares
+// and end on the g and f files, or the c and d files on the queen side.
+type castle struct {
+	king, rook     chess.Square
+	kingTo, rookTo chess.Square
+}
+
+func (c castle) san() string {
+	if c.kingTo.File() == chess.FileG {
+		return "O-O"
+	}
+	return "O-O-O"
+}
+
+// uci returns the castle as the king taking its own rook, as UCI_Chess960
+// engines write it.
+func (c castle) uci() string {
+	return c.king.String() + c.rook.String()
+}
+
+// splitCastling replaces the castling field of a Shredder-FEN with "-" so
+// the chess library accepts it, and returns the field on its own.
+func splitCastling(fen string) (string, string) {
+	fields := strings.Fields(fen)
+	if len(fields) < 3 {
+		return fen, "-"
+	}
+	castling := fields[2]
+	fields[2] = "-"
+	return strings.Join(fields, " "), castling
+}
+
+// withCastling puts a castling field into a FEN.
+func withCastling(fen, castling string) string {
+	fields := strings.Fields(fen)
+	if len(fields) < 3 {
+		return fen
+	}
+	fields[2] = castling
+	return strings.Join(fields, " ")
+}
+
+// parseCastling returns the squares of the rooks named by Shredder-FEN
+// castling rights.
+func parseCastling(castling string) []chess.Square {
+	var rooks []chess.Square
+	for _, r := range castling {
+		switch {
+		case r >= 'A' && r <= 'H':
+			rooks = append(rooks, chess.NewSquare(chess.File(r-'A'), chess.Rank1))
+		case r >= 'a' && r <= 'h':
+			rooks = append(rooks, chess.NewSquare(chess.File(r-'a'), chess.Rank8))
+		}
+	}
+	return rooks
+}
+
+// formatCastling writes castling rights in Shredder-FEN notation, white
+// before black and the king side first.
+func formatCastling(rooks []chess.Square) string {
+	if len(rooks) == 0 {
+		return "-"
+	}
+	sort.Slice(rooks, func(i, j int) bool {
+		if rooks[i].Rank() != rooks[j].Rank() {
+			return rooks[i].Rank() < rooks[j].Rank()
+		}
+		return rooks[i].File() > rooks[j].File()
+	})
+	var b strings.Builder
+	for _, rook := range rooks {
+		file := rook.File().String()
+		if rook.Rank() == chess.Rank1 {
+			file = strings.ToUpper(file)
+		}
+		b.WriteString(file)
+	}
+	return b.String()
+}
+
+// updateCastling drops the rights a move gives up: all of the mover's when
+// the king moves, and a rook's when it moves or is captured. pos is the
+// position before the move.
+func updateCastling(castling string, pos *chess.Position, move *chess.Move) string {
+	mover := pos.Board().Piece(move.S1())
+	var kept []chess.Square
+	for _, rook := range parseCastling(castling) {
+		if mover.Type() == chess.King && rook.Rank() == backRank(mover.Color()) {
+			continue
+		}
+		if rook == move.S1() || rook == move.S2() {
+			continue
+		}
+		kept = append(kept, rook)
+	}
+	return formatCastling(kept)
+}
+
+// dropCastling removes every right of color.
+func dropCastling(castling string, color chess.Color) string {
+	var kept []chess.Square
+	for _, rook := range parseCastling(castling) {
+		if rook.Rank() != backRank(color) {
+			kept = append(kept, rook)
+		}
+	}
+	return formatCastling(kept)
+}
+
+func backRank(color chess.Color) chess.Rank {
+	if color == chess.Black {
+		return chess.Rank8
+	}
+	return chess.Rank1
+}
+
+// kingSquare returns where color's king stands.
+func kingSquare(board *chess.Board, color chess.Color) chess.Square {
+	for sq, piece := range board.SquareMap() {
+		if piece == chess.NewPiece(chess.King, color) {
+			return sq
+		}
+	}
+	return chess.NoSquare
+}
+
+// castles lists the castling moves open to the side to move: the right is
+// still held, every square the king and rook cross or land on is empty
+// apart from those two, and the king is not in check, passes through no
+// attacked square and doesn't end up in check.
+func castles(pos *chess.Position, castling string) []castle {
+	turn := pos.Turn()
+	board := pos.Board()
+	rank := backRank(turn)
+	king := kingSquare(board, turn)
+	if king == chess.NoSquare || king.Rank() != rank || attacked(board, king, turn.Other()) {
+		return nil
+	}
+
+	var moves []castle
+	for _, rook := range parseCastling(castling) {
+		if rook.Rank() != rank || board.Piece(rook) != chess.NewPiece(chess.Rook, turn) {
+			continue
+		}
+		c := castle{king: king, rook: rook}
+		if rook.File() > king.File() {
+			c.kingTo, c.rookTo = chess.NewSquare(chess.FileG, rank), chess.NewSquare(chess.FileF, rank)
+		} else {
+			c.kingTo, c.rookTo = chess.NewSquare(chess.FileC, rank), chess.NewSquare(chess.FileD, rank)
+		}
+		if c.legal(board) {
+			moves = append(moves, c)
+		}
+	}
+	return moves
+}
+
+// legal checks the castle's path; the king is already known not to be in
+// check.
+func (c castle) legal(board *chess.Board) bool {
+	lo := min(c.king.File(), c.rook.File(), c.kingTo.File(), c.rookTo.File())
+	hi := max(c.king.File(), c.rook.File(), c.kingTo.File(), c.rookTo.File())
+	for file := lo; file <= hi; file++ {
+		sq := chess.NewSquare(file, c.king.Rank())
+		if sq != c.king && sq != c.rook && board.Piece(sq) != chess.NoPiece {
+			return false
+		}
+	}
+
+	color := board.Piece(c.king).Color()
+	step := 1
+	if c.kingTo.File() < c.king.File() {
+		step = -1
+	}
+	for file := int(c.king.File()); file != int(c.kingTo.File()); {
+		file += step
+		if attacked(board, chess.NewSquare(chess.File(file), c.king.Rank()), color.Other()) {
+			return false
+		}
+	}
+
+	// The rook may have been shielding the king's new square
+	after := c.apply(board)
+	return !attacked(after, c.kingTo, color.Other())
+}
+
+// apply returns the board after the castle.
+func (c castle) apply(board *chess.Board) *chess.Board {
+	squares := board.SquareMap()
+	king, rook := squares[c.king], squares[c.rook]
+	delete(squares, c.king)
+	delete(squares, c.rook)
+	squares[c.kingTo] = king
+	squares[c.rookTo] = rook
+	return chess.NewBoard(squares)
+}
+
+// castledFEN returns the FEN, without castling rights, of the position
+// after the castle.
+func (c castle) castledFEN(pos *chess.Position) string {
+	fields := strings.Fields(pos.String())
+	halfMoves, _ := strconv.Atoi(fields[4])
+	fullMoves, _ := strconv.Atoi(fields[5])
+	turn := "b"
+	if pos.Turn() == chess.Black {
+		turn = "w"
+		fullMoves++
+	}
+	return fmt.Sprintf("%s %s - - %d %d", c.apply(pos.Board()).String(), turn, halfMoves+1, fullMoves)
+}
+
+// findCastle matches moveStr against the castles open to the side to move.
+// Besides O-O and O-O-O it takes the king taking its own rook, as
+// UCI_Chess960 writes castling, and the king's own move when that isn't
+// also an ordinary king move. The caller must hold game.mutex.
+func (game *GameState) findCastle(moveStr string) (castle, bool) {
+	for _, c := range castles(game.ChessGame.Position(), game.castling) {
+		switch moveStr {
+		case c.san(), c.uci():
+			return c, true
+		case c.king.String() + c.kingTo.String():
+			if c.king != c.kingTo && findValidMove(game.ChessGame.ValidMoves(), moveStr) == nil {
+				return c, true
+			}
+		}
+	}
+	return castle{}, false
+}
+
+// playCastle castles and returns the move as UCI and SAN. The chess library
+// can't play the move itself, so the game carries on from the position
+// after it; castling is irreversible, so no repetition is lost. The caller
+// must hold game.mutex.
+func (game *GameState) playCastle(c castle) (string, string, error) {
+	pos := game.ChessGame.Position()
+	option, err := chess.FEN(c.castledFEN(pos))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to apply move: %v", err)
+	}
+	chessGame := chess.NewGame(option)
+
+	san := c.san()
+	after := chessGame.Position()
+	if chessGame.Method() == chess.Checkmate {
+		san += "#"
+	} else if attacked(after.Board(), kingSquare(after.Board(), after.Turn()), pos.Turn()) {
+		san += "+"
+	}
+
+	game.ChessGame = chessGame
+	game.castling = dropCastling(game.castling, pos.Turn())
+	return c.uci(), san, nil
+}
+
+var (
+	knightSteps   = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	kingSteps     = [][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	straightSteps = [][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+	diagonalSteps = [][2]int{{1, 1}, {-1, 1}, {-1, -1}, {1, -1}}
+)
+
+// attacked reports whether any piece of color by attacks sq.
+func attacked(board *chess.Board, sq chess.Square, by chess.Color) bool {
+	file, rank := int(sq.File()), int(sq.Rank())
+	pieceAt := func(f, r int) chess.Piece {
+		if f < 0 || f > 7 || r < 0 || r > 7 {
+			return chess.NoPiece
+		}
+		return board.Piece(chess.NewSquare(chess.File(f), chess.Rank(r)))
+	}
+	is := func(piece chess.Piece, types ...chess.PieceType) bool {
+		if piece.Color() != by {
+			return false
+		}
+		for _, t := range types {
+			if piece.Type() == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Pawns attack towards the opponent, so look one rank behind sq
+	pawnRank := rank - 1
+	if by == chess.Black {
+		pawnRank = rank + 1
+	}
+	if is(pieceAt(file-1, pawnRank), chess.Pawn) || is(pieceAt(file+1, pawnRank), chess.Pawn) {
+		return true
+	}
+	for _, step := range knightSteps {
+		if is(pieceAt(file+step[0], rank+step[1]), chess.Knight) {
+			return true
+		}
+	}
+	for _, step := range kingSteps {
+		if is(pieceAt(file+step[0], rank+step[1]), chess.King) {
+			return true
+		}
+	}
+
+	slides := func(steps [][2]int, types ...chess.PieceType) bool {
+		for _, step := range steps {
+			for f, r := file+step[0], rank+step[1]; f >= 0 && f <= 7 && r >= 0 && r <= 7; f, r = f+step[0], r+step[1] {
+				if piece := pieceAt(f, r); piece != chess.NoPiece {
+					if is(piece, types...) {
+						return true
+					}
+					break
+				}
+			}
+		}
+		return false
+	}
+	return slides(straightSteps, chess.Rook, chess.Queen) || slides(diagonalSteps, chess.Bishop, chess.Queen)
+}
@@ -0,0 +1,80 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChess960StartPosition(t *testing.T) {
+	fen, err := Chess960StartPosition(518)
+	require.NoError(t, err)
+	assert.Equal(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1", fen)
+
+	fen, err = Chess960StartPosition(0)
+	require.NoError(t, err)
+	assert.Equal(t, "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w HFhf - 0 1", fen)
+
+	_, err = Chess960StartPosition(960)
+	assert.Error(t, err)
+}
+
+func TestChess960_Castling(t *testing.T) {
+	// The white king already stands on g1, so castling only moves the rook
+	replay, plies, err := replayGame(VariantChess960, "rk5r/8/8/8/8/8/8/R5KR w HAha - 0 1", []string{"g1h1", "b8a8"})
+	require.NoError(t, err)
+	assert.Equal(t, "O-O", plies[0].san)
+	assert.Equal(t, "2kr3r/8/8/8/8/8/8/R4RK1 w - - 2 2", replay.fen())
+	assert.Equal(t, "O-O-O", plies[1].san)
+
+	// A king passing through an attacked square can't castle
+	replay, _, err = replayGame(VariantChess960, "rk5r/8/8/8/8/8/8/R1R3K1 b a - 0 1", nil)
+	require.NoError(t, err)
+	_, ok := replay.findCastle("b8a8")
+	assert.False(t, ok, "c8 is attacked")
+}
+
+func TestChess960_CastlingRights(t *testing.T) {
+	replay, _, err := replayGame(VariantChess960, "rk5r/8/8/8/8/8/8/R5KR w HAha - 0 1", []string{"a1a8"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hh", replay.castling, "the a-rooks moved and were captured")
+}
+
+func TestGameService_SetVariant(t *testing.T) {
+	gs := NewGameService(nil)
+	_, err := gs.CreateGame("fischer", "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+
+	position := 518
+	require.NoError(t, gs.SetVariant("fischer", VariantChess960, &position))
+	assert.Error(t, gs.SetStartPosition("fischer", "4k3/8/8/8/8/8/8/4K2R w K - 0 1", ""))
+
+	require.NoError(t, gs.JoinGame("fischer", "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame("fischer", "black-client", "Bob", chess.Black))
+	players := []string{"white-client", "black-client"}
+	for i, move := range []string{"g1f3", "g8f6", "e2e3", "e7e6", "f1e2", "f8e7", "e1h1"} {
+		_, err := gs.MakeMove("fischer", players[i%2], move)
+		require.NoError(t, err, move)
+	}
+
+	state, err := gs.GetGameState("fischer")
+	require.NoError(t, err)
+	assert.Equal(t, VariantChess960, state.Variant)
+	assert.Equal(t, "rnbqk2r/ppppbppp/4pn2/8/8/4PN2/PPPPBPPP/RNBQ1RK1 b ha - 3 4", state.FEN)
+
+	game, _ := gs.GetGame("fischer")
+	pgn := game.pgn()
+	assert.Contains(t, pgn, `[Variant "Chess960"]`)
+	assert.Contains(t, pgn, `[FEN "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1"]`)
+	assert.Contains(t, pgn, "4. O-O *")
+
+	// Takebacks replay the castle
+	_, _, err = gs.RequestTakeback("fischer", "white-client")
+	require.NoError(t, err)
+	require.NoError(t, gs.RespondTakeback("fischer", "black-client", true))
+	state, err = gs.GetGameState("fischer")
+	require.NoError(t, err)
+	assert.Equal(t, "rnbqk2r/ppppbppp/4pn2/8/8/4PN2/PPPPBPPP/RNBQK2R w HAha - 2 4", state.FEN)
+}
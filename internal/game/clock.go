@@ -0,0 +1,227 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+// TimeControl describes a game's clock settings. Values are in milliseconds
+// so they map directly onto the games.time_control JSONB column.
+type TimeControl struct {
+	BaseMs      int64 `json:"baseMs"`
+	IncrementMs int64 `json:"incrementMs,omitempty"` // Fischer increment added after each move
+	DelayMs     int64 `json:"delayMs,omitempty"`     // Bronstein delay refunded after each move
+	MoveLimitMs int64 `json:"moveLimitMs,omitempty"` // Maximum thinking time for a single move
+}
+
+func (tc TimeControl) Validate() error {
+	if tc.BaseMs <= 0 {
+		return fmt.Errorf("time control base must be positive")
+	}
+	if tc.IncrementMs < 0 || tc.DelayMs < 0 || tc.MoveLimitMs < 0 {
+		return fmt.Errorf("time control values cannot be negative")
+	}
+	if tc.IncrementMs > 0 && tc.DelayMs > 0 {
+		return fmt.Errorf("time control cannot use both an increment and a delay")
+	}
+	return nil
+}
+
+// Clock is the server-side chess clock. Only the side to move has a running
+// clock; its remaining time is settled when it moves.
+type Clock struct {
+	Control   TimeControl
+	Remaining map[chess.Color]time.Duration
+	// TurnStartedAt is when the side to move started thinking. Zero while
+	// the clock is stopped.
+	TurnStartedAt time.Time
+}
+
+func NewClock(tc TimeControl) *Clock {
+	base := time.Duration(tc.BaseMs) * time.Millisecond
+	return &Clock{
+		Control: tc,
+		Remaining: map[chess.Color]time.Duration{
+			chess.White: base,
+			chess.Black: base,
+		},
+	}
+}
+
+func (c *Clock) Running() bool {
+	return !c.TurnStartedAt.IsZero()
+}
+
+func (c *Clock) Start(now time.Time) {
+	c.TurnStartedAt = now
+}
+
+func (c *Clock) Stop(turn chess.Color, now time.Time) {
+	if !c.Running() {
+		return
+	}
+	c.Remaining[turn] = c.RemainingAt(turn, turn, now)
+	c.TurnStartedAt = time.Time{}
+}
+
+// RemainingAt returns color's time left at now, given whose turn it is.
+// Time spent inside the Bronstein delay is not counted against the player.
+func (c *Clock) RemainingAt(color, turn chess.Color, now time.Time) time.Duration {
+	remaining := c.Remaining[color]
+	if color != turn || !c.Running() {
+		return remaining
+	}
+	spent := now.Sub(c.TurnStartedAt) - time.Duration(c.Control.DelayMs)*time.Millisecond
+	if spent > 0 {
+		remaining -= spent
+	}
+	return remaining
+}
+
+// Flagged reports whether the side to move has run out of time, either on
+// the main clock or on the per-move limit.
+func (c *Clock) Flagged(turn chess.Color, now time.Time) bool {
+	if !c.Running() {
+		return false
+	}
+	if c.RemainingAt(turn, turn, now) <= 0 {
+		return true
+	}
+	limit := time.Duration(c.Control.MoveLimitMs) * time.Millisecond
+	return limit > 0 && now.Sub(c.TurnStartedAt) >= limit
+}
+
+// FlagIn returns how long until the side to move flags if it doesn't move.
+func (c *Clock) FlagIn(turn chess.Color, now time.Time) time.Duration {
+	elapsed := now.Sub(c.TurnStartedAt)
+	until := c.Remaining[turn] + time.Duration(c.Control.DelayMs)*time.Millisecond - elapsed
+	if limit := time.Duration(c.Control.MoveLimitMs) * time.Millisecond; limit > 0 && limit-elapsed < until {
+		until = limit - elapsed
+	}
+	return until
+}
+
+// Punch settles the mover's clock after a move and starts the opponent's.
+// It returns an error if the mover had already flagged.
+func (c *Clock) Punch(mover chess.Color, now time.Time) error {
+	if !c.Running() {
+		c.Start(now)
+		return nil
+	}
+	if c.Flagged(mover, now) {
+		return fmt.Errorf("%s ran out of time", mover)
+	}
+	c.Remaining[mover] = c.RemainingAt(mover, mover, now) + time.Duration(c.Control.IncrementMs)*time.Millisecond
+	c.TurnStartedAt = now
+	return nil
+}
+
+// Replay charges the mover for a move that took the given thinking time,
+// as Punch would have. It rebuilds a clock from stored move times.
+func (c *Clock) Replay(mover chess.Color, taken time.Duration) {
+	charged := max(taken-time.Duration(c.Control.DelayMs)*time.Millisecond, 0)
+	c.Remaining[mover] += time.Duration(c.Control.IncrementMs)*time.Millisecond - charged
+}
+
+// clockSnapshot is the clock as it stood before a ply was played, so a
+// takeback can give the time back.
+type clockSnapshot struct {
+	ply       int // Index of the ply in MoveHistory
+	remaining map[chess.Color]time.Duration
+	running   bool
+}
+
+// snapshot records the clock before ply is played.
+func (c *Clock) snapshot(ply int) clockSnapshot {
+	remaining := make(map[chess.Color]time.Duration, len(c.Remaining))
+	for color, left := range c.Remaining {
+		remaining[color] = left
+	}
+	return clockSnapshot{ply: ply, remaining: remaining, running: c.Running()}
+}
+
+// restore puts the clock back as it was in s. A clock that was running
+// starts the side to move's turn afresh at now.
+func (c *Clock) restore(s clockSnapshot, now time.Time) {
+	for color, left := range s.remaining {
+		c.Remaining[color] = left
+	}
+	c.TurnStartedAt = time.Time{}
+	if s.running {
+		c.Start(now)
+	}
+}
+
+// ClockResponse is the clock as sent in game_state.
+type ClockResponse struct {
+	TimeControl TimeControl `json:"timeControl"`
+	WhiteMs     int64       `json:"whiteMs"`
+	BlackMs     int64       `json:"blackMs"`
+	Running     bool        `json:"running"`
+	// ServerTime lets clients correct for latency when counting down
+	ServerTime time.Time `json:"serverTime"`
+}
+
+func (c *Clock) Response(turn chess.Color, now time.Time) *ClockResponse {
+	return &ClockResponse{
+		TimeControl: c.Control,
+		WhiteMs:     max(c.RemainingAt(chess.White, turn, now), 0).Milliseconds(),
+		BlackMs:     max(c.RemainingAt(chess.Black, turn, now), 0).Milliseconds(),
+		Running:     c.Running(),
+		ServerTime:  now,
+	}
+}
+
+// hasMatingMaterial reports whether color could possibly deliver mate, so
+// the opponent flagging loses rather than draws. A lone king never can. A
+// lone knight or bishop needs one of the opponent's pieces to hem their
+// king in without it being able to take or block the checking piece: a
+// pawn, which could promote to anything, a knight, a bishop on the other
+// color of square or, against a knight, a rook. A queen can always take
+// or block, so a minor piece against a queen still draws.
+func hasMatingMaterial(board *chess.Board, color chess.Color) bool {
+	pieces := board.SquareMap()
+	minors := 0
+	var minor chess.PieceType
+	var minorSquare chess.Square
+	for square, piece := range pieces {
+		if piece.Color() != color {
+			continue
+		}
+		switch piece.Type() {
+		case chess.Pawn, chess.Rook, chess.Queen:
+			return true
+		case chess.Knight, chess.Bishop:
+			minors++
+			minor, minorSquare = piece.Type(), square
+		}
+	}
+	if minors != 1 {
+		return minors > 1
+	}
+
+	for square, piece := range pieces {
+		if piece.Color() == color {
+			continue
+		}
+		switch piece.Type() {
+		case chess.Pawn, chess.Knight:
+			return true
+		case chess.Rook:
+			if minor == chess.Knight {
+				return true
+			}
+		case chess.Bishop:
+			if minor == chess.Knight || lightSquare(square) != lightSquare(minorSquare) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func lightSquare(square chess.Square) bool {
+	return (int(square.File())+int(square.Rank()))%2 == 1
+}
@@ -0,0 +1,114 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeControl_Validate(t *testing.T) {
+	assert.NoError(t, TimeControl{BaseMs: 180000, IncrementMs: 2000}.Validate())
+	assert.NoError(t, TimeControl{BaseMs: 300000, DelayMs: 3000}.Validate())
+	assert.Error(t, TimeControl{}.Validate())
+	assert.Error(t, TimeControl{BaseMs: 1000, IncrementMs: -1}.Validate())
+	assert.Error(t, TimeControl{BaseMs: 1000, IncrementMs: 1000, DelayMs: 1000}.Validate())
+}
+
+func TestClock_FischerIncrement(t *testing.T) {
+	start := time.Now()
+	clock := NewClock(TimeControl{BaseMs: 60000, IncrementMs: 2000})
+	clock.Start(start)
+
+	require.NoError(t, clock.Punch(chess.White, start.Add(5*time.Second)))
+	assert.Equal(t, 57*time.Second, clock.Remaining[chess.White])
+
+	// Black's clock is now running, white's is frozen
+	later := start.Add(15 * time.Second)
+	assert.Equal(t, 50*time.Second, clock.RemainingAt(chess.Black, chess.Black, later))
+	assert.Equal(t, 57*time.Second, clock.RemainingAt(chess.White, chess.Black, later))
+}
+
+func TestClock_BronsteinDelay(t *testing.T) {
+	start := time.Now()
+	clock := NewClock(TimeControl{BaseMs: 60000, DelayMs: 3000})
+	clock.Start(start)
+
+	// A move inside the delay costs nothing
+	require.NoError(t, clock.Punch(chess.White, start.Add(2*time.Second)))
+	assert.Equal(t, 60*time.Second, clock.Remaining[chess.White])
+
+	// Only time beyond the delay is charged
+	require.NoError(t, clock.Punch(chess.Black, start.Add(12*time.Second)))
+	assert.Equal(t, 53*time.Second, clock.Remaining[chess.Black])
+}
+
+func TestClock_Flagged(t *testing.T) {
+	start := time.Now()
+	clock := NewClock(TimeControl{BaseMs: 10000, MoveLimitMs: 4000})
+	clock.Start(start)
+
+	assert.False(t, clock.Flagged(chess.White, start.Add(3*time.Second)))
+	assert.True(t, clock.Flagged(chess.White, start.Add(4*time.Second)), "per-move limit")
+	assert.Equal(t, 4*time.Second, clock.FlagIn(chess.White, start))
+	assert.Error(t, clock.Punch(chess.White, start.Add(5*time.Second)))
+}
+
+func TestHasMatingMaterial(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want bool
+	}{
+		{"lone king", "8/8/8/4k3/8/8/8/4K2q w - - 0 1", false},
+		{"knight against lone king", "8/8/8/4k3/8/8/8/3NK3 w - - 0 1", false},
+		{"two knights", "8/8/8/4k3/8/8/8/2NNK3 w - - 0 1", true},
+		{"rook", "8/8/8/4k3/8/8/8/3RK3 w - - 0 1", true},
+		{"knight against a pawn", "8/8/8/4k3/4p3/8/8/3NK3 w - - 0 1", true},
+		{"knight against a rook", "8/8/8/4k3/4r3/8/8/3NK3 w - - 0 1", true},
+		{"knight against a bishop", "8/8/8/4k3/4b3/8/8/3NK3 w - - 0 1", true},
+		{"knight against a queen", "8/8/8/4k3/4q3/8/8/3NK3 w - - 0 1", false},
+		{"bishop against a knight", "8/8/8/4k3/4n3/8/8/3BK3 w - - 0 1", true},
+		{"bishop against a rook", "8/8/8/4k3/4r3/8/8/3BK3 w - - 0 1", false},
+		{"bishop against a bishop on the other color", "8/8/8/4k3/3b4/8/8/3BK3 w - - 0 1", true},
+		{"bishop against a bishop on the same color", "8/8/8/4k3/4b3/8/8/3BK3 w - - 0 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fen, err := chess.FEN(tt.fen)
+			require.NoError(t, err)
+			board := chess.NewGame(fen).Position().Board()
+			assert.Equal(t, tt.want, hasMatingMaterial(board, chess.White))
+		})
+	}
+}
+
+func TestGameService_FlagFall(t *testing.T) {
+	gs := NewGameService(nil)
+	changed := make(chan string, 1)
+	gs.SetStateChangeHandler(func(gameID string) { changed <- gameID })
+
+	_, err := gs.CreateGame("flag-game", "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, gs.SetTimeControl("flag-game", TimeControl{BaseMs: 50}))
+	require.NoError(t, gs.JoinGame("flag-game", "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame("flag-game", "black-client", "Bob", chess.Black))
+
+	select {
+	case gameID := <-changed:
+		assert.Equal(t, "flag-game", gameID)
+	case <-time.After(time.Second):
+		t.Fatal("flag never fell")
+	}
+
+	state, err := gs.GetGameState("flag-game")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, state.Status)
+	assert.Equal(t, OutcomeTimeout, state.Outcome)
+	assert.Equal(t, "black", state.Winner)
+
+	_, err = gs.MakeMove("flag-game", "white-client", "e2e4")
+	assert.Error(t, err)
+}
@@ -0,0 +1,259 @@
+package game
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxDaysPerMove caps how long a correspondence move may take.
+const maxDaysPerMove = 14
+
+// ErrSeatToken is returned for a correspondence move made with a token that
+// doesn't hold a seat in the game.
+var ErrSeatToken = errors.New("token does not hold a seat in this game")
+
+func validateCorrespondence(gameType GameType, daysPerMove int) error {
+	if gameType != HumanVsHuman {
+		return fmt.Errorf("only human vs human games can be played by correspondence")
+	}
+	if daysPerMove < 1 || daysPerMove > maxDaysPerMove {
+		return fmt.Errorf("days per move must be between 1 and %d", maxDaysPerMove)
+	}
+	return nil
+}
+
+// SetCorrespondence makes a game that hasn't started yet a correspondence
+// game: each move gets daysPerMove days instead of a clock, and between
+// moves the game only lives in the database.
+func (gs *GameService) SetCorrespondence(gameID string, daysPerMove int) error {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+
+	if err := validateCorrespondence(game.Type, daysPerMove); err != nil {
+		return err
+	}
+	if game.Status != StatusWaiting {
+		return fmt.Errorf("cannot make game %s a correspondence game once it has started", gameID)
+	}
+	if game.Clock != nil {
+		return fmt.Errorf("game %s has a time control", gameID)
+	}
+	game.DaysPerMove = daysPerMove
+	gs.persistCorrespondence(game)
+	return nil
+}
+
+// setMoveDeadline gives the side to move DaysPerMove days from now. The
+// caller must hold game.mutex.
+func (gs *GameService) setMoveDeadline(game *GameState, now time.Time) {
+	game.MoveDeadline = now.Add(time.Duration(game.DaysPerMove) * 24 * time.Hour)
+	gs.persistCorrespondence(game)
+}
+
+// IsCorrespondence reports whether the game is in memory and played by
+// correspondence.
+func (gs *GameService) IsCorrespondence(gameID string) bool {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return false
+	}
+
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	return game.DaysPerMove > 0
+}
+
+// LoadCorrespondenceGame brings a correspondence game back into memory from
+// the database, e.g. when one of its players connects. Games already in
+// memory, unknown games and games with a clock are left alone.
+func (gs *GameService) LoadCorrespondenceGame(ctx context.Context, gameID string) error {
+	if _, exists := gs.GetGame(gameID); exists || gs.db == nil {
+		return nil
+	}
+	row, err := gs.db.GetGame(ctx, gameID)
+	if err != nil || row.DaysPerMove == nil {
+		return nil
+	}
+	_, err = gs.loadGameRow(ctx, row)
+	return err
+}
+
+// ReleaseCorrespondenceGame drops a correspondence game from memory once
+// nobody is playing it; the database holds it until its next move. Without
+// a database it is kept. It reports whether the game was released.
+func (gs *GameService) ReleaseCorrespondenceGame(gameID string) bool {
+	if gs.db == nil || !gs.IsCorrespondence(gameID) {
+		return false
+	}
+	gs.DeleteGame(gameID)
+	log.Printf("Released correspondence game %s", gameID)
+	return true
+}
+
+// IssueSeatToken gives playerID's seat in a correspondence game the secret
+// they move over HTTP with, and returns it. A seat is only issued a token
+// once, so asking again doesn't reveal it; other games get none.
+func (gs *GameService) IssueSeatToken(gameID, playerID string) (string, error) {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return "", fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+
+	if game.DaysPerMove == 0 {
+		return "", nil
+	}
+	for color, player := range game.Players {
+		if player.ID != playerID || player.IsAI {
+			continue
+		}
+		if player.Token != "" {
+			return "", nil
+		}
+		player.Token = uuid.NewString()
+		game.Players[color] = player
+		gs.persistSeatTokens(game)
+		return player.Token, nil
+	}
+	return "", fmt.Errorf("player %s is not seated in game %s", playerID, gameID)
+}
+
+// seatByToken returns the ID of the player whose seat token is token. The
+// caller must hold game.mutex.
+func (game *GameState) seatByToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	for _, player := range game.Players {
+		if subtle.ConstantTimeCompare([]byte(player.Token), []byte(token)) == 1 {
+			return player.ID, true
+		}
+	}
+	return "", false
+}
+
+// MakeCorrespondenceMove plays a move in a correspondence game, loading it
+// from the database first, so a player can move without a connection. The
+// player is whoever holds the seat token; ErrSeatToken is returned when
+// nobody does.
+func (gs *GameService) MakeCorrespondenceMove(ctx context.Context, gameID, token, moveStr string) (*MoveResult, error) {
+	if err := gs.LoadCorrespondenceGame(ctx, gameID); err != nil {
+		return nil, err
+	}
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return nil, fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.RLock()
+	correspondence := game.DaysPerMove > 0
+	playerID, seated := game.seatByToken(token)
+	game.mutex.RUnlock()
+	if !correspondence {
+		return nil, fmt.Errorf("game %s is not a correspondence game", gameID)
+	}
+	if !seated {
+		return nil, ErrSeatToken
+	}
+	return gs.MakeMove(gameID, playerID, moveStr)
+}
+
+// CorrespondenceTurns lists the correspondence games waiting on playerID to
+// move, the most urgent first.
+func (gs *GameService) CorrespondenceTurns(ctx context.Context, playerID string) ([]LobbyGame, error) {
+	if gs.db != nil {
+		rows, err := gs.db.GetCorrespondenceTurns(ctx, playerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list games: %v", err)
+		}
+		games := make([]LobbyGame, 0, len(rows))
+		for _, row := range rows {
+			games = append(games, lobbyGameFromRow(row))
+		}
+		return games, nil
+	}
+
+	games := []LobbyGame{}
+	for _, game := range gs.gamesInMemory() {
+		game.mutex.RLock()
+		toMove := game.Players[game.ChessGame.Position().Turn()]
+		if game.DaysPerMove > 0 && game.Status == StatusInProgress && toMove.ID == playerID {
+			games = append(games, game.lobbyGame())
+		}
+		game.mutex.RUnlock()
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].MoveDeadline.Before(*games[j].MoveDeadline)
+	})
+	return games, nil
+}
+
+// ExpireCorrespondence ends the correspondence games whose side to move let
+// its deadline pass before now, loading them from the database as needed.
+// Their players hear about it through the state change handler. It returns
+// the IDs of the games that ran out of time.
+func (gs *GameService) ExpireCorrespondence(ctx context.Context, now time.Time) []string {
+	var games []*GameState
+	if gs.db != nil {
+		rows, err := gs.db.GetExpiredCorrespondenceGames(ctx, now)
+		if err != nil {
+			log.Printf("Warning: Failed to look up expired correspondence games: %v", err)
+			return nil
+		}
+		for i := range rows {
+			game, exists := gs.GetGame(rows[i].GameID)
+			if !exists {
+				if game, err = gs.loadGameRow(ctx, &rows[i]); err != nil {
+					log.Printf("Warning: %v", err)
+					continue
+				}
+			}
+			games = append(games, game)
+		}
+	} else {
+		games = gs.gamesInMemory()
+	}
+
+	var expired []string
+	for _, game := range games {
+		game.mutex.Lock()
+		if game.DaysPerMove > 0 && gs.flagIfExpired(game, now) {
+			expired = append(expired, game.ID)
+		}
+		game.mutex.Unlock()
+	}
+
+	for _, gameID := range expired {
+		gs.notifyStateChange(gameID)
+	}
+	if len(expired) > 0 {
+		log.Printf("%d correspondence games ran out of time", len(expired))
+	}
+	return expired
+}
+
+// gamesInMemory returns the games the service holds.
+func (gs *GameService) gamesInMemory() []*GameState {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+
+	games := make([]*GameState, 0, len(gs.games))
+	for _, game := range gs.games {
+		games = append(games, game)
+	}
+	return games
+}
@@ -0,0 +1,128 @@
+package game
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/hunterMotko/chess-game/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createCorrespondenceGame(t *testing.T, gs *GameService, gameID string, daysPerMove int) {
+	t.Helper()
+	_, err := gs.CreateGame(gameID, "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, gs.SetCorrespondence(gameID, daysPerMove))
+	require.NoError(t, gs.JoinGame(gameID, "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame(gameID, "black-client", "Bob", chess.Black))
+}
+
+func TestGameService_SetCorrespondence(t *testing.T) {
+	gs := NewGameService(nil)
+	_, err := gs.CreateGame("daily", "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+
+	assert.Error(t, gs.SetCorrespondence("daily", 0))
+	assert.Error(t, gs.SetCorrespondence("daily", maxDaysPerMove+1))
+	require.NoError(t, gs.SetCorrespondence("daily", 3))
+	assert.Error(t, gs.SetTimeControl("daily", TimeControl{BaseMs: 60000}), "correspondence games have no clock")
+
+	_, err = gs.CreateGame("ai-daily", "student", HumanVsAI, 1)
+	require.NoError(t, err)
+	assert.Error(t, gs.SetCorrespondence("ai-daily", 3), "the AI doesn't play by correspondence")
+
+	createHumanGame(t, gs, "started")
+	assert.Error(t, gs.SetCorrespondence("started", 3))
+}
+
+func TestGameService_CorrespondenceDeadlines(t *testing.T) {
+	gs := NewGameService(nil)
+	start := time.Now()
+	createCorrespondenceGame(t, gs, "daily", 2)
+
+	state, err := gs.GetGameState("daily")
+	require.NoError(t, err)
+	require.NotNil(t, state.MoveDeadline)
+	assert.WithinDuration(t, start.Add(48*time.Hour), *state.MoveDeadline, time.Minute)
+
+	turns, err := gs.CorrespondenceTurns(context.Background(), "white-client")
+	require.NoError(t, err)
+	require.Len(t, turns, 1)
+	assert.Equal(t, "daily", turns[0].ID)
+	turns, err = gs.CorrespondenceTurns(context.Background(), "black-client")
+	require.NoError(t, err)
+	assert.Empty(t, turns)
+
+	playMoves(t, gs, "daily", "e2e4")
+	turns, err = gs.CorrespondenceTurns(context.Background(), "black-client")
+	require.NoError(t, err)
+	require.Len(t, turns, 1)
+
+	// Days without a move are no reason to abandon the game
+	abandoned, _ := gs.Sweep(time.Now().Add(24*time.Hour), DefaultSweepConfig)
+	assert.Empty(t, abandoned)
+	assert.Empty(t, gs.ExpireCorrespondence(context.Background(), time.Now().Add(24*time.Hour)))
+
+	expired := gs.ExpireCorrespondence(context.Background(), time.Now().Add(49*time.Hour))
+	assert.Equal(t, []string{"daily"}, expired)
+	result, over := gs.GameResult("daily")
+	require.True(t, over)
+	assert.Equal(t, OutcomeTimeout, result.Outcome)
+	assert.Equal(t, "white", result.Winner)
+}
+
+func TestGameService_MakeCorrespondenceMove(t *testing.T) {
+	gs := NewGameService(nil)
+	createCorrespondenceGame(t, gs, "daily", 1)
+	createHumanGame(t, gs, "live")
+
+	token, err := gs.IssueSeatToken("daily", "white-client")
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	again, err := gs.IssueSeatToken("daily", "white-client")
+	require.NoError(t, err)
+	assert.Empty(t, again, "a seat's token is only handed out once")
+
+	_, err = gs.MakeCorrespondenceMove(context.Background(), "daily", "white-client", "e4")
+	assert.ErrorIs(t, err, ErrSeatToken, "a player ID is no token")
+	result, err := gs.MakeCorrespondenceMove(context.Background(), "daily", token, "e4")
+	require.NoError(t, err)
+	assert.Equal(t, "e2e4", result.Move)
+
+	_, err = gs.MakeCorrespondenceMove(context.Background(), "live", token, "e4")
+	assert.Error(t, err, "live games are played over the websocket")
+	_, err = gs.MakeCorrespondenceMove(context.Background(), "missing", token, "e4")
+	assert.Error(t, err)
+
+	assert.False(t, gs.ReleaseCorrespondenceGame("daily"), "without a database the game stays in memory")
+	_, exists := gs.GetGame("daily")
+	assert.True(t, exists)
+}
+
+func TestRestoreGame_Correspondence(t *testing.T) {
+	white, black := "white-client", "black-client"
+	token := "black-token"
+	days := 3
+	deadline := time.Now().Add(36 * time.Hour)
+	row := database.GameRow{
+		GameID:        "daily",
+		GameType:      string(HumanVsHuman),
+		Status:        string(StatusInProgress),
+		WhitePlayerID: &white,
+		BlackPlayerID: &black,
+		DaysPerMove:   &days,
+		MoveDeadline:  &deadline,
+		BlackToken:    &token,
+	}
+
+	game, err := restoreGame(row, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, game.DaysPerMove)
+	assert.Equal(t, deadline, game.MoveDeadline)
+	playerID, seated := game.seatByToken(token)
+	assert.True(t, seated)
+	assert.Equal(t, black, playerID)
+}
@@ -0,0 +1,181 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+const (
+	// fogOfWarSpectatorDelay is the least spectators of a fog of war game
+	// lag behind, so a player can't watch the full board from a second tab
+	fogOfWarSpectatorDelay = time.Minute
+	// hiddenMove stands in for the opponent's moves in a player's history
+	hiddenMove = "?"
+)
+
+// GetGameStateFor returns the game state as the holder of viewer's seat
+// sees it, chess.NoColor for a connection without a seat. It includes the
+// viewer's premoves. In a fog of war game that is still being played the
+// board shows only the viewer's visible squares and the opponent's moves
+// are hidden; every other game looks the same to everyone.
+func (gs *GameService) GetGameStateFor(gameID string, viewer chess.Color) (*GameStateResponse, error) {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return nil, fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+
+	state := game.response()
+	state.Premoves = append([]Premove(nil), game.Premoves[viewer]...)
+	if !game.fogged() {
+		return state, nil
+	}
+
+	pos := game.ChessGame.Position()
+	visible := visibleSquares(pos, viewer)
+	state.FEN = fogFEN(pos, viewer, visible)
+	state.VisibleSquares = make([]string, 0, len(visible))
+	for sq := range visible {
+		state.VisibleSquares = append(state.VisibleSquares, sq.String())
+	}
+	sort.Strings(state.VisibleSquares)
+
+	// Fog of war games start from the standard position, so white moves
+	// on even plies
+	history := make([]string, len(game.MoveHistory))
+	for i, move := range game.MoveHistory {
+		mover := chess.White
+		if i%2 == 1 {
+			mover = chess.Black
+		}
+		history[i] = hiddenMove
+		if mover == viewer {
+			history[i] = move
+		}
+	}
+	state.MoveHistory = history
+	return state, nil
+}
+
+// HidesBoard reports whether gameID is a fog of war game still being
+// played, whose players each see a board of their own.
+func (gs *GameService) HidesBoard(gameID string) bool {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return false
+	}
+
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+
+	return game.fogged()
+}
+
+// fogged reports whether the game's board is hidden from its players. Once
+// a fog of war game is over everyone sees it. The caller must hold
+// game.mutex.
+func (game *GameState) fogged() bool {
+	return game.Variant == VariantFogOfWar && game.Status != StatusCompleted && game.Status != StatusAbandoned
+}
+
+// visibleSquares returns the squares color sees in fog of war: those its
+// pieces stand on and those they could move to, captures included.
+func visibleSquares(pos *chess.Position, color chess.Color) map[chess.Square]bool {
+	board := pos.Board()
+	visible := make(map[chess.Square]bool)
+	pieceAt := func(f, r int) (chess.Square, chess.Piece, bool) {
+		if f < 0 || f > 7 || r < 0 || r > 7 {
+			return chess.NoSquare, chess.NoPiece, false
+		}
+		sq := chess.NewSquare(chess.File(f), chess.Rank(r))
+		return sq, board.Piece(sq), true
+	}
+	steps := func(file, rank int, offsets [][2]int, slide bool) {
+		for _, step := range offsets {
+			for f, r := file+step[0], rank+step[1]; ; f, r = f+step[0], r+step[1] {
+				sq, piece, ok := pieceAt(f, r)
+				if !ok || piece.Color() == color {
+					break
+				}
+				visible[sq] = true
+				if !slide || piece != chess.NoPiece {
+					break
+				}
+			}
+		}
+	}
+
+	for sq, piece := range board.SquareMap() {
+		if piece.Color() != color {
+			continue
+		}
+		visible[sq] = true
+		file, rank := int(sq.File()), int(sq.Rank())
+
+		switch piece.Type() {
+		case chess.Pawn:
+			forward, startRank := 1, 1
+			if color == chess.Black {
+				forward, startRank = -1, 6
+			}
+			if ahead, occupant, ok := pieceAt(file, rank+forward); ok && occupant == chess.NoPiece {
+				visible[ahead] = true
+				if twoAhead, occupant, ok := pieceAt(file, rank+2*forward); ok && rank == startRank && occupant == chess.NoPiece {
+					visible[twoAhead] = true
+				}
+			}
+			for _, side := range []int{-1, 1} {
+				target, occupant, ok := pieceAt(file+side, rank+forward)
+				if ok && (occupant.Color() == color.Other() || (target == pos.EnPassantSquare() && pos.Turn() == color)) {
+					visible[target] = true
+				}
+			}
+		case chess.Knight:
+			steps(file, rank, knightSteps, false)
+		case chess.King:
+			steps(file, rank, kingSteps, false)
+		case chess.Bishop:
+			steps(file, rank, diagonalSteps, true)
+		case chess.Rook:
+			steps(file, rank, straightSteps, true)
+		case chess.Queen:
+			steps(file, rank, diagonalSteps, true)
+			steps(file, rank, straightSteps, true)
+		}
+	}
+	return visible
+}
+
+// fogFEN renders pos with only the visible squares' pieces, the viewer's
+// own castling rights and, on their turn, the en passant square.
+func fogFEN(pos *chess.Position, viewer chess.Color, visible map[chess.Square]bool) string {
+	squares := make(map[chess.Square]chess.Piece)
+	for sq, piece := range pos.Board().SquareMap() {
+		if visible[sq] {
+			squares[sq] = piece
+		}
+	}
+
+	fields := strings.Fields(pos.String())
+	fields[0] = chess.NewBoard(squares).String()
+	castling := strings.Map(func(r rune) rune {
+		if (viewer == chess.White && strings.ContainsRune("KQ", r)) || (viewer == chess.Black && strings.ContainsRune("kq", r)) {
+			return r
+		}
+		return -1
+	}, fields[2])
+	if castling == "" {
+		castling = "-"
+	}
+	fields[2] = castling
+	if pos.Turn() != viewer {
+		fields[3] = "-"
+	}
+	return strings.Join(fields, " ")
+}
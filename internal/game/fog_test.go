@@ -0,0 +1,61 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFogOfWar_StartPosition(t *testing.T) {
+	gs := playVariantGame(t, VariantFogOfWar, nil)
+
+	white, err := gs.GetGameStateFor("variant-game", chess.White)
+	require.NoError(t, err)
+	assert.Equal(t, "8/8/8/8/8/8/PPPPPPPP/RNBQKBNR w KQ - 0 1", white.FEN)
+	assert.Len(t, white.VisibleSquares, 32, "white sees its first four ranks")
+	assert.Contains(t, white.VisibleSquares, "e4")
+	assert.NotContains(t, white.VisibleSquares, "e5")
+
+	black, err := gs.GetGameStateFor("variant-game", chess.Black)
+	require.NoError(t, err)
+	assert.Equal(t, "rnbqkbnr/pppppppp/8/8/8/8/8/8 w kq - 0 1", black.FEN)
+}
+
+func TestFogOfWar_HidesOpponentMoves(t *testing.T) {
+	gs := playVariantGame(t, VariantFogOfWar, []string{"e2e4", "d7d5"})
+
+	white, err := gs.GetGameStateFor("variant-game", chess.White)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e2e4", hiddenMove}, white.MoveHistory)
+	assert.Contains(t, white.FEN, "/3p4/4P3/", "the e4 pawn can take on d5")
+	assert.NotContains(t, white.FEN, "k", "the black king is out of sight")
+
+	black, err := gs.GetGameStateFor("variant-game", chess.Black)
+	require.NoError(t, err)
+	assert.Equal(t, []string{hiddenMove, "d7d5"}, black.MoveHistory)
+
+	// Spectators get the whole game, late
+	full, err := gs.GetGameState("variant-game")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e2e4", "d7d5"}, full.MoveHistory)
+	assert.Empty(t, full.VisibleSquares)
+	assert.True(t, gs.HidesBoard("variant-game"))
+	assert.Equal(t, fogOfWarSpectatorDelay, gs.SpectatorDelay("variant-game"))
+	require.NoError(t, gs.SetSpectatorDelay("variant-game", 2*time.Minute))
+	assert.Equal(t, 2*time.Minute, gs.SpectatorDelay("variant-game"))
+}
+
+func TestFogOfWar_RevealedWhenOver(t *testing.T) {
+	gs := playVariantGame(t, VariantFogOfWar, []string{"f2f3", "e7e5", "g2g4", "d8h4"})
+
+	state, err := gs.GetGameStateFor("variant-game", chess.White)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, state.Status)
+	assert.Equal(t, "black", state.Winner)
+	assert.Equal(t, []string{"f2f3", "e7e5", "g2g4", "d8h4"}, state.MoveHistory)
+	assert.Empty(t, state.VisibleSquares)
+	assert.False(t, gs.HidesBoard("variant-game"))
+}
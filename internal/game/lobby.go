@@ -0,0 +1,303 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+	"github.com/hunterMotko/chess-game/internal/database"
+)
+
+// maxLobbyGames caps how many games one lobby listing returns.
+const maxLobbyGames = 100
+
+// LobbyRequest describes a game opened from the lobby.
+type LobbyRequest struct {
+	Type       GameType `json:"type"` // human_vs_human or human_vs_ai
+	PlayerID   string   `json:"playerId"`
+	PlayerName string   `json:"playerName"`
+	// Color is the creator's seat: "white", "black" or "random"
+	Color       string       `json:"color"`
+	Difficulty  int          `json:"difficulty"` // AI level, human_vs_ai only
+	TimeControl *TimeControl `json:"timeControl"`
+	// Variant to play, e.g. "chess960". Chess960 games start from
+	// Chess960Position, 0-959, or a random one when it is left out.
+	Variant          string `json:"variant"`
+	Chess960Position *int   `json:"chess960Position"`
+	// DaysPerMove makes a correspondence game, human_vs_human only, in
+	// place of a time control
+	DaysPerMove int `json:"daysPerMove"`
+}
+
+// LobbyGame is a game as the lobby lists it. Players are only named; their
+// IDs are what binds a connection to a seat and stay private.
+type LobbyGame struct {
+	ID          string       `json:"id"`
+	Type        GameType     `json:"type"`
+	Variant     Variant      `json:"variant"`
+	Status      GameStatus   `json:"status"`
+	TimeControl *TimeControl `json:"timeControl,omitempty"`
+	White       string       `json:"white,omitempty"`
+	Black       string       `json:"black,omitempty"`
+	// OpenSeat is the color still free for a second player, if any
+	OpenSeat  string    `json:"openSeat,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// DaysPerMove is set for correspondence games, MoveDeadline once they
+	// are in progress
+	DaysPerMove  int        `json:"daysPerMove,omitempty"`
+	MoveDeadline *time.Time `json:"moveDeadline,omitempty"`
+	// Token is only set in the reply to the player taking a seat in a
+	// correspondence game: the secret they move over HTTP with
+	Token string `json:"token,omitempty"`
+}
+
+// OpenGame creates a game under a fresh server-issued ID and seats the
+// creator in it. A human vs human game then waits in the lobby for an
+// opponent; a game against the AI starts straight away.
+func (gs *GameService) OpenGame(req LobbyRequest) (*LobbyGame, error) {
+	if req.PlayerID == "" {
+		return nil, fmt.Errorf("playerId is required")
+	}
+	if req.Type == "" {
+		req.Type = HumanVsHuman
+	}
+	if req.Type != HumanVsHuman && req.Type != HumanVsAI {
+		return nil, fmt.Errorf("games of type %q can't be opened from the lobby", req.Type)
+	}
+	variant, err := ParseVariant(req.Variant)
+	if err != nil {
+		return nil, err
+	}
+	if req.TimeControl != nil {
+		if err := req.TimeControl.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if req.DaysPerMove != 0 {
+		if err := validateCorrespondence(req.Type, req.DaysPerMove); err != nil {
+			return nil, err
+		}
+		if req.TimeControl != nil {
+			return nil, fmt.Errorf("correspondence games have no time control")
+		}
+	}
+	var color chess.Color
+	switch req.Color {
+	case "white":
+		color = chess.White
+	case "black":
+		color = chess.Black
+	case "", "random":
+		color = []chess.Color{chess.White, chess.Black}[rand.Intn(2)]
+	default:
+		return nil, fmt.Errorf("unknown color %q", req.Color)
+	}
+
+	gameID := uuid.NewString()
+	if _, err := gs.CreateGame(gameID, req.PlayerID, req.Type, req.Difficulty); err != nil {
+		return nil, err
+	}
+	if err := gs.setUpLobbyGame(gameID, req, variant, color); err != nil {
+		gs.DeleteGame(gameID)
+		if gs.db != nil {
+			if dbErr := gs.db.DeleteGame(context.Background(), gameID); dbErr != nil {
+				log.Printf("Warning: Failed to delete game %s from database: %v", gameID, dbErr)
+			}
+		}
+		return nil, err
+	}
+
+	log.Printf("Lobby game %s opened by %s", gameID, req.PlayerID)
+	return gs.seatedLobbyGame(gameID, req.PlayerID)
+}
+
+// setUpLobbyGame applies a lobby request to the game just created for it.
+func (gs *GameService) setUpLobbyGame(gameID string, req LobbyRequest, variant Variant, color chess.Color) error {
+	if variant != VariantStandard {
+		if err := gs.SetVariant(gameID, variant, req.Chess960Position); err != nil {
+			return err
+		}
+	}
+	if req.TimeControl != nil {
+		if err := gs.SetTimeControl(gameID, *req.TimeControl); err != nil {
+			return err
+		}
+	}
+	if req.DaysPerMove != 0 {
+		if err := gs.SetCorrespondence(gameID, req.DaysPerMove); err != nil {
+			return err
+		}
+	}
+	return gs.JoinGame(gameID, req.PlayerID, req.PlayerName, color)
+}
+
+// JoinOpenGame seats playerID in a game found in the lobby, at color or at
+// whichever seat is free for chess.NoColor. The players already connected
+// to the game hear about it through the state change handler.
+func (gs *GameService) JoinOpenGame(gameID, playerID, playerName string, color chess.Color) (*LobbyGame, error) {
+	if playerID == "" {
+		return nil, fmt.Errorf("playerId is required")
+	}
+	listed, err := gs.lobbyGame(gameID)
+	if err != nil {
+		return nil, err
+	}
+	if listed.Status != StatusWaiting {
+		return nil, fmt.Errorf("game %s is no longer open", gameID)
+	}
+	if err := gs.JoinGame(gameID, playerID, playerName, color); err != nil {
+		return nil, err
+	}
+	gs.notifyStateChange(gameID)
+	return gs.seatedLobbyGame(gameID, playerID)
+}
+
+// ListGames returns the newest games with the given status, e.g. the open
+// games waiting for an opponent. The games table is the source when the
+// service has a database, since it outlives restarts; otherwise the games
+// in memory are listed.
+func (gs *GameService) ListGames(ctx context.Context, status GameStatus, limit int) ([]LobbyGame, error) {
+	if limit <= 0 || limit > maxLobbyGames {
+		limit = maxLobbyGames
+	}
+
+	if gs.db != nil {
+		rows, err := gs.db.GetGamesByStatus(ctx, string(status), limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list games: %v", err)
+		}
+		games := make([]LobbyGame, 0, len(rows))
+		for _, row := range rows {
+			games = append(games, lobbyGameFromRow(row))
+		}
+		return games, nil
+	}
+
+	gs.mutex.RLock()
+	states := make([]*GameState, 0, len(gs.games))
+	for _, game := range gs.games {
+		states = append(states, game)
+	}
+	gs.mutex.RUnlock()
+
+	games := []LobbyGame{}
+	for _, game := range states {
+		game.mutex.RLock()
+		if game.Status == status {
+			games = append(games, game.lobbyGame())
+		}
+		game.mutex.RUnlock()
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].CreatedAt.After(games[j].CreatedAt)
+	})
+	if len(games) > limit {
+		games = games[:limit]
+	}
+	return games, nil
+}
+
+func (gs *GameService) lobbyGame(gameID string) (*LobbyGame, error) {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return nil, fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+
+	listed := game.lobbyGame()
+	return &listed, nil
+}
+
+// seatedLobbyGame lists the game for the player who just took a seat in it,
+// with their seat token if it is a correspondence game.
+func (gs *GameService) seatedLobbyGame(gameID, playerID string) (*LobbyGame, error) {
+	token, err := gs.IssueSeatToken(gameID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	listed, err := gs.lobbyGame(gameID)
+	if err != nil {
+		return nil, err
+	}
+	listed.Token = token
+	return listed, nil
+}
+
+// lobbyGame lists the game. The caller must hold game.mutex.
+func (game *GameState) lobbyGame() LobbyGame {
+	listed := LobbyGame{
+		ID:        game.ID,
+		Type:      game.Type,
+		Variant:   game.Variant,
+		Status:    game.Status,
+		White:     game.Players[chess.White].Name,
+		Black:     game.Players[chess.Black].Name,
+		CreatedAt: game.CreatedAt,
+	}
+	if game.Clock != nil {
+		tc := game.Clock.Control
+		listed.TimeControl = &tc
+	}
+	if game.DaysPerMove > 0 {
+		listed.DaysPerMove = game.DaysPerMove
+		if game.Status == StatusInProgress {
+			deadline := game.MoveDeadline
+			listed.MoveDeadline = &deadline
+		}
+	}
+	_, whiteSeated := game.Players[chess.White]
+	_, blackSeated := game.Players[chess.Black]
+	listed.OpenSeat = openSeat(whiteSeated, blackSeated, game.Status)
+	return listed
+}
+
+func lobbyGameFromRow(row database.GameRow) LobbyGame {
+	listed := LobbyGame{
+		ID:        row.GameID,
+		Type:      GameType(row.GameType),
+		Variant:   Variant(row.Variant),
+		Status:    GameStatus(row.Status),
+		CreatedAt: row.CreatedAt,
+	}
+	if row.WhitePlayerName != nil {
+		listed.White = *row.WhitePlayerName
+	}
+	if row.BlackPlayerName != nil {
+		listed.Black = *row.BlackPlayerName
+	}
+	if row.TimeControl != nil {
+		var tc TimeControl
+		if err := json.Unmarshal([]byte(*row.TimeControl), &tc); err == nil {
+			listed.TimeControl = &tc
+		}
+	}
+	if row.DaysPerMove != nil {
+		listed.DaysPerMove = *row.DaysPerMove
+		if listed.Status == StatusInProgress {
+			listed.MoveDeadline = row.MoveDeadline
+		}
+	}
+	listed.OpenSeat = openSeat(row.WhitePlayerID != nil, row.BlackPlayerID != nil, listed.Status)
+	return listed
+}
+
+// openSeat names the seat a second player can still take in a waiting game.
+func openSeat(whiteSeated, blackSeated bool, status GameStatus) string {
+	switch {
+	case status != StatusWaiting || (whiteSeated && blackSeated):
+		return ""
+	case !whiteSeated && blackSeated:
+		return "white"
+	case whiteSeated && !blackSeated:
+		return "black"
+	}
+	return "any"
+}
@@ -0,0 +1,72 @@
+package game
+
+import (
+	"context"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameService_OpenGame(t *testing.T) {
+	gs := NewGameService(nil)
+
+	opened, err := gs.OpenGame(LobbyRequest{
+		PlayerID:    "alice-client",
+		PlayerName:  "Alice",
+		Color:       "black",
+		TimeControl: &TimeControl{BaseMs: 180000, IncrementMs: 2000},
+		Variant:     "chess960",
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, opened.ID)
+	assert.Equal(t, HumanVsHuman, opened.Type)
+	assert.Equal(t, VariantChess960, opened.Variant)
+	assert.Equal(t, StatusWaiting, opened.Status)
+	assert.Equal(t, "Alice", opened.Black)
+	assert.Equal(t, "white", opened.OpenSeat)
+	assert.Equal(t, int64(180000), opened.TimeControl.BaseMs)
+
+	seeks, err := gs.ListGames(context.Background(), StatusWaiting, 0)
+	require.NoError(t, err)
+	require.Len(t, seeks, 1)
+	assert.Equal(t, opened.ID, seeks[0].ID)
+
+	joined, err := gs.JoinOpenGame(opened.ID, "bob-client", "Bob", chess.NoColor)
+	require.NoError(t, err)
+	assert.Equal(t, StatusInProgress, joined.Status)
+	assert.Equal(t, "Bob", joined.White)
+	assert.Empty(t, joined.OpenSeat)
+
+	_, err = gs.JoinOpenGame(opened.ID, "carol-client", "Carol", chess.NoColor)
+	assert.Error(t, err, "the game is full")
+
+	seeks, err = gs.ListGames(context.Background(), StatusWaiting, 0)
+	require.NoError(t, err)
+	assert.Empty(t, seeks)
+}
+
+func TestGameService_OpenGame_Invalid(t *testing.T) {
+	gs := NewGameService(nil)
+
+	position := 960
+	tests := map[string]LobbyRequest{
+		"no player":       {},
+		"AI vs AI":        {PlayerID: "p", Type: AIVsAI},
+		"unknown variant": {PlayerID: "p", Variant: "bughouse"},
+		"unknown color":   {PlayerID: "p", Color: "green"},
+		"bad clock":       {PlayerID: "p", TimeControl: &TimeControl{}},
+		"bad position":    {PlayerID: "p", Variant: "chess960", Chess960Position: &position},
+	}
+	for name, req := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := gs.OpenGame(req)
+			assert.Error(t, err)
+		})
+	}
+
+	games, err := gs.ListGames(context.Background(), StatusWaiting, 0)
+	require.NoError(t, err)
+	assert.Empty(t, games, "failed games are cleaned up")
+}
@@ -0,0 +1,187 @@
+package game
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+)
+
+// MoveInput is a move as a client sends it: Move in UCI ("e7e8q"), SAN
+// ("exd8=Q+") or LAN ("Ng1-f3"), or the squares and promotion piece
+// spelled out.
+type MoveInput struct {
+	Move      string `json:"move"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Promotion string `json:"promotion"` // "q" or "queen", "r", "b" or "n"
+}
+
+var promotionLetters = map[string]string{
+	"q": "q", "queen": "q",
+	"r": "r", "rook": "r",
+	"b": "b", "bishop": "b",
+	"n": "n", "knight": "n",
+}
+
+// Notation returns the move as a single string for decodeMove.
+func (in MoveInput) Notation() (string, error) {
+	if in.Move != "" {
+		return in.Move, nil
+	}
+	if in.From == "" || in.To == "" {
+		return "", fmt.Errorf("a move needs either move or from and to")
+	}
+	notation := strings.ToLower(in.From + in.To)
+	if in.Promotion != "" {
+		letter, ok := promotionLetters[strings.ToLower(in.Promotion)]
+		if !ok {
+			return "", fmt.Errorf("unknown promotion piece %q", in.Promotion)
+		}
+		notation += letter
+	}
+	return notation, nil
+}
+
+// MoveErrorCode says why a move was rejected.
+type MoveErrorCode string
+
+const (
+	MoveUnreadable MoveErrorCode = "unreadable" // Not UCI, SAN or LAN
+	MoveIllegal    MoveErrorCode = "illegal"
+	// MoveAmbiguous matches more than one legal move, e.g. a promotion
+	// without its piece or a SAN move missing its disambiguation
+	MoveAmbiguous MoveErrorCode = "ambiguous"
+)
+
+// LegalMove is a legal move in both notations.
+type LegalMove struct {
+	UCI string `json:"uci"`
+	SAN string `json:"san"`
+}
+
+// MoveError rejects a move, listing the legal moves the player may have
+// meant: the matches of an ambiguous move, or the moves of the piece on the
+// square an illegal move started from, or else every legal move.
+type MoveError struct {
+	Input        string        `json:"input"`
+	Code         MoveErrorCode `json:"code"`
+	Reason       string        `json:"reason"`
+	Alternatives []LegalMove   `json:"alternatives,omitempty"`
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("invalid move: %s", e.Reason)
+}
+
+// movePattern reads SAN, LAN and UCI alike once check marks, annotations
+// and the promotion's "=" are stripped: an optional piece letter, whatever
+// part of the from-square is given, an optional capture or dash, the
+// to-square and an optional promotion piece.
+var movePattern = regexp.MustCompile(`^([KQRBN])?([a-h])?([1-8])?[-x:]?([a-h][1-8])([QRBNqrbn])?$`)
+
+// decodeMove finds the move input means among the legal moves of pos. A
+// move without a piece letter is a pawn move unless it names its whole
+// from-square, as UCI and LAN do.
+func decodeMove(pos *chess.Position, legal []chess.Move, input string) (*chess.Move, error) {
+	notation := strings.TrimRight(strings.TrimSpace(input), "+#!?")
+	notation = strings.ReplaceAll(notation, "=", "")
+
+	var candidates []chess.Move
+	fromSquare := ""
+	switch castle := strings.ReplaceAll(notation, "0", "O"); castle {
+	case "O-O", "O-O-O":
+		for _, move := range legal {
+			if strings.TrimRight(chess.AlgebraicNotation{}.Encode(pos, &move), "+#") == castle {
+				candidates = append(candidates, move)
+			}
+		}
+	default:
+		parts := movePattern.FindStringSubmatch(notation)
+		if parts == nil {
+			return nil, &MoveError{
+				Input:  input,
+				Code:   MoveUnreadable,
+				Reason: fmt.Sprintf("%q is not a UCI, SAN or LAN move", input),
+			}
+		}
+		piece, file, rank, to, promotion := parts[1], parts[2], parts[3], parts[4], strings.ToLower(parts[5])
+		if file != "" && rank != "" {
+			fromSquare = file + rank
+		}
+		for _, move := range legal {
+			uci := move.String()
+			moved := pos.Board().Piece(move.S1()).Type()
+			switch {
+			case uci[2:4] != to,
+				file != "" && uci[0:1] != file,
+				rank != "" && uci[1:2] != rank,
+				promotion != "" && (len(uci) != 5 || uci[4:] != promotion):
+				continue
+			case piece != "" && moved != pieceTypeOf(piece):
+				continue
+			case piece == "" && fromSquare == "" && moved != chess.Pawn:
+				continue
+			}
+			candidates = append(candidates, move)
+		}
+	}
+
+	switch len(candidates) {
+	case 1:
+		return &candidates[0], nil
+	case 0:
+		alternatives := legal
+		if fromSquare != "" {
+			var fromMoves []chess.Move
+			for _, move := range legal {
+				if move.S1().String() == fromSquare {
+					fromMoves = append(fromMoves, move)
+				}
+			}
+			if len(fromMoves) > 0 {
+				alternatives = fromMoves
+			}
+		}
+		return nil, &MoveError{
+			Input:        input,
+			Code:         MoveIllegal,
+			Reason:       fmt.Sprintf("%s is not a legal move", input),
+			Alternatives: listMoves(pos, alternatives),
+		}
+	}
+	return nil, &MoveError{
+		Input:        input,
+		Code:         MoveAmbiguous,
+		Reason:       fmt.Sprintf("%s could be %d different moves", input, len(candidates)),
+		Alternatives: listMoves(pos, candidates),
+	}
+}
+
+func pieceTypeOf(letter string) chess.PieceType {
+	switch letter {
+	case "K":
+		return chess.King
+	case "Q":
+		return chess.Queen
+	case "R":
+		return chess.Rook
+	case "B":
+		return chess.Bishop
+	case "N":
+		return chess.Knight
+	}
+	return chess.NoPieceType
+}
+
+func listMoves(pos *chess.Position, moves []chess.Move) []LegalMove {
+	listed := make([]LegalMove, len(moves))
+	for i := range moves {
+		listed[i] = LegalMove{
+			UCI: moves[i].String(),
+			SAN: chess.AlgebraicNotation{}.Encode(pos, &moves[i]),
+		}
+	}
+	return listed
+}
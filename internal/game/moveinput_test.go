@@ -0,0 +1,109 @@
+package game
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decode decodes input in the position fen, standard if empty.
+func decode(t *testing.T, fen, input string) (string, error) {
+	t.Helper()
+	chessGame, err := newChessGame(fen)
+	require.NoError(t, err)
+	move, err := decodeMove(chessGame.Position(), chessGame.ValidMoves(), input)
+	if err != nil {
+		return "", err
+	}
+	return move.String(), nil
+}
+
+func TestDecodeMove_Notations(t *testing.T) {
+	for input, want := range map[string]string{
+		"e2e4":   "e2e4", // UCI
+		"e4":     "e2e4", // SAN
+		"Nf3":    "g1f3",
+		"Nf3!?":  "g1f3",
+		"Ng1-f3": "g1f3", // LAN
+		"g1-f3":  "g1f3",
+		"Ngf3":   "g1f3", // Over-disambiguated SAN
+	} {
+		got, err := decode(t, "", input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	got, err := decode(t, "r3k3/8/8/8/8/8/8/4K2R w Kq - 0 1", "0-0+")
+	require.NoError(t, err)
+	assert.Equal(t, "e1g1", got)
+}
+
+func TestDecodeMove_Promotion(t *testing.T) {
+	const fen = "3r4/4P3/8/8/8/8/k7/4K3 w - - 0 1"
+	for input, want := range map[string]string{
+		"e7e8q":  "e7e8q",
+		"e7e8N":  "e7e8n",
+		"e8=Q":   "e7e8q",
+		"exd8=R": "e7d8r",
+		"e7xd8b": "e7d8b",
+	} {
+		got, err := decode(t, fen, input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"e7e8", "e8", "exd8"} {
+		_, err := decode(t, fen, input)
+		var moveErr *MoveError
+		require.True(t, errors.As(err, &moveErr), input)
+		assert.Equal(t, MoveAmbiguous, moveErr.Code, input)
+		assert.Len(t, moveErr.Alternatives, 4, "one per promotion piece")
+	}
+
+	notation, err := MoveInput{From: "e7", To: "e8", Promotion: "Queen"}.Notation()
+	require.NoError(t, err)
+	assert.Equal(t, "e7e8q", notation)
+	_, err = MoveInput{From: "e7", To: "e8", Promotion: "king"}.Notation()
+	assert.Error(t, err)
+}
+
+func TestDecodeMove_Rejected(t *testing.T) {
+	var moveErr *MoveError
+
+	_, err := decode(t, "", "e2e5")
+	require.True(t, errors.As(err, &moveErr))
+	assert.Equal(t, MoveIllegal, moveErr.Code)
+	assert.ElementsMatch(t, []LegalMove{{UCI: "e2e3", SAN: "e3"}, {UCI: "e2e4", SAN: "e4"}}, moveErr.Alternatives)
+
+	_, err = decode(t, "", "Ke2")
+	require.True(t, errors.As(err, &moveErr))
+	assert.Equal(t, MoveIllegal, moveErr.Code)
+	assert.Len(t, moveErr.Alternatives, 20, "every legal move")
+
+	// Knights on b1 and f3 can both reach d2
+	_, err = decode(t, "4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", "Nd2")
+	require.True(t, errors.As(err, &moveErr))
+	assert.Equal(t, MoveAmbiguous, moveErr.Code)
+	assert.ElementsMatch(t, []string{"b1d2", "f3d2"}, []string{moveErr.Alternatives[0].UCI, moveErr.Alternatives[1].UCI})
+
+	_, err = decode(t, "", "castle kingside")
+	require.True(t, errors.As(err, &moveErr))
+	assert.Equal(t, MoveUnreadable, moveErr.Code)
+}
+
+func TestGameService_MakeMove_RecordsUCI(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "san-game")
+
+	result, err := gs.MakeMove("san-game", "white-client", "Nf3")
+	require.NoError(t, err)
+	assert.Equal(t, "g1f3", result.Move)
+	_, err = gs.MakeMove("san-game", "black-client", "d7-d5")
+	require.NoError(t, err)
+
+	state, err := gs.GetGameState("san-game")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"g1f3", "d7d5"}, state.MoveHistory)
+}
@@ -0,0 +1,414 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/hunterMotko/chess-game/internal/database"
+)
+
+// persistGame writes the game's status, position and result to the games
+// table. The caller must hold game.mutex.
+func (gs *GameService) persistGame(game *GameState) {
+	if gs.db == nil {
+		return
+	}
+
+	var winner, outcome *string
+	if game.Winner != "" {
+		winner = &game.Winner
+	}
+	if game.Outcome != "" {
+		o := string(game.Outcome)
+		outcome = &o
+	}
+	var variantState *string
+	if game.VariantState != nil {
+		stateJSON, _ := json.Marshal(game.VariantState)
+		state := string(stateJSON)
+		variantState = &state
+	}
+	completedAt := &game.CompletedAt
+	if game.CompletedAt.IsZero() {
+		completedAt = nil
+	}
+
+	err := gs.db.UpdateGame(context.Background(), game.ID, string(game.Status), game.fen(),
+		game.pgn(), colorName(game.ChessGame.Position().Turn()), len(game.MoveHistory),
+		winner, outcome, variantState, completedAt)
+	if err != nil {
+		log.Printf("Warning: Failed to save game %s: %v", game.ID, err)
+	}
+}
+
+// persistPlayers writes the game's seats to the games table. The caller
+// must hold game.mutex.
+func (gs *GameService) persistPlayers(game *GameState) {
+	if gs.db == nil {
+		return
+	}
+
+	seat := func(color chess.Color) (id, name *string) {
+		player, seated := game.Players[color]
+		if !seated {
+			return nil, nil
+		}
+		return &player.ID, &player.Name
+	}
+	whiteID, whiteName := seat(chess.White)
+	blackID, blackName := seat(chess.Black)
+
+	err := gs.db.UpdateGamePlayers(context.Background(), game.ID, whiteID, whiteName, blackID, blackName)
+	if err != nil {
+		log.Printf("Warning: Failed to save players of game %s: %v", game.ID, err)
+	}
+}
+
+// persistMove records the ply that was just played. The caller must hold
+// game.mutex.
+func (gs *GameService) persistMove(game *GameState, move, san string, timeTaken *int) {
+	if gs.db == nil {
+		return
+	}
+
+	moveNumber, mover := lastMove(game.ChessGame.Position())
+	err := gs.db.SaveGamePly(context.Background(), game.ID, moveNumber, colorName(mover), move, san,
+		game.fen(), timeTaken)
+	if err != nil {
+		log.Printf("Warning: Failed to save move %s of game %s: %v", move, game.ID, err)
+	}
+}
+
+// persistCorrespondence writes the game's days per move and current move
+// deadline to the games table. The caller must hold game.mutex.
+func (gs *GameService) persistCorrespondence(game *GameState) {
+	if gs.db == nil {
+		return
+	}
+
+	var moveDeadline *time.Time
+	if !game.MoveDeadline.IsZero() {
+		moveDeadline = &game.MoveDeadline
+	}
+	err := gs.db.UpdateGameCorrespondence(context.Background(), game.ID, game.DaysPerMove, moveDeadline)
+	if err != nil {
+		log.Printf("Warning: Failed to save move deadline of game %s: %v", game.ID, err)
+	}
+}
+
+// persistSeatTokens writes the seat tokens of a correspondence game to the
+// games table. The caller must hold game.mutex.
+func (gs *GameService) persistSeatTokens(game *GameState) {
+	if gs.db == nil {
+		return
+	}
+
+	token := func(color chess.Color) *string {
+		if player, seated := game.Players[color]; seated && player.Token != "" {
+			return &player.Token
+		}
+		return nil
+	}
+	if err := gs.db.UpdateGameSeatTokens(context.Background(), game.ID, token(chess.White), token(chess.Black)); err != nil {
+		log.Printf("Warning: Failed to save seat tokens of game %s: %v", game.ID, err)
+	}
+}
+
+// persistOptions writes the game's spectator delay and whether its AI
+// grants every takeback to the games table. The caller must hold
+// game.mutex.
+func (gs *GameService) persistOptions(game *GameState) {
+	if gs.db == nil {
+		return
+	}
+
+	err := gs.db.UpdateGameOptions(context.Background(), game.ID, game.SpectatorDelay.Milliseconds(), game.AutoAcceptTakebacks)
+	if err != nil {
+		log.Printf("Warning: Failed to save options of game %s: %v", game.ID, err)
+	}
+}
+
+// persistTournament writes the tournament that paired the game to the
+// games table. The caller must hold game.mutex.
+func (gs *GameService) persistTournament(game *GameState) {
+	if gs.db == nil {
+		return
+	}
+
+	if err := gs.db.UpdateGameTournament(context.Background(), game.ID, game.TournamentID); err != nil {
+		log.Printf("Warning: Failed to save tournament of game %s: %v", game.ID, err)
+	}
+}
+
+// storedAIMatch is an AI match's settings as the games table holds them.
+type storedAIMatch struct {
+	White  *AISettings `json:"white,omitempty"`
+	Black  *AISettings `json:"black,omitempty"`
+	PaceMs int64       `json:"paceMs,omitempty"`
+}
+
+// persistAISettings writes each side's engine settings of an AI match, and
+// its pace, to the games table. The caller must hold game.mutex.
+func (gs *GameService) persistAISettings(game *GameState) {
+	if gs.db == nil {
+		return
+	}
+
+	settings := storedAIMatch{PaceMs: game.AIMatchPace.Milliseconds()}
+	if white, ok := game.AISettings[chess.White]; ok {
+		settings.White = &white
+	}
+	if black, ok := game.AISettings[chess.Black]; ok {
+		settings.Black = &black
+	}
+	settingsJSON, _ := json.Marshal(settings)
+	if err := gs.db.UpdateGameAISettings(context.Background(), game.ID, string(settingsJSON)); err != nil {
+		log.Printf("Warning: Failed to save AI settings of game %s: %v", game.ID, err)
+	}
+}
+
+// persistTakeback drops the plies that were taken back. The caller must
+// hold game.mutex.
+func (gs *GameService) persistTakeback(game *GameState) {
+	if gs.db == nil {
+		return
+	}
+
+	moveNumber, mover := lastMove(game.ChessGame.Position())
+	if err := gs.db.TruncateGameMoves(context.Background(), game.ID, moveNumber, colorName(mover)); err != nil {
+		log.Printf("Warning: Failed to remove taken back moves of game %s: %v", game.ID, err)
+	}
+	gs.persistGame(game)
+}
+
+// RestoreGames loads every unfinished game from the database so a restart
+// doesn't wipe out live games. Correspondence games stay in the database
+// until they are played. Tournament games are abandoned instead: their
+// tournament lived in memory and is gone, so nothing could score them. It
+// returns the IDs of the restored games.
+func (gs *GameService) RestoreGames(ctx context.Context) ([]string, error) {
+	if gs.db == nil {
+		return nil, nil
+	}
+
+	rows, err := gs.db.GetActiveGames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load active games: %v", err)
+	}
+
+	var restored []string
+	for _, row := range rows {
+		if row.DaysPerMove != nil {
+			continue // Correspondence games are loaded when they are played
+		}
+		moves, err := gs.db.GetGameMoves(ctx, row.ID)
+		if err != nil {
+			log.Printf("Warning: Failed to load moves of game %s: %v", row.GameID, err)
+			continue
+		}
+		game, err := restoreGame(row, moves)
+		if err != nil {
+			log.Printf("Warning: Could not restore game %s: %v", row.GameID, err)
+			continue
+		}
+		if game.TournamentID != "" {
+			gs.abandonOrphaned(game)
+			continue
+		}
+
+		gs.mutex.Lock()
+		if _, exists := gs.games[game.ID]; exists {
+			gs.mutex.Unlock()
+			continue
+		}
+		gs.games[game.ID] = game
+		gs.mutex.Unlock()
+
+		game.mutex.Lock()
+		if game.Type == HumanVsAI {
+			game.AIEngine = newAIEngine(game.ID, game.AIDifficulty)
+		}
+		if game.AISettings != nil {
+			game.openAIEngines()
+		}
+		game.configureEngines()
+		// Nobody could move while the server was down, so the side to move
+		// starts its turn afresh
+		if game.Status == StatusInProgress && game.Clock != nil {
+			game.Clock.Start(time.Now())
+			gs.armClockTimer(game)
+		}
+		game.mutex.Unlock()
+
+		restored = append(restored, game.ID)
+	}
+
+	log.Printf("Restored %d unfinished games from the database", len(restored))
+	return restored, nil
+}
+
+// abandonOrphaned ends a tournament game whose tournament didn't survive
+// the restart, with no winner, without bringing it back into memory.
+func (gs *GameService) abandonOrphaned(game *GameState) {
+	game.finish("", OutcomeAbandoned)
+	game.Status = StatusAbandoned
+	gs.persistGame(game)
+	log.Printf("Game %s abandoned, its tournament %s is gone after the restart", game.ID, game.TournamentID)
+}
+
+// restoreGame rebuilds a game from its games row and game_moves rows.
+func restoreGame(row database.GameRow, moves []database.GameMoveRow) (*GameState, error) {
+	type ply struct {
+		mover     chess.Color
+		timeTaken *int
+	}
+	var history []string
+	var plies []ply
+	for _, move := range moves {
+		if move.WhiteMove != nil {
+			history = append(history, *move.WhiteMove)
+			plies = append(plies, ply{chess.White, move.TimeTakenWhite})
+		}
+		if move.BlackMove != nil {
+			history = append(history, *move.BlackMove)
+			plies = append(plies, ply{chess.Black, move.TimeTakenBlack})
+		}
+	}
+
+	variant := VariantStandard
+	if row.Variant != "" {
+		variant = Variant(row.Variant)
+	}
+	startFEN := ""
+	if row.StartFEN != nil {
+		startFEN = *row.StartFEN
+	}
+	replay, _, err := replayGame(variant, startFEN, history)
+	if err != nil {
+		return nil, err
+	}
+	if replay.fen() != row.FEN {
+		log.Printf("Warning: Game %s moves lead to %s, stored position is %s", row.GameID, replay.fen(), row.FEN)
+	}
+
+	variantState := replay.VariantState
+	if row.VariantState != nil {
+		var stored VariantState
+		if err := json.Unmarshal([]byte(*row.VariantState), &stored); err != nil {
+			return nil, fmt.Errorf("invalid variant state: %v", err)
+		}
+		variantState = &stored
+	}
+
+	players := make(map[chess.Color]Player)
+	seats := []struct {
+		color           chess.Color
+		id, name, token *string
+	}{
+		{chess.White, row.WhitePlayerID, row.WhitePlayerName, row.WhiteToken},
+		{chess.Black, row.BlackPlayerID, row.BlackPlayerName, row.BlackToken},
+	}
+	for _, seat := range seats {
+		if seat.id == nil {
+			continue
+		}
+		player := Player{
+			ID:    *seat.id,
+			IsAI:  *seat.id == aiPlayerID(row.GameID),
+			Color: seat.color,
+		}
+		if seat.name != nil {
+			player.Name = *seat.name
+		}
+		if seat.token != nil {
+			player.Token = *seat.token
+		}
+		players[seat.color] = player
+	}
+
+	var clock *Clock
+	var clockHistory []clockSnapshot
+	if row.TimeControl != nil {
+		var tc TimeControl
+		if err := json.Unmarshal([]byte(*row.TimeControl), &tc); err != nil {
+			return nil, fmt.Errorf("invalid time control: %v", err)
+		}
+		clock = NewClock(tc)
+		for i, ply := range plies {
+			clockHistory = append(clockHistory, clock.snapshot(i))
+			clockHistory[i].running = ply.timeTaken != nil
+			if ply.timeTaken != nil {
+				clock.Replay(ply.mover, time.Duration(*ply.timeTaken)*time.Millisecond)
+			}
+		}
+	}
+
+	var aiSettings map[chess.Color]AISettings
+	var aiMatchPace time.Duration
+	if row.AISettings != nil {
+		var settings storedAIMatch
+		if err := json.Unmarshal([]byte(*row.AISettings), &settings); err != nil {
+			return nil, fmt.Errorf("invalid AI settings: %v", err)
+		}
+		aiSettings = make(map[chess.Color]AISettings, 2)
+		if settings.White != nil {
+			aiSettings[chess.White] = *settings.White
+		}
+		if settings.Black != nil {
+			aiSettings[chess.Black] = *settings.Black
+		}
+		aiMatchPace = time.Duration(settings.PaceMs) * time.Millisecond
+	}
+
+	ownerID, rematchOf := "", ""
+	if row.OwnerID != nil {
+		ownerID = *row.OwnerID
+	}
+	if row.RematchOf != nil {
+		rematchOf = *row.RematchOf
+	}
+
+	game := &GameState{
+		ID:           row.GameID,
+		OwnerID:      ownerID,
+		Type:         GameType(row.GameType),
+		ChessGame:    replay.ChessGame,
+		Variant:      variant,
+		StartFEN:     startFEN,
+		castling:     replay.castling,
+		VariantState: variantState,
+		Players:      players,
+		CurrentTurn:  replay.ChessGame.Position().Turn(),
+		Status:       GameStatus(row.Status),
+		CreatedAt:    row.CreatedAt,
+		LastMoveAt:   row.UpdatedAt,
+		AIDifficulty: row.AIDifficulty,
+		AISettings:   aiSettings,
+		AIMatchPace:  aiMatchPace,
+		MoveHistory:  replay.MoveHistory,
+
+		Clock:        clock,
+		clockHistory: clockHistory,
+		setupPlies:   min(row.SetupPlies, len(replay.MoveHistory)),
+		RematchOf:    rematchOf,
+	}
+	game.SpectatorDelay = time.Duration(row.SpectatorDelayMs) * time.Millisecond
+	game.AutoAcceptTakebacks = row.AutoAcceptTakebacks
+	if row.TournamentID != nil {
+		game.TournamentID = *row.TournamentID
+	}
+	if game.Status == StatusAdjourned {
+		game.AdjournedAt = row.UpdatedAt
+	}
+	if row.DaysPerMove != nil {
+		game.DaysPerMove = *row.DaysPerMove
+	}
+	if row.MoveDeadline != nil {
+		game.MoveDeadline = *row.MoveDeadline
+	}
+	return game, nil
+}
@@ -0,0 +1,146 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/hunterMotko/chess-game/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreGame(t *testing.T) {
+	white, black := "white-client", "ai_restored-game"
+	whiteName, blackName := "Alice", "Stockfish (Level 5)"
+	timeControl := `{"baseMs":60000,"incrementMs":1000}`
+	e4, e5, nf3 := "e2e4", "e7e5", "g1f3"
+	took := func(ms int) *int { return &ms }
+
+	row := database.GameRow{
+		GameID:          "restored-game",
+		OwnerID:         &white,
+		GameType:        string(HumanVsAI),
+		Status:          string(StatusInProgress),
+		WhitePlayerID:   &white,
+		WhitePlayerName: &whiteName,
+		BlackPlayerID:   &black,
+		BlackPlayerName: &blackName,
+		AIDifficulty:    5,
+		TimeControl:     &timeControl,
+	}
+	moves := []database.GameMoveRow{
+		{MoveNumber: 1, WhiteMove: &e4, BlackMove: &e5, TimeTakenWhite: took(3000), TimeTakenBlack: took(500)},
+		{MoveNumber: 2, WhiteMove: &nf3, TimeTakenWhite: took(10000)},
+	}
+
+	game, err := restoreGame(row, moves)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"e2e4", "e7e5", "g1f3"}, game.MoveHistory)
+	assert.Equal(t, chess.Black, game.ChessGame.Position().Turn())
+	assert.Equal(t, StatusInProgress, game.Status)
+	assert.False(t, game.Players[chess.White].IsAI)
+	assert.True(t, game.Players[chess.Black].IsAI)
+	assert.Equal(t, "Alice", game.Players[chess.White].Name)
+	assert.Equal(t, white, game.OwnerID)
+
+	require.NotNil(t, game.Clock)
+	assert.Equal(t, 49*time.Second, game.Clock.Remaining[chess.White])
+	assert.Equal(t, 60500*time.Millisecond, game.Clock.Remaining[chess.Black])
+}
+
+func TestRestoreGame_SetupPlies(t *testing.T) {
+	e4, e5, nf3 := "e2e4", "e7e5", "g1f3"
+	row := database.GameRow{
+		GameID:     "pgn-game",
+		GameType:   string(HumanVsHuman),
+		Status:     string(StatusInProgress),
+		SetupPlies: 2,
+	}
+	moves := []database.GameMoveRow{
+		{MoveNumber: 1, WhiteMove: &e4, BlackMove: &e5},
+		{MoveNumber: 2, WhiteMove: &nf3},
+	}
+
+	game, err := restoreGame(row, moves)
+	require.NoError(t, err)
+	assert.Equal(t, 2, game.setupPlies)
+	assert.Equal(t, []string{"e2e4", "e7e5"}, game.rematchSettings().setupMoves)
+}
+
+func TestRestoreGame_Options(t *testing.T) {
+	row := database.GameRow{
+		GameID:              "delayed-game",
+		GameType:            string(HumanVsAI),
+		Status:              string(StatusInProgress),
+		SpectatorDelayMs:    15000,
+		AutoAcceptTakebacks: true,
+	}
+
+	game, err := restoreGame(row, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Second, game.SpectatorDelay)
+	assert.True(t, game.AutoAcceptTakebacks)
+}
+
+func TestRestoreGame_Tournament(t *testing.T) {
+	tournamentID := "club-night"
+	row := database.GameRow{
+		GameID:       "board-1",
+		GameType:     string(HumanVsHuman),
+		Status:       string(StatusInProgress),
+		TournamentID: &tournamentID,
+	}
+
+	game, err := restoreGame(row, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "club-night", game.TournamentID)
+
+	gs := NewGameService(nil)
+	gs.abandonOrphaned(game)
+	assert.Equal(t, StatusAbandoned, game.Status)
+	assert.Equal(t, OutcomeAbandoned, game.Outcome)
+	assert.Empty(t, game.Winner, "nobody wins a game its tournament can't score")
+}
+
+func TestRestoreGame_InvalidHistory(t *testing.T) {
+	bad := "e2e5"
+	_, err := restoreGame(database.GameRow{GameID: "broken"}, []database.GameMoveRow{{MoveNumber: 1, WhiteMove: &bad}})
+	assert.Error(t, err)
+}
+
+func TestRestoreGame_VariantState(t *testing.T) {
+	checks := `{"checks":{"white":2}}`
+	row := database.GameRow{
+		GameID:       "three-check",
+		Status:       string(StatusInProgress),
+		Variant:      string(VariantThreeCheck),
+		VariantState: &checks,
+	}
+
+	game, err := restoreGame(row, nil)
+	require.NoError(t, err)
+	require.NotNil(t, game.VariantState)
+	assert.Equal(t, 2, game.VariantState.Checks["white"])
+}
+
+func TestRestoreGame_AIMatchSettings(t *testing.T) {
+	ai := aiPlayerID("engine-match")
+	settings := `{"white":{"difficulty":3,"depth":4},"black":{"difficulty":18,"moveTimeMs":250},"paceMs":300}`
+	row := database.GameRow{
+		GameID:        "engine-match",
+		GameType:      string(AIVsAI),
+		Status:        string(StatusInProgress),
+		WhitePlayerID: &ai,
+		BlackPlayerID: &ai,
+		AIDifficulty:  3,
+		AISettings:    &settings,
+	}
+
+	game, err := restoreGame(row, nil)
+	require.NoError(t, err)
+	assert.Equal(t, AISettings{Difficulty: 3, Depth: 4}, game.aiSettingsFor(chess.White))
+	assert.Equal(t, AISettings{Difficulty: 18, MoveTimeMs: 250}, game.aiSettingsFor(chess.Black))
+	assert.Equal(t, 300*time.Millisecond, game.AIMatchPace)
+}
@@ -0,0 +1,215 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+	"github.com/hunterMotko/chess-game/internal/database"
+)
+
+// SetStartPosition sets up a game that hasn't started yet from a custom FEN
+// or from a PGN, which may carry its own FEN tag. A PGN's moves become the
+// game's move history, so play continues from where the PGN ends.
+func (gs *GameService) SetStartPosition(gameID, fen, pgn string) error {
+	if fen == "" && pgn == "" {
+		return nil
+	}
+
+	chessGame, startFEN, history, err := newGameFrom(fen, pgn)
+	if err != nil {
+		return err
+	}
+
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+
+	if game.Status != StatusWaiting {
+		return fmt.Errorf("cannot change the starting position once game %s has started", gameID)
+	}
+	if game.Variant != VariantStandard {
+		return fmt.Errorf("%s games start from their own positions", game.Variant)
+	}
+
+	game.ChessGame = chessGame
+	game.StartFEN = startFEN
+	game.MoveHistory = history
+	game.setupPlies = len(history)
+	game.CurrentTurn = chessGame.Position().Turn()
+
+	gs.persistStartPosition(game)
+	log.Printf("Game %s set up from %q with %d moves", gameID, chessGame.FEN(), len(history))
+	return nil
+}
+
+// SetOpening sets up a game that hasn't started yet from an opening in the
+// openings table, so play continues from the end of the opening's moves.
+func (gs *GameService) SetOpening(ctx context.Context, gameID, openingID string) (*database.Opening, error) {
+	if gs.db == nil {
+		return nil, fmt.Errorf("openings are not available without a database")
+	}
+	id, err := uuid.Parse(openingID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid opening id %q", openingID)
+	}
+
+	opening, err := gs.db.GetOpeningByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s not found: %v", openingID, err)
+	}
+	if err := gs.SetStartPosition(gameID, "", opening.Pgn); err != nil {
+		return nil, fmt.Errorf("opening %s (%s): %v", opening.Eco, opening.Name, err)
+	}
+
+	game, exists := gs.GetGame(gameID)
+	if exists {
+		game.mutex.Lock()
+		game.Opening = opening
+		game.mutex.Unlock()
+	}
+	return opening, nil
+}
+
+// newGameFrom builds a chess game from a FEN or a PGN. It returns the
+// starting FEN, empty for the standard position, and the PGN's moves in
+// UCI notation.
+func newGameFrom(fen, pgn string) (*chess.Game, string, []string, error) {
+	if fen != "" && pgn != "" {
+		return nil, "", nil, fmt.Errorf("give either a FEN or a PGN, not both")
+	}
+
+	startFEN := strings.TrimSpace(fen)
+	history := []string{}
+	if pgn != "" {
+		option, err := chess.PGN(strings.NewReader(pgn))
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("invalid PGN: %v", err)
+		}
+		parsed := chess.NewGame(option)
+		startFEN = parsed.GetRootMove().Position().String()
+		for _, move := range parsed.Moves() {
+			history = append(history, move.String())
+		}
+	}
+	if startFEN == chess.StartingPosition().String() {
+		startFEN = ""
+	}
+
+	start, err := newChessGame(startFEN)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if err := validatePosition(start.Position()); err != nil {
+		return nil, "", nil, err
+	}
+
+	// Replaying drops the PGN's variations, comments and claimed result
+	replay, _, err := replayGame(VariantStandard, startFEN, history)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	chessGame := replay.ChessGame
+	if chessGame.Outcome() != chess.NoOutcome {
+		return nil, "", nil, fmt.Errorf("the position is already decided (%s)", chessGame.Method())
+	}
+
+	if startFEN != "" {
+		startFEN = start.FEN() // normalised
+	}
+	return chessGame, startFEN, history, nil
+}
+
+// newChessGame starts a chess game from startFEN, or from the standard
+// position when it is empty.
+func newChessGame(startFEN string) (*chess.Game, error) {
+	if startFEN == "" {
+		return chess.NewGame(), nil
+	}
+
+	option, err := chess.FEN(startFEN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FEN: %v", err)
+	}
+	chessGame := chess.NewGame(option)
+	chessGame.AddTagPair("SetUp", "1")
+	chessGame.AddTagPair("FEN", startFEN)
+	return chessGame, nil
+}
+
+// validatePosition rejects positions no legal game can reach: each side
+// needs exactly one king, and the side that just moved can't be in check.
+func validatePosition(pos *chess.Position) error {
+	kings := map[chess.Color]int{}
+	for _, piece := range pos.Board().SquareMap() {
+		if piece.Type() == chess.King {
+			kings[piece.Color()]++
+		}
+	}
+	if kings[chess.White] != 1 || kings[chess.Black] != 1 {
+		return fmt.Errorf("each side needs exactly one king")
+	}
+
+	// If the side to move could take the king, the other side is in check
+	for _, move := range pos.ValidMoves() {
+		if pos.Board().Piece(move.S2()).Type() == chess.King {
+			return fmt.Errorf("the side not to move is in check")
+		}
+	}
+	return nil
+}
+
+// fullMoveNumber returns the FEN full move number of a position.
+func fullMoveNumber(pos *chess.Position) int {
+	if pos.Turn() == chess.White {
+		return (pos.Ply() + 1) / 2
+	}
+	return pos.Ply() / 2
+}
+
+// lastMove returns the full move number and color of the ply that led to
+// pos.
+func lastMove(pos *chess.Position) (int, chess.Color) {
+	mover := pos.Turn().Other()
+	if mover == chess.White {
+		return fullMoveNumber(pos), mover
+	}
+	return fullMoveNumber(pos) - 1, mover
+}
+
+// persistStartPosition writes a game's starting position and any moves it
+// was set up with. The caller must hold game.mutex.
+func (gs *GameService) persistStartPosition(game *GameState) {
+	if gs.db == nil {
+		return
+	}
+
+	ctx := context.Background()
+	var startFEN *string
+	if game.StartFEN != "" {
+		startFEN = &game.StartFEN
+	}
+	if err := gs.db.UpdateGameStartPosition(ctx, game.ID, string(game.Variant), startFEN, game.setupPlies); err != nil {
+		log.Printf("Warning: Failed to save starting position of game %s: %v", game.ID, err)
+	}
+
+	_, plies, err := replayGame(game.Variant, game.StartFEN, game.MoveHistory)
+	if err != nil {
+		return
+	}
+	for _, ply := range plies {
+		err := gs.db.SaveGamePly(ctx, game.ID, fullMoveNumber(ply.before), colorName(ply.before.Turn()), ply.uci,
+			ply.san, ply.fen, nil)
+		if err != nil {
+			log.Printf("Warning: Failed to save move %s of game %s: %v", ply.uci, game.ID, err)
+		}
+	}
+	gs.persistGame(game)
+}
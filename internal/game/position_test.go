@@ -0,0 +1,100 @@
+package game
+
+import (
+	"context"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGameFrom(t *testing.T) {
+	chessGame, startFEN, history, err := newGameFrom("4k3/8/8/8/8/8/8/4K2R b K - 0 20", "")
+	require.NoError(t, err)
+	assert.Equal(t, "4k3/8/8/8/8/8/8/4K2R b K - 0 20", startFEN)
+	assert.Empty(t, history)
+	assert.Equal(t, chess.Black, chessGame.Position().Turn())
+
+	chessGame, startFEN, history, err = newGameFrom("", "1. e4 e5 2. Nf3 Nc6 *")
+	require.NoError(t, err)
+	assert.Empty(t, startFEN, "standard start")
+	assert.Equal(t, []string{"e2e4", "e7e5", "g1f3", "b8c6"}, history)
+	assert.Equal(t, chess.White, chessGame.Position().Turn())
+
+	_, startFEN, history, err = newGameFrom("", "[SetUp \"1\"]\n[FEN \"4k3/8/8/8/8/8/8/4K2R w K - 0 1\"]\n\n1. Kd2 Kd7 *")
+	require.NoError(t, err)
+	assert.Equal(t, "4k3/8/8/8/8/8/8/4K2R w K - 0 1", startFEN)
+	assert.Equal(t, []string{"e1d2", "e8d7"}, history)
+}
+
+func TestNewGameFrom_Invalid(t *testing.T) {
+	tests := map[string][2]string{
+		"both given":         {"4k3/8/8/8/8/8/8/4K3 w - - 0 1", "1. e4 *"},
+		"bad FEN":            {"not a fen", ""},
+		"missing king":       {"8/8/8/8/8/8/8/4K2R w - - 0 1", ""},
+		"side not to move":   {"4k3/4R3/8/8/8/8/8/4K3 w - - 0 1", ""},
+		"already checkmated": {"", "1. f3 e5 2. g4 Qh4# 0-1"},
+		"illegal PGN move":   {"", "1. e5 *"},
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, _, err := newGameFrom(input[0], input[1])
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGameService_SetStartPosition(t *testing.T) {
+	gs := NewGameService(nil)
+
+	_, err := gs.CreateGame("endgame", "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, gs.SetStartPosition("endgame", "4k3/8/8/8/8/8/8/4K2R b K - 0 20", ""))
+	require.NoError(t, gs.JoinGame("endgame", "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame("endgame", "black-client", "Bob", chess.Black))
+
+	assert.Error(t, gs.SetStartPosition("endgame", "", "1. e4 *"), "game has started")
+
+	_, err = gs.MakeMove("endgame", "black-client", "e8d7")
+	require.NoError(t, err)
+	_, err = gs.MakeMove("endgame", "white-client", "h1h7")
+	require.NoError(t, err)
+
+	state, err := gs.GetGameState("endgame")
+	require.NoError(t, err)
+	assert.Equal(t, "4k3/8/8/8/8/8/8/4K2R b K - 0 20", state.StartFEN)
+	assert.Equal(t, []string{"e8d7", "h1h7"}, state.MoveHistory)
+
+	// Takebacks rebuild the game from the custom start
+	_, _, err = gs.RequestTakeback("endgame", "white-client")
+	require.NoError(t, err)
+	require.NoError(t, gs.RespondTakeback("endgame", "black-client", true))
+
+	state, err = gs.GetGameState("endgame")
+	require.NoError(t, err)
+	assert.Equal(t, "8/3k4/8/8/8/8/8/4K2R w K - 1 21", state.FEN)
+}
+
+func TestLastMove(t *testing.T) {
+	chessGame, err := newChessGame("4k3/8/8/8/8/8/8/4K2R b K - 0 20")
+	require.NoError(t, err)
+	moveNumber, mover := lastMove(chessGame.Position())
+	assert.Equal(t, 20, moveNumber)
+	assert.Equal(t, chess.White, mover)
+
+	require.NoError(t, chessGame.Move(findValidMove(chessGame.ValidMoves(), "e8d7"), nil))
+	moveNumber, mover = lastMove(chessGame.Position())
+	assert.Equal(t, 20, moveNumber)
+	assert.Equal(t, chess.Black, mover)
+}
+
+func TestGameService_SetOpening_NoDatabase(t *testing.T) {
+	gs := NewGameService(nil)
+	_, err := gs.CreateGame("opening-game", "student", HumanVsAI, 5)
+	require.NoError(t, err)
+
+	_, err = gs.SetOpening(context.Background(), "opening-game", uuid.NewString())
+	assert.Error(t, err)
+}
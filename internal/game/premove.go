@@ -0,0 +1,111 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+)
+
+// maxPremoves caps how many moves a player may queue at once.
+const maxPremoves = 10
+
+// Premove is a move queued while the opponent is thinking and played the
+// moment their move lands.
+type Premove struct {
+	Move string `json:"move"` // UCI, e.g. "e7e5" or "e7e8q"
+	// IfMove makes the premove conditional: it is only played if the
+	// opponent answered with this move, in UCI
+	IfMove string `json:"ifMove,omitempty"`
+}
+
+// SetPremoves replaces playerID's queued moves. Each is played in turn,
+// one per opponent move, for as long as it is legal and its condition
+// holds. An empty queue cancels them all. Moves are checked when they are
+// played, not here, since the position will have changed by then.
+func (gs *GameService) SetPremoves(gameID, playerID string, premoves []Premove) error {
+	game, err := gs.lockInProgress(gameID)
+	if err != nil {
+		return err
+	}
+	defer game.mutex.Unlock()
+
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		return err
+	}
+	if len(premoves) > maxPremoves {
+		return fmt.Errorf("at most %d premoves can be queued", maxPremoves)
+	}
+	if len(premoves) > 0 && game.ChessGame.Position().Turn() == color {
+		return fmt.Errorf("it is already your turn")
+	}
+	for i, premove := range premoves {
+		if !isUCIMove(premove.Move) || (premove.IfMove != "" && !isUCIMove(premove.IfMove)) {
+			return fmt.Errorf("premove %d is not a UCI move", i+1)
+		}
+	}
+
+	if game.Premoves == nil {
+		game.Premoves = make(map[chess.Color][]Premove)
+	}
+	game.Premoves[color] = append([]Premove{}, premoves...)
+	log.Printf("Game %s: %s queued %d premoves", gameID, color.Name(), len(premoves))
+	return nil
+}
+
+// isUCIMove reports whether s looks like a UCI move, without checking it
+// against any position.
+func isUCIMove(s string) bool {
+	if len(s) != 4 && len(s) != 5 {
+		return false
+	}
+	for i := 0; i < 4; i += 2 {
+		if s[i] < 'a' || s[i] > 'h' || s[i+1] < '1' || s[i+1] > '8' {
+			return false
+		}
+	}
+	return len(s) == 4 || strings.ContainsRune("qrbn", rune(s[4]))
+}
+
+// playPremove answers the move just played, lastMove in UCI, with the side
+// to move's first premove. A premove that is illegal or whose condition
+// doesn't hold drops the whole queue, since the rest were planned on it.
+// It returns result, or the premove's result when one was played. The
+// caller must hold game.mutex.
+func (gs *GameService) playPremove(game *GameState, lastMove string, result *MoveResult) *MoveResult {
+	if result.GameStatus == StatusCompleted {
+		game.Premoves = nil
+		return result
+	}
+	mover := game.ChessGame.Position().Turn()
+	queue := game.Premoves[mover]
+	if len(queue) == 0 {
+		return result
+	}
+	premove := queue[0]
+	if premove.IfMove != "" && premove.IfMove != lastMove {
+		log.Printf("Game %s: %s's premoves dropped, %s didn't follow", game.ID, mover.Name(), premove.IfMove)
+		delete(game.Premoves, mover)
+		return result
+	}
+
+	uci, san, err := game.playMove(premove.Move)
+	if err != nil {
+		log.Printf("Game %s: %s's premoves dropped: %v", game.ID, mover.Name(), err)
+		delete(game.Premoves, mover)
+		return result
+	}
+	game.Premoves[mover] = queue[1:]
+	game.expireDrawOffer(mover)
+	reply := gs.recordMove(game, mover, uci, san)
+	log.Printf("Premove played in game %s: %s", game.ID, premove.Move)
+
+	reply.Premove = reply.Move
+	reply.Move = result.Move
+	if reply.GameStatus == StatusCompleted {
+		game.Premoves = nil
+	}
+	return reply
+}
@@ -0,0 +1,74 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPremoves_PlayedAfterOpponentMove(t *testing.T) {
+	gs := playVariantGame(t, VariantStandard, nil)
+	assert.Error(t, gs.SetPremoves("variant-game", "white-client", []Premove{{Move: "d2d4"}}), "white is to move")
+	require.NoError(t, gs.SetPremoves("variant-game", "black-client", []Premove{{Move: "e7e5"}, {Move: "g8f6"}}))
+
+	black, err := gs.GetGameStateFor("variant-game", chess.Black)
+	require.NoError(t, err)
+	assert.Len(t, black.Premoves, 2)
+	white, err := gs.GetGameStateFor("variant-game", chess.White)
+	require.NoError(t, err)
+	assert.Empty(t, white.Premoves, "premoves are private")
+
+	result, err := gs.MakeMove("variant-game", "white-client", "e2e4")
+	require.NoError(t, err)
+	assert.Equal(t, "e2e4", result.Move)
+	assert.Equal(t, "e7e5", result.Premove)
+	assert.Equal(t, chess.White, result.Turn)
+
+	result, err = gs.MakeMove("variant-game", "white-client", "g1f3")
+	require.NoError(t, err)
+	assert.Equal(t, "g8f6", result.Premove)
+
+	state, err := gs.GetGameState("variant-game")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e2e4", "e7e5", "g1f3", "g8f6"}, state.MoveHistory)
+}
+
+func TestPremoves_Dropped(t *testing.T) {
+	gs := playVariantGame(t, VariantStandard, nil)
+
+	// The condition doesn't hold
+	require.NoError(t, gs.SetPremoves("variant-game", "black-client", []Premove{{Move: "d7d5", IfMove: "d2d4"}}))
+	result, err := gs.MakeMove("variant-game", "white-client", "e2e4")
+	require.NoError(t, err)
+	assert.Empty(t, result.Premove)
+	assert.Equal(t, chess.Black, result.Turn)
+
+	// The second premove is illegal once white has answered
+	_, err = gs.MakeMove("variant-game", "black-client", "e7e6")
+	require.NoError(t, err)
+	require.NoError(t, gs.SetPremoves("variant-game", "black-client", []Premove{{Move: "d7d5"}, {Move: "d5d4"}}))
+	result, err = gs.MakeMove("variant-game", "white-client", "b1c3")
+	require.NoError(t, err)
+	assert.Equal(t, "d7d5", result.Premove)
+	result, err = gs.MakeMove("variant-game", "white-client", "d2d4")
+	require.NoError(t, err)
+	assert.Empty(t, result.Premove)
+
+	black, err := gs.GetGameStateFor("variant-game", chess.Black)
+	require.NoError(t, err)
+	assert.Empty(t, black.Premoves)
+	assert.Equal(t, chess.Black, black.Turn)
+}
+
+func TestPremoves_Cancel(t *testing.T) {
+	gs := playVariantGame(t, VariantStandard, nil)
+	assert.Error(t, gs.SetPremoves("variant-game", "black-client", []Premove{{Move: "e7"}}))
+	require.NoError(t, gs.SetPremoves("variant-game", "black-client", []Premove{{Move: "e7e5"}}))
+	require.NoError(t, gs.SetPremoves("variant-game", "black-client", nil))
+
+	result, err := gs.MakeMove("variant-game", "white-client", "e2e4")
+	require.NoError(t, err)
+	assert.Empty(t, result.Premove)
+}
@@ -0,0 +1,176 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+)
+
+// rematchSettings is what a rematch takes over from the game before it.
+type rematchSettings struct {
+	gameType            GameType
+	ownerID             string
+	difficulty          int
+	variant             Variant
+	startFEN            string
+	setupMoves          []string
+	timeControl         *TimeControl
+	daysPerMove         int
+	spectatorDelay      time.Duration
+	autoAcceptTakebacks bool
+	players             map[chess.Color]Player
+}
+
+// OfferRematch offers playerID's opponent a rematch of a finished game.
+// The AI accepts straight away, and so does an opponent who has already
+// offered one; the rematch is then created and its ID returned. Otherwise
+// the offer waits for AcceptRematch and the returned ID is empty.
+func (gs *GameService) OfferRematch(gameID, playerID string) (string, error) {
+	return gs.rematch(gameID, playerID, false)
+}
+
+// AcceptRematch accepts the opponent's rematch offer and returns the ID of
+// the rematch.
+func (gs *GameService) AcceptRematch(gameID, playerID string) (string, error) {
+	return gs.rematch(gameID, playerID, true)
+}
+
+func (gs *GameService) rematch(gameID, playerID string, accepting bool) (string, error) {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return "", fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.Lock()
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		game.mutex.Unlock()
+		return "", err
+	}
+	if game.Status != StatusCompleted && game.Status != StatusAbandoned {
+		game.mutex.Unlock()
+		return "", fmt.Errorf("game %s is not over yet", gameID)
+	}
+	if game.RematchID != "" {
+		game.mutex.Unlock()
+		return "", fmt.Errorf("game %s already has a rematch", gameID)
+	}
+
+	opponent := game.Players[color.Other()]
+	agreed := opponent.IsAI || game.RematchOfferedBy == color.Other()
+	if accepting && !agreed {
+		game.mutex.Unlock()
+		return "", fmt.Errorf("no rematch offer from your opponent is pending")
+	}
+	if !agreed {
+		game.RematchOfferedBy = color
+		game.mutex.Unlock()
+		log.Printf("Game %s: %s offered a rematch", gameID, color.Name())
+		return "", nil
+	}
+
+	// Claim the rematch before letting go of the lock so it is only made
+	// once; creating it takes the service lock, which comes first
+	rematchID := uuid.NewString()
+	game.RematchID = rematchID
+	game.RematchOfferedBy = chess.NoColor
+	settings := game.rematchSettings()
+	game.mutex.Unlock()
+
+	if err := gs.createRematch(gameID, rematchID, settings); err != nil {
+		gs.DeleteGame(rematchID)
+		game.mutex.Lock()
+		game.RematchID = ""
+		game.mutex.Unlock()
+		return "", fmt.Errorf("failed to create rematch: %v", err)
+	}
+
+	log.Printf("Game %s: rematch %s agreed", gameID, rematchID)
+	return rematchID, nil
+}
+
+// rematchSettings takes the game's settings for its rematch. The caller
+// must hold game.mutex.
+func (game *GameState) rematchSettings() rematchSettings {
+	settings := rematchSettings{
+		gameType:            game.Type,
+		ownerID:             game.OwnerID,
+		difficulty:          game.AIDifficulty,
+		variant:             game.Variant,
+		startFEN:            game.StartFEN,
+		setupMoves:          append([]string{}, game.MoveHistory[:game.setupPlies]...),
+		daysPerMove:         game.DaysPerMove,
+		spectatorDelay:      game.SpectatorDelay,
+		autoAcceptTakebacks: game.AutoAcceptTakebacks,
+		players:             make(map[chess.Color]Player),
+	}
+	if game.Clock != nil {
+		tc := game.Clock.Control
+		settings.timeControl = &tc
+	}
+	for color, player := range game.Players {
+		settings.players[color] = player
+	}
+	return settings
+}
+
+// createRematch sets up rematchID with the previous game's settings and
+// seats its players with their colors swapped.
+func (gs *GameService) createRematch(previousID, rematchID string, settings rematchSettings) error {
+	rematch, err := gs.CreateGame(rematchID, settings.ownerID, settings.gameType, settings.difficulty)
+	if err != nil {
+		return err
+	}
+
+	rematch.mutex.Lock()
+	replay, _, err := replayGame(settings.variant, settings.startFEN, settings.setupMoves)
+	if err != nil {
+		rematch.mutex.Unlock()
+		return err
+	}
+	rematch.Variant = settings.variant
+	rematch.StartFEN = settings.startFEN
+	rematch.ChessGame = replay.ChessGame
+	rematch.castling = replay.castling
+	rematch.VariantState = replay.VariantState
+	rematch.MoveHistory = replay.MoveHistory
+	rematch.setupPlies = len(replay.MoveHistory)
+	rematch.CurrentTurn = replay.ChessGame.Position().Turn()
+	rematch.SpectatorDelay = settings.spectatorDelay
+	rematch.AutoAcceptTakebacks = settings.autoAcceptTakebacks
+	rematch.RematchOf = previousID
+	rematch.configureEngines()
+	gs.persistStartPosition(rematch)
+	gs.persistOptions(rematch)
+	rematch.mutex.Unlock()
+
+	if gs.db != nil {
+		if err := gs.db.UpdateGameRematchOf(context.Background(), rematchID, previousID); err != nil {
+			log.Printf("Warning: Failed to link rematch %s to game %s: %v", rematchID, previousID, err)
+		}
+	}
+
+	if settings.daysPerMove > 0 {
+		if err := gs.SetCorrespondence(rematchID, settings.daysPerMove); err != nil {
+			return err
+		}
+	}
+	if settings.timeControl != nil {
+		if err := gs.SetTimeControl(rematchID, *settings.timeControl); err != nil {
+			return err
+		}
+	}
+	for color, player := range settings.players {
+		if player.IsAI {
+			continue // JoinGame seats the AI opposite its human
+		}
+		if err := gs.JoinGame(rematchID, player.ID, player.Name, color.Other()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
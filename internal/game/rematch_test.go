@@ -0,0 +1,99 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameService_Rematch(t *testing.T) {
+	gs := NewGameService(nil)
+	_, err := gs.CreateGame("first-game", "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, gs.SetTimeControl("first-game", TimeControl{BaseMs: int64(5 * time.Minute / time.Millisecond)}))
+	require.NoError(t, gs.JoinGame("first-game", "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame("first-game", "black-client", "Bob", chess.Black))
+
+	_, err = gs.OfferRematch("first-game", "white-client")
+	assert.Error(t, err, "the game isn't over yet")
+
+	require.NoError(t, gs.Resign("first-game", "black-client"))
+	_, err = gs.AcceptRematch("first-game", "black-client")
+	assert.Error(t, err, "nothing offered yet")
+	_, err = gs.OfferRematch("first-game", "spectator")
+	assert.Error(t, err)
+
+	rematchID, err := gs.OfferRematch("first-game", "white-client")
+	require.NoError(t, err)
+	assert.Empty(t, rematchID, "the offer waits for black")
+	state, err := gs.GetGameState("first-game")
+	require.NoError(t, err)
+	assert.Equal(t, chess.White, state.RematchOfferedBy)
+
+	rematchID, err = gs.AcceptRematch("first-game", "black-client")
+	require.NoError(t, err)
+	require.NotEmpty(t, rematchID)
+
+	rematch, err := gs.GetGameState(rematchID)
+	require.NoError(t, err)
+	assert.Equal(t, "first-game", rematch.RematchOf)
+	assert.Equal(t, StatusInProgress, rematch.Status)
+	assert.Equal(t, "black-client", rematch.Players[chess.White].ID, "colors are swapped")
+	assert.Equal(t, "white-client", rematch.Players[chess.Black].ID)
+	require.NotNil(t, rematch.Clock)
+	assert.Equal(t, int64(5*time.Minute/time.Millisecond), rematch.Clock.TimeControl.BaseMs)
+
+	state, err = gs.GetGameState("first-game")
+	require.NoError(t, err)
+	assert.Equal(t, rematchID, state.RematchID)
+	assert.Equal(t, chess.NoColor, state.RematchOfferedBy)
+
+	_, err = gs.OfferRematch("first-game", "black-client")
+	assert.Error(t, err, "a game only has one rematch")
+}
+
+func TestGameService_RematchAgainstAI(t *testing.T) {
+	gs := NewGameService(nil)
+	_, err := gs.CreateGame("ai-game", "student", HumanVsAI, 3)
+	require.NoError(t, err)
+	require.NoError(t, gs.SetVariant("ai-game", VariantThreeCheck, nil))
+	require.NoError(t, gs.JoinGame("ai-game", "student", "Student", chess.White))
+	require.NoError(t, gs.Resign("ai-game", "student"))
+
+	rematchID, err := gs.OfferRematch("ai-game", "student")
+	require.NoError(t, err)
+	require.NotEmpty(t, rematchID, "the AI accepts straight away")
+
+	rematch, err := gs.GetGameState(rematchID)
+	require.NoError(t, err)
+	assert.Equal(t, HumanVsAI, rematch.Type)
+	assert.Equal(t, VariantThreeCheck, rematch.Variant)
+	assert.Equal(t, "student", rematch.Players[chess.Black].ID)
+	assert.True(t, rematch.Players[chess.White].IsAI)
+	assert.Equal(t, "Stockfish (Level 3)", rematch.Players[chess.White].Name)
+}
+
+func TestGameService_RematchCorrespondence(t *testing.T) {
+	gs := NewGameService(nil)
+	createCorrespondenceGame(t, gs, "daily", 3)
+	require.NoError(t, gs.Resign("daily", "black-client"))
+
+	_, err := gs.OfferRematch("daily", "black-client")
+	require.NoError(t, err)
+	rematchID, err := gs.AcceptRematch("daily", "white-client")
+	require.NoError(t, err)
+
+	rematch, err := gs.GetGameState(rematchID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, rematch.DaysPerMove, "a correspondence game's rematch is one too")
+	assert.Nil(t, rematch.Clock)
+	assert.Equal(t, StatusInProgress, rematch.Status)
+	assert.False(t, rematch.MoveDeadline.IsZero())
+
+	token, err := gs.IssueSeatToken(rematchID, "black-client")
+	require.NoError(t, err)
+	assert.NotEmpty(t, token, "the rematch seats get tokens of their own")
+}
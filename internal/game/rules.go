@@ -0,0 +1,136 @@
+package game
+
+import (
+	"github.com/corentings/chess/v2"
+)
+
+// rules is what a rule variant adds on top of standard chess: its own
+// start position, restrictions on moves the chess library allows, state
+// of its own and extra ways to win.
+type rules interface {
+	// startFEN is the variant's start position, empty for the standard one
+	startFEN() string
+	// uciVariant is the engine's UCI_Variant name for the variant
+	uciVariant() string
+	// newState returns the variant's state at the start of a game
+	newState() *VariantState
+	// allows reports whether a move the chess library accepts is legal in
+	// the variant
+	allows(before, after *chess.Position) bool
+	// update records a played move in the variant's state
+	update(state *VariantState, before, after *chess.Position)
+	// result returns the winner, "white", "black" or "draw", once the
+	// variant's own rules decide the game
+	result(state *VariantState, pos *chess.Position) (string, bool)
+	// outcome is how a game decided by result ended
+	outcome() Outcome
+}
+
+// VariantState is what a rule variant keeps track of beyond the position.
+// It is sent in game_state and stored with the game.
+type VariantState struct {
+	// Checks counts the checks each side has given in three-check, keyed
+	// by "white" and "black"
+	Checks map[string]int `json:"checks,omitempty"`
+}
+
+var ruleVariants = map[Variant]rules{
+	VariantThreeCheck:    threeCheck{},
+	VariantKingOfTheHill: kingOfTheHill{},
+	VariantRacingKings:   racingKings{},
+}
+
+// inCheck reports whether the side to move is in check.
+func inCheck(pos *chess.Position) bool {
+	board := pos.Board()
+	return attacked(board, kingSquare(board, pos.Turn()), pos.Turn().Other())
+}
+
+// threeCheck is won by giving check three times.
+type threeCheck struct{}
+
+func (threeCheck) startFEN() string   { return "" }
+func (threeCheck) uciVariant() string { return "3check" }
+func (threeCheck) outcome() Outcome   { return OutcomeThreeCheck }
+
+func (threeCheck) newState() *VariantState {
+	return &VariantState{Checks: map[string]int{"white": 0, "black": 0}}
+}
+
+func (threeCheck) allows(before, after *chess.Position) bool { return true }
+
+func (threeCheck) update(state *VariantState, before, after *chess.Position) {
+	if inCheck(after) {
+		state.Checks[colorName(before.Turn())]++
+	}
+}
+
+func (threeCheck) result(state *VariantState, pos *chess.Position) (string, bool) {
+	for color, checks := range state.Checks {
+		if checks >= 3 {
+			return color, true
+		}
+	}
+	return "", false
+}
+
+// kingOfTheHill is won by bringing the king to one of the four centre
+// squares.
+type kingOfTheHill struct{}
+
+var hillSquares = []chess.Square{chess.D4, chess.E4, chess.D5, chess.E5}
+
+func (kingOfTheHill) startFEN() string                                          { return "" }
+func (kingOfTheHill) uciVariant() string                                        { return "kingofthehill" }
+func (kingOfTheHill) outcome() Outcome                                          { return OutcomeKingOfTheHill }
+func (kingOfTheHill) newState() *VariantState                                   { return &VariantState{} }
+func (kingOfTheHill) allows(before, after *chess.Position) bool                 { return true }
+func (kingOfTheHill) update(state *VariantState, before, after *chess.Position) {}
+
+func (kingOfTheHill) result(state *VariantState, pos *chess.Position) (string, bool) {
+	for _, sq := range hillSquares {
+		if piece := pos.Board().Piece(sq); piece.Type() == chess.King {
+			return colorName(piece.Color()), true
+		}
+	}
+	return "", false
+}
+
+// racingKings is won by the first king to reach the eighth rank. Nobody may
+// give check, and if white gets there first black has one move to draw by
+// arriving too.
+type racingKings struct{}
+
+func (racingKings) startFEN() string                                          { return "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1" }
+func (racingKings) uciVariant() string                                        { return "racingkings" }
+func (racingKings) outcome() Outcome                                          { return OutcomeRacingKings }
+func (racingKings) newState() *VariantState                                   { return &VariantState{} }
+func (racingKings) update(state *VariantState, before, after *chess.Position) {}
+
+func (racingKings) allows(before, after *chess.Position) bool {
+	return !inCheck(after)
+}
+
+func (r racingKings) result(state *VariantState, pos *chess.Position) (string, bool) {
+	board := pos.Board()
+	whiteHome := kingSquare(board, chess.White).Rank() == chess.Rank8
+	blackHome := kingSquare(board, chess.Black).Rank() == chess.Rank8
+
+	switch {
+	case whiteHome && blackHome:
+		return WinnerDraw, true
+	case blackHome:
+		return "black", true
+	case whiteHome && pos.Turn() == chess.White:
+		return "white", true // black had its move and didn't catch up
+	case whiteHome:
+		for _, move := range pos.ValidMoves() {
+			after := pos.Update(&move)
+			if board.Piece(move.S1()).Type() == chess.King && move.S2().Rank() == chess.Rank8 && r.allows(pos, after) {
+				return "", false
+			}
+		}
+		return "white", true
+	}
+	return "", false
+}
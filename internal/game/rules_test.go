@@ -0,0 +1,127 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// playVariantGame sets up a human game under variant and plays moves,
+// alternating sides from white.
+func playVariantGame(t *testing.T, variant Variant, moves []string) *GameService {
+	t.Helper()
+	gs := NewGameService(nil)
+	_, err := gs.CreateGame("variant-game", "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, gs.SetVariant("variant-game", variant, nil))
+	require.NoError(t, gs.JoinGame("variant-game", "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame("variant-game", "black-client", "Bob", chess.Black))
+
+	players := []string{"white-client", "black-client"}
+	for i, move := range moves {
+		_, err := gs.MakeMove("variant-game", players[i%2], move)
+		require.NoError(t, err, move)
+	}
+	return gs
+}
+
+func TestThreeCheck(t *testing.T) {
+	gs := playVariantGame(t, VariantThreeCheck, []string{"e2e4", "f7f6", "d1h5", "g7g6", "h5g6", "h7g6", "f1c4", "a7a6"})
+
+	state, err := gs.GetGameState("variant-game")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"white": 2, "black": 0}, state.VariantState.Checks)
+	assert.Equal(t, StatusInProgress, state.Status)
+
+	result, err := gs.MakeMove("variant-game", "white-client", "c4f7")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, result.GameStatus)
+
+	state, err = gs.GetGameState("variant-game")
+	require.NoError(t, err)
+	assert.Equal(t, "white", state.Winner)
+	assert.Equal(t, OutcomeThreeCheck, state.Outcome)
+
+	game, _ := gs.GetGame("variant-game")
+	assert.Contains(t, game.pgn(), `[Variant "Three-check"]`)
+	assert.Contains(t, game.pgn(), "5. Bf7+ 1-0")
+}
+
+func TestKingOfTheHill(t *testing.T) {
+	gs := playVariantGame(t, VariantKingOfTheHill, []string{"e2e3", "e7e6", "e1e2", "e8e7", "e2d3", "e7d6", "d3d4"})
+
+	state, err := gs.GetGameState("variant-game")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, state.Status)
+	assert.Equal(t, "white", state.Winner)
+	assert.Equal(t, OutcomeKingOfTheHill, state.Outcome)
+}
+
+func TestKingOfTheHill_BareKingsPlayOn(t *testing.T) {
+	replay, _, err := replayGame(VariantKingOfTheHill, "4k3/8/8/8/8/8/5r2/4K3 w - - 0 1", []string{"e1f2"})
+	require.NoError(t, err)
+	require.Equal(t, chess.InsufficientMaterial, replay.ChessGame.Method())
+
+	replay.Status = StatusInProgress
+	assert.False(t, NewGameService(nil).checkTermination(replay), "the kings can still race to the centre")
+}
+
+func TestVariant_AutomaticDrawsAfterBareKings(t *testing.T) {
+	gs := NewGameService(nil)
+	shuffle := []string{"e1d1", "e8d8", "d1e1", "d8e8"}
+
+	var moves []string
+	for i := 0; i < 4; i++ {
+		moves = append(moves, shuffle...)
+	}
+	replay, _, err := replayGame(VariantThreeCheck, "4k3/8/8/8/8/8/8/4KN2 w - - 0 1", moves[:12])
+	require.NoError(t, err)
+	require.Equal(t, chess.InsufficientMaterial, replay.ChessGame.Method())
+	replay.Status = StatusInProgress
+	assert.False(t, gs.checkTermination(replay), "the position has only occurred four times")
+
+	replay, _, err = replayGame(VariantThreeCheck, "4k3/8/8/8/8/8/8/4KN2 w - - 0 1", moves)
+	require.NoError(t, err)
+	replay.Status = StatusInProgress
+	require.True(t, gs.checkTermination(replay))
+	assert.Equal(t, WinnerDraw, replay.Winner)
+	assert.Equal(t, OutcomeFivefoldRepetition, replay.Outcome)
+
+	replay, _, err = replayGame(VariantKingOfTheHill, "4k3/8/8/8/8/8/8/4KN2 w - - 149 80", []string{"e1f2"})
+	require.NoError(t, err)
+	replay.Status = StatusInProgress
+	require.True(t, gs.checkTermination(replay))
+	assert.Equal(t, OutcomeSeventyFiveMoveRule, replay.Outcome)
+}
+
+func TestRacingKings(t *testing.T) {
+	gs := playVariantGame(t, VariantRacingKings, nil)
+	state, err := gs.GetGameState("variant-game")
+	require.NoError(t, err)
+	assert.Equal(t, "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1", state.FEN)
+
+	_, _, err = replayGame(VariantRacingKings, "k7/8/8/8/8/8/8/K6R w - - 0 1", []string{"h1h8"})
+	assert.Error(t, err, "moves may not give check")
+
+	tests := map[string]struct {
+		fen     string
+		winner  string
+		decided bool
+	}{
+		"black can still catch up": {"K7/7k/8/8/8/8/8/8 b - - 0 1", "", false},
+		"black caught up":          {"K6k/8/8/8/8/8/8/8 w - - 0 1", WinnerDraw, true},
+		"black is too far behind":  {"K7/8/8/8/8/8/8/7k b - - 0 1", "white", true},
+		"black got there first":    {"7k/K7/8/8/8/8/8/8 w - - 0 1", "black", true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			replay, _, err := replayGame(VariantRacingKings, tt.fen, nil)
+			require.NoError(t, err)
+			winner, decided := racingKings{}.result(replay.VariantState, replay.ChessGame.Position())
+			assert.Equal(t, tt.decided, decided)
+			assert.Equal(t, tt.winner, winner)
+		})
+	}
+}
@@ -2,12 +2,15 @@ package game
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
 	"github.com/hunterMotko/chess-game/internal/database"
 	"github.com/hunterMotko/chess-game/internal/engine"
 )
@@ -22,8 +25,12 @@ const (
 
 type GameState struct {
 	ID           string
+	OwnerID      string // Player who created the game and may restart it
 	Type         GameType
 	ChessGame    *chess.Game
+	Variant      Variant
+	StartFEN     string            // Custom starting position, empty for the standard one
+	Opening      *database.Opening // Opening the game was set up from, if any
 	Players      map[chess.Color]Player
 	CurrentTurn  chess.Color
 	Status       GameStatus
@@ -31,16 +38,69 @@ type GameState struct {
 	LastMoveAt   time.Time
 	AIEngine     *engine.StockfishEngine
 	AIDifficulty int
-	MoveHistory  []string // Store move history for persistence
+	// AIEngines and AISettings configure each side of an AI vs AI game
+	AIEngines   map[chess.Color]*engine.StockfishEngine
+	AISettings  map[chess.Color]AISettings
+	AIMatchPace time.Duration // Pause between an AI match's moves
+	MoveHistory []string      // Store move history for persistence
+	// setupPlies counts the moves the game was set up with from a PGN or an
+	// opening, which begin MoveHistory
+	setupPlies int
+	// castling holds Chess960 castling rights in Shredder-FEN notation; the
+	// chess library tracks castling itself in standard games
+	castling string
+	// VariantState is kept by rule variants such as three-check
+	VariantState *VariantState
+	// SpectatorDelay holds broadcasts back from spectators so they can't
+	// relay moves to a player in real time
+	SpectatorDelay time.Duration
+	Clock          *Clock // nil for untimed games
+	Winner         string // "white", "black" or "draw" once completed
+	Outcome        Outcome
+	CompletedAt    time.Time
+	DrawOfferedBy  chess.Color // chess.NoColor when no offer is pending
+	drawOfferPly   int
+	// TakebackRequestedBy is the color waiting on a takeback answer
+	TakebackRequestedBy chess.Color
+	// Premoves are the moves each side has queued for its next turns
+	Premoves map[chess.Color][]Premove
+	// RematchOfferedBy is the color waiting on a rematch answer
+	RematchOfferedBy chess.Color
+	// RematchOf links a rematch to the game before it, RematchID to the
+	// game after it
+	RematchOf string
+	RematchID string
+	// AdjournRequestedBy and ResumeRequestedBy are the colors waiting on the
+	// opponent to agree to pause or carry on
+	AdjournRequestedBy chess.Color
+	ResumeRequestedBy  chess.Color
+	AdjournedAt        time.Time // Zero unless the game is adjourned
+	resumedAt          time.Time
+	// DaysPerMove makes a correspondence game: each move gets that many
+	// days instead of a clock, until MoveDeadline
+	DaysPerMove  int
+	MoveDeadline time.Time
+	// AutoAcceptTakebacks lets the AI grant every takeback, for teaching games
+	AutoAcceptTakebacks bool
+	// TournamentID is the tournament that paired the game. Tournaments live
+	// in memory only, so the game isn't restored after a restart
+	TournamentID string
+	// clockHistory holds the clock before each timed ply, for takebacks
+	clockHistory []clockSnapshot
+	clockTimer   *time.Timer
 	mutex        sync.RWMutex
 }
 
 type Player struct {
-	ID     string
+	// ID binds a connection to the seat, so it is never sent to clients
+	ID     string `json:"-"`
 	Name   string
 	IsAI   bool
 	Color  chess.Color
 	Rating int
+	// Token is the secret a correspondence player moves over HTTP with,
+	// told only to them when they take the seat
+	Token string `json:"-"`
 }
 
 type GameStatus string
@@ -50,12 +110,48 @@ const (
 	StatusInProgress GameStatus = "in_progress"
 	StatusCompleted  GameStatus = "completed"
 	StatusAbandoned  GameStatus = "abandoned"
+	// StatusAdjourned is a game paused by its players, to be resumed later
+	StatusAdjourned GameStatus = "adjourned"
 )
 
+// Outcome is how a game ended, stored in games.outcome.
+type Outcome string
+
+const (
+	OutcomeCheckmate            Outcome = "checkmate"
+	OutcomeStalemate            Outcome = "stalemate"
+	OutcomeThreefoldRepetition  Outcome = "threefold_repetition"
+	OutcomeFivefoldRepetition   Outcome = "fivefold_repetition"
+	OutcomeFiftyMoveRule        Outcome = "fifty_move_rule"
+	OutcomeSeventyFiveMoveRule  Outcome = "seventy_five_move_rule"
+	OutcomeInsufficientMaterial Outcome = "insufficient_material"
+	OutcomeTimeout              Outcome = "timeout"
+	OutcomeResignation          Outcome = "resignation"
+	OutcomeDrawAgreement        Outcome = "draw_agreement"
+	OutcomeAbandoned            Outcome = "abandoned"
+	OutcomeThreeCheck           Outcome = "three_check"
+	OutcomeKingOfTheHill        Outcome = "king_of_the_hill"
+	OutcomeRacingKings          Outcome = "racing_kings"
+)
+
+const WinnerDraw = "draw"
+
+// colorName returns the lowercase color name used by the games table.
+func colorName(color chess.Color) string {
+	return strings.ToLower(color.Name())
+}
+
 type GameService struct {
 	games map[string]*GameState
 	db    *database.Service
 	mutex sync.RWMutex
+	// onStateChange is called when the service changes a game on its own,
+	// e.g. when a flag falls, so the caller can broadcast the new state
+	onStateChange func(gameID string)
+	// gameOverHandlers hear the result of every game that ends; they have
+	// their own lock since games end while their lock is held
+	gameOverMutex    sync.RWMutex
+	gameOverHandlers []func(GameResult)
 }
 
 func NewGameService(db *database.Service) *GameService {
@@ -65,24 +161,74 @@ func NewGameService(db *database.Service) *GameService {
 	}
 }
 
-func (gs *GameService) CreateGame(gameID string, gameType GameType, difficulty int) (*GameState, error) {
+// SetStateChangeHandler registers a callback for changes the service makes
+// outside of a client request.
+func (gs *GameService) SetStateChangeHandler(handler func(gameID string)) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	gs.onStateChange = handler
+}
+
+func (gs *GameService) notifyStateChange(gameID string) {
+	gs.mutex.RLock()
+	handler := gs.onStateChange
+	gs.mutex.RUnlock()
+	if handler != nil {
+		handler(gameID)
+	}
+}
+
+// finish completes the game. The caller must hold game.mutex.
+func (game *GameState) finish(winner string, outcome Outcome) {
+	now := time.Now()
+	game.Status = StatusCompleted
+	game.Winner = winner
+	game.Outcome = outcome
+	game.CompletedAt = now
+	if game.Clock != nil {
+		game.Clock.Stop(game.ChessGame.Position().Turn(), now)
+	}
+	game.stopClockTimer()
+	game.closeEngines()
+}
+
+func (game *GameState) stopClockTimer() {
+	if game.clockTimer != nil {
+		game.clockTimer.Stop()
+		game.clockTimer = nil
+	}
+}
+
+// CreateGame creates a game owned by ownerID, the player setting it up. An
+// existing game under the same ID is only replaced once it has finished or
+// when its owner restarts it.
+func (gs *GameService) CreateGame(gameID, ownerID string, gameType GameType, difficulty int) (*GameState, error) {
 	gs.mutex.Lock()
 	defer gs.mutex.Unlock()
 	
 	// If game already exists, delete it first to allow clean restart
+	replacing := false
 	if existingGame, exists := gs.games[gameID]; exists {
-		log.Printf("Game %s already exists, cleaning up before creating new game", gameID)
-		if existingGame.AIEngine != nil {
-			existingGame.AIEngine.Close()
+		existingGame.mutex.Lock()
+		if !existingGame.replaceableBy(ownerID) {
+			existingGame.mutex.Unlock()
+			return nil, fmt.Errorf("game %s belongs to another player", gameID)
 		}
+		log.Printf("Game %s already exists, cleaning up before creating new game", gameID)
+		existingGame.stopClockTimer()
+		existingGame.closeEngines()
+		existingGame.mutex.Unlock()
 		delete(gs.games, gameID)
+		replacing = true
 	}
 	
 	chessGame := chess.NewGame()
 	game := &GameState{
 		ID:           gameID,
+		OwnerID:      ownerID,
 		Type:         gameType,
 		ChessGame:    chessGame,
+		Variant:      VariantStandard,
 		Players:      make(map[chess.Color]Player),
 		CurrentTurn:  chessGame.Position().Turn(), // Sync with actual chess state
 		Status:       StatusWaiting,
@@ -91,18 +237,10 @@ func (gs *GameService) CreateGame(gameID string, gameType GameType, difficulty i
 		MoveHistory:  []string{}, // Initialize empty move history
 	}
 	
-	// Initialize AI engine for AI games
-	if gameType == HumanVsAI || gameType == AIVsAI {
-		stockfishEngine, err := engine.NewStockfish()
-		if err != nil {
-			log.Printf("Warning: Could not initialize Stockfish engine: %v", err)
-			log.Printf("Game %s will continue without AI opponent", gameID)
-		} else {
-			game.AIEngine = stockfishEngine
-			if err := stockfishEngine.SetDifficulty(difficulty); err != nil {
-				log.Printf("Warning: Could not set AI difficulty: %v", err)
-			}
-		}
+	// Initialize AI engine for AI games. AI vs AI games get an engine per
+	// side from CreateAIMatch.
+	if gameType == HumanVsAI {
+		game.AIEngine = newAIEngine(gameID, difficulty)
 	}
 	
 	gs.games[gameID] = game
@@ -110,10 +248,19 @@ func (gs *GameService) CreateGame(gameID string, gameType GameType, difficulty i
 	// Save to database if available
 	if gs.db != nil {
 		ctx := context.Background()
-		err := gs.db.CreateGame(ctx, gameID, string(gameType), string(StatusWaiting), 
+		if replacing {
+			if err := gs.db.DeleteGame(ctx, gameID); err != nil {
+				log.Printf("Warning: Failed to delete replaced game from database: %v", err)
+			}
+		}
+		err := gs.db.CreateGame(ctx, gameID, ownerID, string(gameType), string(StatusWaiting), 
 			game.ChessGame.FEN(), nil, nil, nil, nil, "white", difficulty)
 		if err != nil {
-			log.Printf("Warning: Failed to save game to database: %v", err)
+			// The ID may belong to a game that is only in the database;
+			// carrying on would overwrite its row and moves
+			delete(gs.games, gameID)
+			game.closeEngines()
+			return nil, fmt.Errorf("failed to save game %s: %v", gameID, err)
 		}
 	}
 	
@@ -122,6 +269,67 @@ func (gs *GameService) CreateGame(gameID string, gameType GameType, difficulty i
 	return game, nil
 }
 
+// replaceableBy reports whether playerID may replace the game with a new
+// one under its ID. The caller must hold game.mutex.
+func (game *GameState) replaceableBy(playerID string) bool {
+	finished := game.Status == StatusCompleted || game.Status == StatusAbandoned
+	return finished || (game.OwnerID != "" && game.OwnerID == playerID)
+}
+
+// NewGameID returns the ID a new game set up by playerID on gameID's
+// connection should take. A free ID, or an unfinished game of playerID's
+// own being restarted, keeps gameID. A finished game is kept for the
+// record, as is any game only in the database, e.g. one swept from memory
+// or adjourned, so the new game gets a fresh ID. Anyone else's unfinished
+// game can't be replaced.
+func (gs *GameService) NewGameID(gameID, playerID string) (string, error) {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		if gs.db == nil {
+			return gameID, nil
+		}
+		stored, err := gs.db.GameExists(context.Background(), gameID)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up game %s: %v", gameID, err)
+		}
+		if stored {
+			return uuid.NewString(), nil
+		}
+		return gameID, nil
+	}
+	
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	
+	switch {
+	case game.Status == StatusCompleted || game.Status == StatusAbandoned:
+		return uuid.NewString(), nil
+	case game.replaceableBy(playerID):
+		return gameID, nil
+	}
+	return "", fmt.Errorf("game %s belongs to another player", gameID)
+}
+
+// newAIEngine starts Stockfish at the given difficulty. It returns nil if
+// the engine isn't available, leaving the game without an AI opponent.
+func newAIEngine(gameID string, difficulty int) *engine.StockfishEngine {
+	stockfishEngine, err := engine.NewStockfish()
+	if err != nil {
+		log.Printf("Warning: Could not initialize Stockfish engine: %v", err)
+		log.Printf("Game %s will continue without AI opponent", gameID)
+		return nil
+	}
+	if err := stockfishEngine.SetDifficulty(difficulty); err != nil {
+		log.Printf("Warning: Could not set AI difficulty: %v", err)
+	}
+	return stockfishEngine
+}
+
+// aiPlayerID is the player ID given to the AI seat of a game.
+func aiPlayerID(gameID string) string {
+	return fmt.Sprintf("ai_%s", gameID)
+}
+
 func (gs *GameService) GetGame(gameID string) (*GameState, bool) {
 	gs.mutex.RLock()
 	defer gs.mutex.RUnlock()
@@ -130,6 +338,9 @@ func (gs *GameService) GetGame(gameID string) (*GameState, bool) {
 	return game, exists
 }
 
+// JoinGame seats playerID at the given color. Passing chess.NoColor takes
+// whichever seat is still free. Rejoining a seat the player already holds
+// is a no-op so reconnecting clients can call it again safely.
 func (gs *GameService) JoinGame(gameID, playerID, playerName string, color chess.Color) error {
 	gs.mutex.Lock()
 	defer gs.mutex.Unlock()
@@ -142,6 +353,23 @@ func (gs *GameService) JoinGame(gameID, playerID, playerName string, color chess
 	game.mutex.Lock()
 	defer game.mutex.Unlock()
 	
+	for seat, player := range game.Players {
+		if player.ID == playerID && !player.IsAI {
+			if color != chess.NoColor && color != seat {
+				return fmt.Errorf("player %s already seated as %s", playerID, seat)
+			}
+			return nil
+		}
+	}
+	
+	if color == chess.NoColor {
+		if _, occupied := game.Players[chess.White]; !occupied {
+			color = chess.White
+		} else {
+			color = chess.Black
+		}
+	}
+	
 	// Check if player slot is available
 	if _, occupied := game.Players[color]; occupied {
 		return fmt.Errorf("color %s already taken", color)
@@ -164,7 +392,7 @@ func (gs *GameService) JoinGame(gameID, playerID, playerName string, color chess
 		}
 		
 		aiPlayer := Player{
-			ID:    fmt.Sprintf("ai_%s", gameID),
+			ID:    aiPlayerID(gameID),
 			Name:  fmt.Sprintf("Stockfish (Level %d)", game.AIDifficulty),
 			IsAI:  true,
 			Color: aiColor,
@@ -174,22 +402,206 @@ func (gs *GameService) JoinGame(gameID, playerID, playerName string, color chess
 	
 	// Start game if we have enough players
 	if len(game.Players) >= 2 || game.Type == HumanVsAI {
-		game.Status = StatusInProgress
-		log.Printf("Game %s started", gameID)
+		gs.startGame(game)
+	}
+	
+	gs.persistPlayers(game)
+	gs.persistGame(game)
+	
+	return nil
+}
+
+// startGame puts a seated game in progress and starts its clock. The
+// caller must hold game.mutex.
+func (gs *GameService) startGame(game *GameState) {
+	game.Status = StatusInProgress
+	if game.Clock != nil {
+		game.Clock.Start(time.Now())
+		gs.armClockTimer(game)
+	}
+	if game.DaysPerMove > 0 {
+		gs.setMoveDeadline(game, time.Now())
+	}
+	log.Printf("Game %s started", game.ID)
+}
+
+// PlayerColor returns the seat held by playerID in the given game.
+func (gs *GameService) PlayerColor(gameID, playerID string) (chess.Color, bool) {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return chess.NoColor, false
+	}
+	
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	
+	color, err := game.seatOf(playerID)
+	return color, err == nil
+}
+
+// SetSpectatorDelay sets how long spectators lag behind the live game.
+func (gs *GameService) SetSpectatorDelay(gameID string, delay time.Duration) error {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+	if delay < 0 {
+		return fmt.Errorf("spectator delay cannot be negative")
+	}
+	
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+	
+	game.SpectatorDelay = delay
+	gs.persistOptions(game)
+	return nil
+}
+
+// SetTournament marks a game as paired in a tournament.
+func (gs *GameService) SetTournament(gameID, tournamentID string) error {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return fmt.Errorf("game %s not found", gameID)
 	}
 	
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+	
+	game.TournamentID = tournamentID
+	gs.persistTournament(game)
 	return nil
 }
 
+func (gs *GameService) SpectatorDelay(gameID string) time.Duration {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return 0
+	}
+	
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+	
+	if game.Variant == VariantFogOfWar {
+		return max(game.SpectatorDelay, fogOfWarSpectatorDelay)
+	}
+	return game.SpectatorDelay
+}
+
+// SetTimeControl puts a clock on a game that hasn't started yet.
+func (gs *GameService) SetTimeControl(gameID string, tc TimeControl) error {
+	if err := tc.Validate(); err != nil {
+		return err
+	}
+	
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+	
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+	
+	if game.Status != StatusWaiting {
+		return fmt.Errorf("cannot change time control once game %s has started", gameID)
+	}
+	if game.DaysPerMove > 0 {
+		return fmt.Errorf("game %s is a correspondence game and has no clock", gameID)
+	}
+	game.Clock = NewClock(tc)
+	
+	if gs.db != nil {
+		timeControl, _ := json.Marshal(tc)
+		if err := gs.db.UpdateGameTimeControl(context.Background(), gameID, string(timeControl)); err != nil {
+			log.Printf("Warning: Failed to save time control to database: %v", err)
+		}
+	}
+	
+	return nil
+}
+
+// armClockTimer schedules a flag check for the side to move so the flag
+// falls on the server even if the client never moves again. The caller
+// must hold game.mutex.
+func (gs *GameService) armClockTimer(game *GameState) {
+	game.stopClockTimer()
+	if game.Clock == nil || !game.Clock.Running() {
+		return
+	}
+	
+	gameID := game.ID
+	flagIn := game.Clock.FlagIn(game.ChessGame.Position().Turn(), time.Now())
+	game.clockTimer = time.AfterFunc(max(flagIn, 0), func() {
+		gs.checkFlag(gameID)
+	})
+}
+
+func (gs *GameService) checkFlag(gameID string) {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return
+	}
+	
+	game.mutex.Lock()
+	flagged := gs.flagIfExpired(game, time.Now())
+	if !flagged && game.Status == StatusInProgress {
+		// Timer fired a little early; check again when it is really due
+		gs.armClockTimer(game)
+	}
+	game.mutex.Unlock()
+	
+	if flagged {
+		gs.notifyStateChange(gameID)
+	}
+}
+
+// flagIfExpired ends the game on time if the side to move has flagged, or
+// has let its correspondence deadline pass. The caller must hold
+// game.mutex.
+func (gs *GameService) flagIfExpired(game *GameState, now time.Time) bool {
+	if game.Status != StatusInProgress {
+		return false
+	}
+	turn := game.ChessGame.Position().Turn()
+	switch {
+	case game.Clock != nil && game.Clock.Flagged(turn, now):
+	case game.DaysPerMove > 0 && !game.MoveDeadline.IsZero() && now.After(game.MoveDeadline):
+	default:
+		return false
+	}
+	
+	winner := colorName(turn.Other())
+	if !hasMatingMaterial(game.ChessGame.Position().Board(), turn.Other()) {
+		winner = WinnerDraw
+	}
+	gs.endGame(game, winner, OutcomeTimeout)
+	log.Printf("Game %s: %s flagged", game.ID, turn.Name())
+	return true
+}
+
 func (gs *GameService) MakeMove(gameID string, playerID string, moveStr string) (*MoveResult, error) {
 	game, exists := gs.GetGame(gameID)
 	if !exists {
 		return nil, fmt.Errorf("game %s not found", gameID)
 	}
 	
+	flagged := false
+	defer func() {
+		if flagged {
+			gs.notifyStateChange(gameID)
+		}
+	}()
+	
 	game.mutex.Lock()
 	defer game.mutex.Unlock()
 	
+	if flagged = gs.flagIfExpired(game, time.Now()); flagged {
+		return nil, fmt.Errorf("game %s: time expired", gameID)
+	}
+	
+	if game.Status != StatusInProgress {
+		return nil, fmt.Errorf("game %s is not in progress (status: %s)", gameID, game.Status)
+	}
+	
 	// Validate player can make this move - use actual chess game turn, not stored CurrentTurn
 	actualTurn := game.ChessGame.Position().Turn()
 	currentPlayer, exists := game.Players[actualTurn]
@@ -200,52 +612,41 @@ func (gs *GameService) MakeMove(gameID string, playerID string, moveStr string)
 	log.Printf("🔍 Move validation - Turn: %s, CurrentPlayer: %s (IsAI: %v), Incoming PlayerID: %s", 
 		actualTurn.String(), currentPlayer.ID, currentPlayer.IsAI, playerID)
 	
-	if !currentPlayer.IsAI && currentPlayer.ID != playerID {
+	if currentPlayer.IsAI || currentPlayer.ID != playerID {
 		return nil, fmt.Errorf("not your turn - expected player %s but got %s", currentPlayer.ID, playerID)
 	}
 	
-	// Find and validate the move
-	validMoves := game.ChessGame.ValidMoves()
-	var selectedMove *chess.Move
-	
-	for _, move := range validMoves {
-		if move.String() == moveStr || 
-		   (len(move.String()) >= 4 && move.String()[:4] == moveStr) {
-			selectedMove = &move
-			break
-		}
-	}
-	
-	if selectedMove == nil {
-		// Enhanced debugging - show current state when move fails
-		currentFEN := game.ChessGame.FEN()
-		log.Printf("🔍 Move validation failed for %s", moveStr)
-		log.Printf("  Current FEN: %s", currentFEN)
-		log.Printf("  Valid moves available:")
-		for i, validMove := range validMoves {
-			if i < 10 { // Show first 10 valid moves
-				log.Printf("    %s", validMove.String())
-			}
-		}
-		if len(validMoves) > 10 {
-			log.Printf("    ... and %d more moves", len(validMoves)-10)
-		}
-		return nil, fmt.Errorf("invalid move: %s", moveStr)
+	// Validate and apply the move; a rejected move comes back as a
+	// *MoveError listing the legal alternatives
+	uci, san, err := game.playMove(moveStr)
+	if err != nil {
+		log.Printf("🔍 Move %s rejected in %s: %v", moveStr, game.fen(), err)
+		return nil, err
 	}
+	game.expireDrawOffer(actualTurn)
+	result := gs.recordMove(game, actualTurn, uci, san)
+	log.Printf("Move made in game %s: %s (%s)", gameID, uci, moveStr)
 	
-	// Apply move
-	if err := game.ChessGame.Move(selectedMove, nil); err != nil {
-		return nil, fmt.Errorf("failed to apply move: %v", err)
-	}
+	return gs.playPremove(game, uci, result), nil
+}
+
+// recordMove does the bookkeeping for a move mover has just played: the
+// clock, the move history, persistence and the end of the game. Moves are
+// recorded in UCI whatever notation they came in. The caller must hold
+// game.mutex.
+func (gs *GameService) recordMove(game *GameState, mover chess.Color, uci, san string) *MoveResult {
+	timeTaken := game.punchClock(mover)
 	
 	// Add move to history
-	game.MoveHistory = append(game.MoveHistory, moveStr)
+	game.TakebackRequestedBy = chess.NoColor
+	game.MoveHistory = append(game.MoveHistory, uci)
 	game.LastMoveAt = time.Now()
+	gs.persistMove(game, uci, san, timeTaken)
 	
 	// Check game status
 	result := &MoveResult{
-		Move:         moveStr,
-		FEN:          game.ChessGame.FEN(),
+		Move:         uci,
+		FEN:          game.fen(),
 		Turn:         game.ChessGame.Position().Turn(),
 		IsCheck:      game.ChessGame.Position().Status().String() == "in_check",
 		IsCheckmate:  game.ChessGame.Method() == chess.Checkmate,
@@ -254,19 +655,29 @@ func (gs *GameService) MakeMove(gameID string, playerID string, moveStr string)
 	}
 	
 	// Update game status if ended
-	if result.IsCheckmate || result.IsStalemate || game.ChessGame.Outcome() != chess.NoOutcome {
-		game.Status = StatusCompleted
+	if gs.checkTermination(game) {
 		result.GameStatus = StatusCompleted
-		if game.AIEngine != nil {
-			game.AIEngine.Close()
-		}
 	} else {
 		// Switch turns
 		game.CurrentTurn = game.ChessGame.Position().Turn()
+		gs.armClockTimer(game)
+		gs.persistGame(game)
+		if game.DaysPerMove > 0 {
+			gs.setMoveDeadline(game, game.LastMoveAt)
+		}
 	}
-	
-	log.Printf("Move made in game %s: %s", gameID, moveStr)
-	return result, nil
+	return result
+}
+
+// findValidMove finds a move, given exactly in UCI, among the legal moves.
+// Players' moves go through decodeMove instead.
+func findValidMove(validMoves []chess.Move, moveStr string) *chess.Move {
+	for _, move := range validMoves {
+		if move.String() == moveStr {
+			return &move
+		}
+	}
+	return nil
 }
 
 func (gs *GameService) GetAIMove(gameID string) (*MoveResult, error) {
@@ -275,9 +686,24 @@ func (gs *GameService) GetAIMove(gameID string) (*MoveResult, error) {
 		return nil, fmt.Errorf("game %s not found", gameID)
 	}
 	
+	flagged := false
+	defer func() {
+		if flagged {
+			gs.notifyStateChange(gameID)
+		}
+	}()
+	
 	game.mutex.Lock()
 	defer game.mutex.Unlock()
 	
+	if flagged = gs.flagIfExpired(game, time.Now()); flagged {
+		return nil, fmt.Errorf("game %s: time expired", gameID)
+	}
+	
+	if game.Status != StatusInProgress {
+		return nil, fmt.Errorf("game %s is not in progress (status: %s)", gameID, game.Status)
+	}
+	
 	// Check if it's AI's turn - use actual chess game turn, not stored CurrentTurn
 	actualTurn := game.ChessGame.Position().Turn()
 	currentPlayer, exists := game.Players[actualTurn]
@@ -287,16 +713,30 @@ func (gs *GameService) GetAIMove(gameID string) (*MoveResult, error) {
 		return nil, fmt.Errorf("not AI's turn - current turn: %s, isAI: %v", actualTurn.String(), exists && currentPlayer.IsAI)
 	}
 	
-	if game.AIEngine == nil {
+	aiEngine := game.engineFor(actualTurn)
+	if aiEngine == nil {
 		return nil, fmt.Errorf("AI engine not available")
 	}
 	
 	// Get AI move with optimized time limit based on difficulty for better UX
 	// Faster response times for smoother gameplay
-	timeLimit := time.Duration(500+game.AIDifficulty*150) * time.Millisecond
-	depth := 1 + game.AIDifficulty/4 // Slightly reduced depth for faster responses
+	settings := game.aiSettingsFor(actualTurn)
+	timeLimit := time.Duration(500+settings.Difficulty*150) * time.Millisecond
+	depth := 1 + settings.Difficulty/4 // Slightly reduced depth for faster responses
+	if settings.Depth > 0 {
+		depth = settings.Depth
+	}
+	if settings.MoveTimeMs > 0 {
+		timeLimit = time.Duration(settings.MoveTimeMs) * time.Millisecond
+	}
+	
+	// The AI plays on the same clock, so never think past a fraction of it
+	if game.Clock != nil {
+		budget := game.Clock.FlagIn(actualTurn, time.Now()) / 20
+		timeLimit = max(min(timeLimit, budget), 50*time.Millisecond)
+	}
 	
-	moveResponse, err := game.AIEngine.GetBestMove(game.ChessGame.FEN(), depth, timeLimit)
+	moveResponse, err := aiEngine.GetBestMove(game.fen(), depth, timeLimit)
 	if err != nil {
 		return nil, fmt.Errorf("AI engine error: %v", err)
 	}
@@ -310,75 +750,44 @@ func (gs *GameService) GetAIMove(gameID string) (*MoveResult, error) {
 		return nil, fmt.Errorf("invalid UCI move format: %s", uciMove)
 	}
 	
-	promotion := ""
-	if len(uciMove) > 4 {
-		promotion = uciMove[4:]
-	}
-	
-	// Find the valid move that matches the UCI format
-	validMoves := game.ChessGame.ValidMoves()
-	var selectedMove *chess.Move
-	
-	for _, move := range validMoves {
-		// Check if move matches UCI format (e.g., e2e4, e7e8q)
-		moveStr := move.String()
-		
-		// Standard move matching
-		if moveStr == uciMove {
-			selectedMove = &move
-			break
-		}
-		
-		// For promotions, the chess library might format differently
-		if promotion != "" {
-			// Try different promotion formats
-			if len(moveStr) >= 4 && moveStr[:4] == uciMove[:4] {
-				selectedMove = &move
-				break
-			}
-		}
-	}
-	
-	if selectedMove == nil {
-		return nil, fmt.Errorf("invalid AI move: %s (no matching valid move found)", uciMove)
+	// The engine might still have overstepped the clock
+	if flagged = gs.flagIfExpired(game, time.Now()); flagged {
+		return nil, fmt.Errorf("game %s: AI ran out of time", gameID)
 	}
 	
-	// Apply the move
-	if err := game.ChessGame.Move(selectedMove, nil); err != nil {
-		return nil, fmt.Errorf("failed to apply AI move: %v", err)
+	// Apply the move, matching it against the legal moves (e.g., e2e4, e7e8q)
+	uci, san, err := game.playMove(uciMove)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AI move: %v", err)
 	}
 	
-	log.Printf("Successfully applied AI move: %s", selectedMove.String())
+	log.Printf("Successfully applied AI move: %s", uci)
 	
-	// Add AI move to history
-	game.MoveHistory = append(game.MoveHistory, uciMove)
-	game.LastMoveAt = time.Now()
+	// Record the move as matched, which may differ from the engine's notation
+	result := gs.recordMove(game, actualTurn, uci, san)
+	log.Printf("AI move made in game %s: %s", gameID, moveResponse.Move)
 	
-	// Check game status
-	result := &MoveResult{
-		Move:         uciMove, // Use the original UCI move
-		FEN:          game.ChessGame.FEN(),
-		Turn:         game.ChessGame.Position().Turn(),
-		IsCheck:      game.ChessGame.Position().Status().String() == "in_check",
-		IsCheckmate:  game.ChessGame.Method() == chess.Checkmate,
-		IsStalemate:  game.ChessGame.Method() == chess.Stalemate,
-		GameStatus:   game.Status,
+	return gs.playPremove(game, uci, result), nil
+}
+
+// punchClock settles the mover's clock after a move and returns how long
+// the mover thought, in milliseconds, for timed games. The caller must hold
+// game.mutex and have already checked the mover hasn't flagged.
+func (game *GameState) punchClock(mover chess.Color) *int {
+	if game.Clock == nil {
+		return nil
 	}
-	
-	// Update game status if ended
-	if result.IsCheckmate || result.IsStalemate || game.ChessGame.Outcome() != chess.NoOutcome {
-		game.Status = StatusCompleted
-		result.GameStatus = StatusCompleted
-		if game.AIEngine != nil {
-			game.AIEngine.Close()
-		}
-	} else {
-		// Switch turns
-		game.CurrentTurn = game.ChessGame.Position().Turn()
+	now := time.Now()
+	game.clockHistory = append(game.clockHistory, game.Clock.snapshot(len(game.MoveHistory)))
+	var timeTaken *int
+	if game.Clock.Running() {
+		ms := int(now.Sub(game.Clock.TurnStartedAt).Milliseconds())
+		timeTaken = &ms
 	}
-	
-	log.Printf("AI move made in game %s: %s", gameID, moveResponse.Move)
-	return result, nil
+	if err := game.Clock.Punch(mover, now); err != nil {
+		log.Printf("Game %s: %v", game.ID, err)
+	}
+	return timeTaken
 }
 
 func (gs *GameService) GetGameState(gameID string) (*GameStateResponse, error) {
@@ -390,20 +799,51 @@ func (gs *GameService) GetGameState(gameID string) (*GameStateResponse, error) {
 	game.mutex.RLock()
 	defer game.mutex.RUnlock()
 	
+	return game.response(), nil
+}
+
+// response renders the full game state. The caller must hold game.mutex.
+func (game *GameState) response() *GameStateResponse {
+	var clock *ClockResponse
+	if game.Clock != nil {
+		clock = game.Clock.Response(game.ChessGame.Position().Turn(), time.Now())
+	}
+	var moveDeadline *time.Time
+	if game.DaysPerMove > 0 && game.Status == StatusInProgress {
+		moveDeadline = &game.MoveDeadline
+	}
+	
 	return &GameStateResponse{
-		ID:          game.ID,
-		Type:        game.Type,
-		FEN:         game.ChessGame.FEN(),
-		Turn:        game.ChessGame.Position().Turn(),
-		Status:      game.Status,
-		Players:     game.Players,
-		IsCheck:     game.ChessGame.Position().Status().String() == "in_check",
-		IsCheckmate: game.ChessGame.Method() == chess.Checkmate,
-		IsStalemate: game.ChessGame.Method() == chess.Stalemate,
-		CreatedAt:   game.CreatedAt,
-		LastMoveAt:  game.LastMoveAt,
-		MoveHistory: game.MoveHistory,
-	}, nil
+		ID:                  game.ID,
+		Type:                game.Type,
+		Variant:             game.Variant,
+		VariantState:        game.VariantState,
+		FEN:                 game.fen(),
+		StartFEN:            game.StartFEN,
+		Opening:             game.Opening,
+		Turn:                game.ChessGame.Position().Turn(),
+		Status:              game.Status,
+		Players:             game.Players,
+		IsCheck:             game.ChessGame.Position().Status().String() == "in_check",
+		IsCheckmate:         game.ChessGame.Method() == chess.Checkmate,
+		IsStalemate:         game.ChessGame.Method() == chess.Stalemate,
+		CreatedAt:           game.CreatedAt,
+		LastMoveAt:          game.LastMoveAt,
+		MoveHistory:         game.MoveHistory,
+		Clock:               clock,
+		Winner:              game.Winner,
+		Outcome:             game.Outcome,
+		DrawOfferedBy:       game.DrawOfferedBy,
+		ClaimableDraws:      game.claimableDraws(),
+		TakebackRequestedBy: game.TakebackRequestedBy,
+		RematchOfferedBy:    game.RematchOfferedBy,
+		RematchOf:           game.RematchOf,
+		RematchID:           game.RematchID,
+		AdjournRequestedBy:  game.AdjournRequestedBy,
+		ResumeRequestedBy:   game.ResumeRequestedBy,
+		DaysPerMove:         game.DaysPerMove,
+		MoveDeadline:        moveDeadline,
+	}
 }
 
 func (gs *GameService) DeleteGame(gameID string) error {
@@ -411,9 +851,10 @@ func (gs *GameService) DeleteGame(gameID string) error {
 	defer gs.mutex.Unlock()
 	
 	if game, exists := gs.games[gameID]; exists {
-		if game.AIEngine != nil {
-			game.AIEngine.Close()
-		}
+		game.mutex.Lock()
+		game.stopClockTimer()
+		game.closeEngines()
+		game.mutex.Unlock()
 		delete(gs.games, gameID)
 		log.Printf("Deleted game: %s", gameID)
 	}
@@ -429,19 +870,50 @@ type MoveResult struct {
 	IsCheckmate bool        `json:"isCheckmate"`
 	IsStalemate bool        `json:"isStalemate"`
 	GameStatus  GameStatus  `json:"gameStatus"`
+	// Premove is the reply queued by the opponent, if it was played straight
+	// away; the fields above then describe the position after it
+	Premove string `json:"premove,omitempty"`
 }
 
 type GameStateResponse struct {
-	ID          string                 `json:"id"`
-	Type        GameType               `json:"type"`
-	FEN         string                 `json:"fen"`
-	Turn        chess.Color            `json:"turn"`
-	Status      GameStatus             `json:"status"`
-	Players     map[chess.Color]Player `json:"players"`
-	IsCheck     bool                   `json:"isCheck"`
-	IsCheckmate bool                   `json:"isCheckmate"`
-	IsStalemate bool                   `json:"isStalemate"`
-	CreatedAt   time.Time              `json:"createdAt"`
-	LastMoveAt  time.Time              `json:"lastMoveAt"`
-	MoveHistory []string               `json:"moveHistory"`
-}
\ No newline at end of file
+	ID            string                 `json:"id"`
+	Type          GameType               `json:"type"`
+	Variant       Variant                `json:"variant"`
+	VariantState  *VariantState          `json:"variantState,omitempty"` // e.g. three-check counters
+	FEN           string                 `json:"fen"`                    // Shredder-FEN in Chess960
+	StartFEN      string                 `json:"startFen,omitempty"`     // Position MoveHistory starts from
+	Opening       *database.Opening      `json:"opening,omitempty"`
+	Turn          chess.Color            `json:"turn"`
+	Status        GameStatus             `json:"status"`
+	Players       map[chess.Color]Player `json:"players"`
+	IsCheck       bool                   `json:"isCheck"`
+	IsCheckmate   bool                   `json:"isCheckmate"`
+	IsStalemate   bool                   `json:"isStalemate"`
+	CreatedAt     time.Time              `json:"createdAt"`
+	LastMoveAt    time.Time              `json:"lastMoveAt"`
+	MoveHistory   []string               `json:"moveHistory"`
+	Clock         *ClockResponse         `json:"clock,omitempty"`
+	Winner        string                 `json:"winner,omitempty"`
+	Outcome       Outcome                `json:"outcome,omitempty"`
+	DrawOfferedBy chess.Color            `json:"drawOfferedBy,omitempty"` // Color with a pending draw offer
+	// ClaimableDraws lists the draws the side to move may claim right now
+	ClaimableDraws []Outcome `json:"claimableDraws,omitempty"`
+	// TakebackRequestedBy is the color waiting on a takeback answer, if any
+	TakebackRequestedBy chess.Color `json:"takebackRequestedBy,omitempty"`
+	// VisibleSquares are the squares a fog of war player sees; FEN leaves
+	// every other square empty
+	VisibleSquares []string `json:"visibleSquares,omitempty"`
+	// Premoves are the viewer's own queued moves
+	Premoves []Premove `json:"premoves,omitempty"`
+	// RematchOfferedBy is the color waiting on a rematch answer, if any
+	RematchOfferedBy chess.Color `json:"rematchOfferedBy,omitempty"`
+	RematchOf        string      `json:"rematchOf,omitempty"` // Game this one is a rematch of
+	RematchID        string      `json:"rematchId,omitempty"` // Rematch of this game, once agreed
+	// AdjournRequestedBy and ResumeRequestedBy are the colors waiting on an
+	// answer to pause or carry on, if any
+	AdjournRequestedBy chess.Color `json:"adjournRequestedBy,omitempty"`
+	ResumeRequestedBy  chess.Color `json:"resumeRequestedBy,omitempty"`
+	// DaysPerMove and MoveDeadline are set for correspondence games
+	DaysPerMove  int        `json:"daysPerMove,omitempty"`
+	MoveDeadline *time.Time `json:"moveDeadline,omitempty"`
+}
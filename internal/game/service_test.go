@@ -0,0 +1,99 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createHumanGame creates a human vs human game with both seats filled
+func createHumanGame(t *testing.T, gs *GameService, gameID string) {
+	t.Helper()
+	_, err := gs.CreateGame(gameID, "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, gs.JoinGame(gameID, "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame(gameID, "black-client", "Bob", chess.NoColor))
+}
+
+func TestGameService_JoinGame(t *testing.T) {
+	gs := NewGameService(nil)
+
+	_, err := gs.CreateGame("join-game", "client-1", HumanVsHuman, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, gs.JoinGame("join-game", "client-1", "Alice", chess.NoColor))
+	game, _ := gs.GetGame("join-game")
+	assert.Equal(t, StatusWaiting, game.Status)
+
+	// Rejoining the same seat is allowed, taking a second one is not
+	assert.NoError(t, gs.JoinGame("join-game", "client-1", "Alice", chess.White))
+	assert.Error(t, gs.JoinGame("join-game", "client-1", "Alice", chess.Black))
+
+	require.NoError(t, gs.JoinGame("join-game", "client-2", "Bob", chess.NoColor))
+	assert.Equal(t, StatusInProgress, game.Status)
+
+	color, seated := gs.PlayerColor("join-game", "client-2")
+	assert.True(t, seated)
+	assert.Equal(t, chess.Black, color)
+
+	assert.Error(t, gs.JoinGame("join-game", "client-3", "Eve", chess.NoColor))
+}
+
+func TestGameService_CreateGame_Ownership(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "owned-game")
+
+	_, err := gs.CreateGame("owned-game", "black-client", HumanVsAI, 3)
+	assert.Error(t, err, "only the owner may replace an unfinished game")
+	_, err = gs.NewGameID("owned-game", "black-client")
+	assert.Error(t, err)
+
+	gameID, err := gs.NewGameID("owned-game", "white-client")
+	require.NoError(t, err)
+	assert.Equal(t, "owned-game", gameID, "the owner restarts in place")
+
+	require.NoError(t, gs.Resign("owned-game", "white-client"))
+	gameID, err = gs.NewGameID("owned-game", "black-client")
+	require.NoError(t, err)
+	assert.NotEqual(t, "owned-game", gameID, "a finished game is kept")
+	_, err = gs.CreateGame("owned-game", "black-client", HumanVsHuman, 0)
+	assert.NoError(t, err, "finished games may be replaced")
+
+	gameID, err = gs.NewGameID("free-game", "anyone")
+	require.NoError(t, err)
+	assert.Equal(t, "free-game", gameID)
+}
+
+func TestGameService_MakeMove_SeatAuthorisation(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "seat-game")
+
+	_, err := gs.MakeMove("seat-game", "black-client", "e2e4")
+	assert.Error(t, err, "black cannot move for white")
+
+	_, err = gs.MakeMove("seat-game", "spectator", "e2e4")
+	assert.Error(t, err, "unseated clients cannot move")
+
+	result, err := gs.MakeMove("seat-game", "white-client", "e2e4")
+	require.NoError(t, err)
+	assert.Equal(t, chess.Black, result.Turn)
+
+	_, err = gs.MakeMove("seat-game", "black-client", "e7e5")
+	assert.NoError(t, err)
+}
+
+func TestGameService_GetGameState_HidesPlayerIDs(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "private-game")
+
+	state, err := gs.GetGameState("private-game")
+	require.NoError(t, err)
+	stateJSON, err := json.Marshal(state)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stateJSON), "white-client", "seat IDs would let anyone take the seat")
+	assert.NotContains(t, string(stateJSON), "black-client")
+	assert.Contains(t, string(stateJSON), "Alice")
+}
@@ -0,0 +1,102 @@
+package game
+
+import (
+	"log"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+// SweepConfig sets when the sweeper gives up on idle games and forgets
+// finished ones.
+type SweepConfig struct {
+	// IdleAfter is how long a game may go without a move before it is
+	// abandoned
+	IdleAfter time.Duration
+	// EvictAfter is how long a finished game stays in memory; the games
+	// table keeps it after that
+	EvictAfter time.Duration
+	// Interval is how often the sweeper runs
+	Interval time.Duration
+}
+
+var DefaultSweepConfig = SweepConfig{
+	IdleAfter:  30 * time.Minute,
+	EvictAfter: 10 * time.Minute,
+	Interval:   time.Minute,
+}
+
+// Sweep abandons the games nobody has moved in for cfg.IdleAfter and
+// evicts the games finished more than cfg.EvictAfter ago, closing their
+// engines. Timed games in progress are left to their clocks, correspondence
+// games to their move deadlines. Adjourned games are never abandoned; with
+// a database to resume them from they are evicted like finished ones. It
+// returns the IDs of the games abandoned and evicted.
+func (gs *GameService) Sweep(now time.Time, cfg SweepConfig) (abandoned, evicted []string) {
+	gs.mutex.RLock()
+	games := make([]*GameState, 0, len(gs.games))
+	for _, game := range gs.games {
+		games = append(games, game)
+	}
+	gs.mutex.RUnlock()
+
+	for _, game := range games {
+		game.mutex.Lock()
+		switch {
+		case game.Status == StatusCompleted || game.Status == StatusAbandoned:
+			if now.Sub(game.CompletedAt) >= cfg.EvictAfter {
+				evicted = append(evicted, game.ID)
+			}
+		case game.Status == StatusAdjourned:
+			if gs.db != nil && now.Sub(game.AdjournedAt) >= cfg.EvictAfter {
+				evicted = append(evicted, game.ID)
+			}
+		case game.Status == StatusInProgress && game.Clock != nil && game.Clock.Running():
+		case game.DaysPerMove > 0:
+			// Correspondence games are left to their move deadlines
+		case now.Sub(game.lastActivity()) >= cfg.IdleAfter:
+			gs.abandon(game, game.idleSide())
+			abandoned = append(abandoned, game.ID)
+		}
+		game.mutex.Unlock()
+	}
+
+	for _, gameID := range abandoned {
+		gs.notifyStateChange(gameID)
+	}
+	for _, gameID := range evicted {
+		gs.DeleteGame(gameID)
+	}
+	if len(abandoned) > 0 || len(evicted) > 0 {
+		log.Printf("Sweep: %d games abandoned, %d evicted", len(abandoned), len(evicted))
+	}
+	return abandoned, evicted
+}
+
+// lastActivity is when the game last moved or was resumed, or was created
+// if neither has happened. The caller must hold game.mutex.
+func (game *GameState) lastActivity() time.Time {
+	latest := game.CreatedAt
+	for _, t := range []time.Time{game.LastMoveAt, game.resumedAt} {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// idleSide is the side blamed for an idle game. In human games it is the
+// player who should have moved, who forfeits; in games against the AI it is
+// the human, who left and wins nothing. The caller must hold game.mutex.
+func (game *GameState) idleSide() chess.Color {
+	turn := game.ChessGame.Position().Turn()
+	if game.Type == HumanVsHuman {
+		return turn
+	}
+	for color, player := range game.Players {
+		if !player.IsAI {
+			return color
+		}
+	}
+	return turn
+}
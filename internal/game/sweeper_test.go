@@ -0,0 +1,58 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameService_Sweep(t *testing.T) {
+	gs := NewGameService(nil)
+	cfg := SweepConfig{IdleAfter: 30 * time.Minute, EvictAfter: 10 * time.Minute}
+
+	createHumanGame(t, gs, "idle-game")
+	_, err := gs.MakeMove("idle-game", "white-client", "e2e4")
+	require.NoError(t, err)
+
+	_, err = gs.CreateGame("timed-game", "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, gs.SetTimeControl("timed-game", TimeControl{BaseMs: int64(time.Hour / time.Millisecond)}))
+	require.NoError(t, gs.JoinGame("timed-game", "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame("timed-game", "black-client", "Bob", chess.Black))
+
+	_, err = gs.CreateGame("ai-game", "student", HumanVsAI, 1)
+	require.NoError(t, err)
+	require.NoError(t, gs.JoinGame("ai-game", "student", "Student", chess.Black))
+
+	_, err = gs.CreateGame("empty-seek", "nobody", HumanVsHuman, 0)
+	require.NoError(t, err)
+
+	// Nothing is idle yet
+	abandoned, evicted := gs.Sweep(time.Now().Add(time.Minute), cfg)
+	assert.Empty(t, abandoned)
+	assert.Empty(t, evicted)
+
+	later := time.Now().Add(time.Hour)
+	abandoned, evicted = gs.Sweep(later, cfg)
+	assert.ElementsMatch(t, []string{"idle-game", "ai-game", "empty-seek"}, abandoned, "timed games are left to the clock")
+	assert.Empty(t, evicted)
+
+	result, over := gs.GameResult("idle-game")
+	require.True(t, over)
+	assert.Equal(t, "white", result.Winner, "black never answered")
+	assert.Equal(t, OutcomeAbandoned, result.Outcome)
+
+	result, _ = gs.GameResult("ai-game")
+	assert.Empty(t, result.Winner, "nobody wins against a player who left")
+
+	// Finished games are evicted once they have been over a while
+	_, evicted = gs.Sweep(time.Now().Add(11*time.Minute), cfg)
+	assert.ElementsMatch(t, []string{"idle-game", "ai-game", "empty-seek"}, evicted)
+	_, exists := gs.GetGame("idle-game")
+	assert.False(t, exists)
+	_, exists = gs.GetGame("timed-game")
+	assert.True(t, exists)
+}
@@ -0,0 +1,152 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+// SetAutoAcceptTakebacks makes the AI grant every takeback in a game.
+func (gs *GameService) SetAutoAcceptTakebacks(gameID string, autoAccept bool) error {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+
+	game.AutoAcceptTakebacks = autoAccept
+	gs.persistOptions(game)
+	return nil
+}
+
+// RequestTakeback asks to undo playerID's last move. Against the AI the
+// request is answered straight away and accepted reports the answer;
+// against a human it waits for RespondTakeback.
+func (gs *GameService) RequestTakeback(gameID, playerID string) (answered, accepted bool, err error) {
+	game, err := gs.lockInProgress(gameID)
+	if err != nil {
+		return false, false, err
+	}
+	defer game.mutex.Unlock()
+
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		return false, false, err
+	}
+	if game.TakebackRequestedBy != chess.NoColor {
+		return false, false, fmt.Errorf("a takeback request is already pending")
+	}
+	if _, err := game.takebackPlies(color); err != nil {
+		return false, false, err
+	}
+
+	if opponent, exists := game.Players[color.Other()]; exists && opponent.IsAI {
+		if !game.AutoAcceptTakebacks {
+			return true, false, nil
+		}
+		if err := gs.takeBack(game, color); err != nil {
+			return false, false, err
+		}
+		return true, true, nil
+	}
+
+	game.TakebackRequestedBy = color
+	return false, false, nil
+}
+
+// RespondTakeback answers the opponent's pending takeback request.
+func (gs *GameService) RespondTakeback(gameID, playerID string, accept bool) error {
+	game, err := gs.lockInProgress(gameID)
+	if err != nil {
+		return err
+	}
+	defer game.mutex.Unlock()
+
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		return err
+	}
+	requester := game.TakebackRequestedBy
+	if requester != color.Other() {
+		return fmt.Errorf("no takeback request from your opponent is pending")
+	}
+
+	game.TakebackRequestedBy = chess.NoColor
+	if !accept {
+		return nil
+	}
+	return gs.takeBack(game, requester)
+}
+
+// takebackPlies returns how many plies must be undone to take back the
+// requester's last move: one if the opponent hasn't replied yet, two if
+// they have. The moves the game was set up with can't be taken back. The
+// caller must hold game.mutex.
+func (game *GameState) takebackPlies(requester chess.Color) (int, error) {
+	plies := 2
+	if game.ChessGame.Position().Turn() != requester {
+		plies = 1
+	}
+	if len(game.MoveHistory)-plies < game.setupPlies {
+		return 0, fmt.Errorf("no move to take back")
+	}
+	return plies, nil
+}
+
+// restoreClock gives back the time used on the plies from ply on, setting
+// the clock as it was before ply was played. Without a snapshot of it, e.g.
+// after a restart, the side to move just starts its turn afresh. The
+// caller must hold game.mutex.
+func (game *GameState) restoreClock(ply int, now time.Time) {
+	history := game.clockHistory
+	for i := len(history) - 1; i >= 0 && history[i].ply >= ply; i-- {
+		if history[i].ply == ply {
+			game.Clock.restore(history[i], now)
+			game.clockHistory = history[:i]
+			return
+		}
+		game.clockHistory = history[:i]
+	}
+	if game.Clock.Running() {
+		game.Clock.Start(now)
+	}
+}
+
+// takeBack undoes the requester's last move by rebuilding the chess game
+// from the truncated move history. The caller must hold game.mutex.
+func (gs *GameService) takeBack(game *GameState, requester chess.Color) error {
+	plies, err := game.takebackPlies(requester)
+	if err != nil {
+		return err
+	}
+
+	replay, _, err := replayGame(game.Variant, game.StartFEN, game.MoveHistory[:len(game.MoveHistory)-plies])
+	if err != nil {
+		return fmt.Errorf("failed to rebuild game: %v", err)
+	}
+
+	game.ChessGame = replay.ChessGame
+	game.castling = replay.castling
+	game.VariantState = replay.VariantState
+	game.MoveHistory = replay.MoveHistory
+	game.CurrentTurn = replay.ChessGame.Position().Turn()
+	game.DrawOfferedBy = chess.NoColor
+	game.TakebackRequestedBy = chess.NoColor
+	game.Premoves = nil
+	if game.Clock != nil {
+		game.restoreClock(len(game.MoveHistory), time.Now())
+		if game.Clock.Running() {
+			gs.armClockTimer(game)
+		} else {
+			game.stopClockTimer()
+		}
+	}
+	gs.persistTakeback(game)
+
+	log.Printf("Game %s: took back %d plies for %s", game.ID, plies, requester.Name())
+	return nil
+}
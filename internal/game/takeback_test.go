@@ -0,0 +1,140 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameService_Takeback(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "takeback-game")
+
+	_, _, err := gs.RequestTakeback("takeback-game", "white-client")
+	assert.Error(t, err, "nothing to take back yet")
+
+	playMoves(t, gs, "takeback-game", "e2e4", "e7e5")
+
+	// White asks after black replied, so both plies are undone
+	answered, _, err := gs.RequestTakeback("takeback-game", "white-client")
+	require.NoError(t, err)
+	assert.False(t, answered, "a human opponent has to answer")
+
+	assert.Error(t, gs.RespondTakeback("takeback-game", "white-client", true), "cannot answer your own request")
+	require.NoError(t, gs.RespondTakeback("takeback-game", "black-client", true))
+
+	state, err := gs.GetGameState("takeback-game")
+	require.NoError(t, err)
+	assert.Empty(t, state.MoveHistory)
+	assert.Equal(t, chess.White, state.Turn)
+	assert.Equal(t, chess.NewGame().FEN(), state.FEN)
+}
+
+func TestGameService_TakebackKeepsSetupMoves(t *testing.T) {
+	gs := NewGameService(nil)
+	_, err := gs.CreateGame("setup-game", "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, gs.SetStartPosition("setup-game", "", "1. e4 e5"))
+	require.NoError(t, gs.JoinGame("setup-game", "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame("setup-game", "black-client", "Bob", chess.Black))
+
+	_, _, err = gs.RequestTakeback("setup-game", "white-client")
+	assert.Error(t, err, "the PGN's moves can't be taken back")
+
+	playMoves(t, gs, "setup-game", "g1f3", "b8c6")
+	_, _, err = gs.RequestTakeback("setup-game", "white-client")
+	require.NoError(t, err)
+	require.NoError(t, gs.RespondTakeback("setup-game", "black-client", true))
+
+	state, err := gs.GetGameState("setup-game")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e2e4", "e7e5"}, state.MoveHistory)
+	_, _, err = gs.RequestTakeback("setup-game", "white-client")
+	assert.Error(t, err)
+}
+
+func TestGameService_TakebackDeclined(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "declined-game")
+	playMoves(t, gs, "declined-game", "e2e4")
+
+	_, _, err := gs.RequestTakeback("declined-game", "white-client")
+	require.NoError(t, err)
+	require.NoError(t, gs.RespondTakeback("declined-game", "black-client", false))
+
+	state, err := gs.GetGameState("declined-game")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e2e4"}, state.MoveHistory)
+	assert.Equal(t, chess.NoColor, state.TakebackRequestedBy)
+}
+
+func TestGameService_TakebackAgainstAI(t *testing.T) {
+	gs := NewGameService(nil)
+	_, err := gs.CreateGame("ai-takeback", "student", HumanVsAI, 1)
+	require.NoError(t, err)
+	require.NoError(t, gs.JoinGame("ai-takeback", "student", "Student", chess.White))
+	_, err = gs.MakeMove("ai-takeback", "student", "e2e4")
+	require.NoError(t, err)
+
+	answered, accepted, err := gs.RequestTakeback("ai-takeback", "student")
+	require.NoError(t, err)
+	assert.True(t, answered)
+	assert.False(t, accepted, "the AI declines unless configured otherwise")
+
+	require.NoError(t, gs.SetAutoAcceptTakebacks("ai-takeback", true))
+	answered, accepted, err = gs.RequestTakeback("ai-takeback", "student")
+	require.NoError(t, err)
+	assert.True(t, answered)
+	assert.True(t, accepted)
+
+	state, err := gs.GetGameState("ai-takeback")
+	require.NoError(t, err)
+	assert.Empty(t, state.MoveHistory)
+}
+
+func TestGameService_TakebackRestoresClock(t *testing.T) {
+	gs := NewGameService(nil)
+	_, err := gs.CreateGame("timed-takeback", "white-client", HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, gs.SetTimeControl("timed-takeback", TimeControl{BaseMs: 60000, IncrementMs: 2000}))
+	require.NoError(t, gs.JoinGame("timed-takeback", "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame("timed-takeback", "black-client", "Bob", chess.Black))
+
+	playMoves(t, gs, "timed-takeback", "e2e4")
+	game, _ := gs.GetGame("timed-takeback")
+	game.mutex.Lock()
+	game.Clock.TurnStartedAt = time.Now().Add(-10 * time.Second) // Black thinks for 10s
+	game.mutex.Unlock()
+	playMoves(t, gs, "timed-takeback", "e7e5")
+
+	state, err := gs.GetGameState("timed-takeback")
+	require.NoError(t, err)
+	assert.InDelta(t, 52000, state.Clock.BlackMs, 100, "10s used, 2s increment")
+
+	// Black takes e5 back before white replies and gets the time back
+	_, _, err = gs.RequestTakeback("timed-takeback", "black-client")
+	require.NoError(t, err)
+	require.NoError(t, gs.RespondTakeback("timed-takeback", "white-client", true))
+
+	state, err = gs.GetGameState("timed-takeback")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e2e4"}, state.MoveHistory)
+	assert.InDelta(t, 60000, state.Clock.BlackMs, 100, "back to the time black had before e5")
+	assert.InDelta(t, 62000, state.Clock.WhiteMs, 100)
+	assert.True(t, state.Clock.Running)
+
+	// White takes e4 back too and loses the increment it earned
+	_, _, err = gs.RequestTakeback("timed-takeback", "white-client")
+	require.NoError(t, err)
+	require.NoError(t, gs.RespondTakeback("timed-takeback", "black-client", true))
+
+	state, err = gs.GetGameState("timed-takeback")
+	require.NoError(t, err)
+	assert.Empty(t, state.MoveHistory)
+	assert.InDelta(t, 60000, state.Clock.WhiteMs, 100)
+	assert.Equal(t, int64(60000), state.Clock.BlackMs)
+	assert.True(t, state.Clock.Running)
+}
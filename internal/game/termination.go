@@ -0,0 +1,398 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+// methodOutcomes maps the chess library's termination methods onto the
+// outcomes stored in the games table.
+var methodOutcomes = map[chess.Method]Outcome{
+	chess.Checkmate:            OutcomeCheckmate,
+	chess.Stalemate:            OutcomeStalemate,
+	chess.ThreefoldRepetition:  OutcomeThreefoldRepetition,
+	chess.FivefoldRepetition:   OutcomeFivefoldRepetition,
+	chess.FiftyMoveRule:        OutcomeFiftyMoveRule,
+	chess.SeventyFiveMoveRule:  OutcomeSeventyFiveMoveRule,
+	chess.InsufficientMaterial: OutcomeInsufficientMaterial,
+	chess.Resignation:          OutcomeResignation,
+	chess.DrawOffer:            OutcomeDrawAgreement,
+}
+
+// claimMethods are the draws a player has to claim; every other draw by
+// rule is applied automatically after the move that causes it.
+var claimMethods = map[Outcome]chess.Method{
+	OutcomeThreefoldRepetition: chess.ThreefoldRepetition,
+	OutcomeFiftyMoveRule:       chess.FiftyMoveRule,
+}
+
+// GameResult is the server's verdict on a finished game, sent as game_over.
+type GameResult struct {
+	GameID      string    `json:"gameId"`
+	Winner      string    `json:"winner"`
+	Outcome     Outcome   `json:"outcome"`
+	Result      string    `json:"result"` // PGN result, e.g. "1-0"
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+func pgnResult(winner string) string {
+	switch winner {
+	case "white":
+		return string(chess.WhiteWon)
+	case "black":
+		return string(chess.BlackWon)
+	case WinnerDraw:
+		return string(chess.Draw)
+	}
+	return string(chess.NoOutcome)
+}
+
+func winnerOf(outcome chess.Outcome) string {
+	switch outcome {
+	case chess.WhiteWon:
+		return "white"
+	case chess.BlackWon:
+		return "black"
+	}
+	return WinnerDraw
+}
+
+// checkTermination ends the game if the last move finished it by rule:
+// checkmate, stalemate or one of the automatic draws. The caller must hold
+// game.mutex.
+func (gs *GameService) checkTermination(game *GameState) bool {
+	if variantRules, ok := ruleVariants[game.Variant]; ok {
+		if winner, decided := variantRules.result(game.VariantState, game.ChessGame.Position()); decided {
+			gs.endGame(game, winner, variantRules.outcome())
+			return true
+		}
+		if !game.hasVariantMove() {
+			gs.endGame(game, WinnerDraw, OutcomeStalemate)
+			return true
+		}
+	}
+
+	outcome := game.ChessGame.Outcome()
+	if outcome == chess.NoOutcome || outcome == chess.UnknownOutcome {
+		return false
+	}
+	// Bare kings can still win a variant, e.g. by reaching the hill
+	if _, ok := ruleVariants[game.Variant]; ok && game.ChessGame.Method() == chess.InsufficientMaterial {
+		if draw, drawn := game.variantDraw(); drawn {
+			gs.endGame(game, WinnerDraw, draw)
+			return true
+		}
+		return false
+	}
+
+	method, ok := methodOutcomes[game.ChessGame.Method()]
+	if !ok {
+		log.Printf("Game %s ended by unknown method %v", game.ID, game.ChessGame.Method())
+	}
+	gs.endGame(game, winnerOf(outcome), method)
+	return true
+}
+
+// variantDraw judges the automatic draws of a rule variant game the chess
+// library has called drawn on insufficient material. That doesn't decide a
+// variant game, but the library stops judging the position once it has an
+// outcome, so stalemate and the 75-move and fivefold repetition draws are
+// judged here. The caller must hold game.mutex.
+func (game *GameState) variantDraw() (Outcome, bool) {
+	pos := game.ChessGame.Position()
+	switch {
+	case pos.Status() == chess.Stalemate:
+		return OutcomeStalemate, true
+	case game.repetitions(pos) >= 5:
+		return OutcomeFivefoldRepetition, true
+	case pos.HalfMoveClock() >= 150:
+		return OutcomeSeventyFiveMoveRule, true
+	}
+	return "", false
+}
+
+// repetitions counts how often pos has occurred in the game: the same
+// pieces, side to move, castling rights and en passant square. The caller
+// must hold game.mutex.
+func (game *GameState) repetitions(pos *chess.Position) int {
+	key := func(p *chess.Position) string {
+		return strings.Join(strings.Fields(p.String())[:4], " ")
+	}
+	current, count := key(pos), 0
+	for _, p := range game.ChessGame.Positions() {
+		if p != nil && key(p) == current {
+			count++
+		}
+	}
+	return count
+}
+
+// claimableDraws lists the draws the side to move may claim. The caller
+// must hold game.mutex.
+func (game *GameState) claimableDraws() []Outcome {
+	if game.Status != StatusInProgress {
+		return nil
+	}
+	var draws []Outcome
+	for _, method := range game.ChessGame.EligibleDraws() {
+		if outcome := methodOutcomes[method]; claimMethods[outcome] != chess.NoMethod {
+			draws = append(draws, outcome)
+		}
+	}
+	return draws
+}
+
+// ClaimDraw ends the game on a threefold repetition or fifty-move claim.
+// Only the side to move may claim.
+func (gs *GameService) ClaimDraw(gameID, playerID string, outcome Outcome) error {
+	game, err := gs.lockInProgress(gameID)
+	if err != nil {
+		return err
+	}
+	defer game.mutex.Unlock()
+
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		return err
+	}
+	if color != game.ChessGame.Position().Turn() {
+		return fmt.Errorf("only the side to move can claim a draw")
+	}
+
+	method, ok := claimMethods[outcome]
+	if !ok {
+		return fmt.Errorf("%q is not a claimable draw", outcome)
+	}
+	if err := game.ChessGame.Draw(method); err != nil {
+		return fmt.Errorf("draw claim rejected: %v", err)
+	}
+
+	gs.endGame(game, WinnerDraw, outcome)
+	return nil
+}
+
+// GameResult returns the result of a finished game.
+func (gs *GameService) GameResult(gameID string) (*GameResult, bool) {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return nil, false
+	}
+
+	game.mutex.RLock()
+	defer game.mutex.RUnlock()
+
+	if game.Status != StatusCompleted && game.Status != StatusAbandoned {
+		return nil, false
+	}
+	return game.result(), true
+}
+
+// result returns the verdict on a finished game. The caller must hold
+// game.mutex.
+func (game *GameState) result() *GameResult {
+	return &GameResult{
+		GameID:      game.ID,
+		Winner:      game.Winner,
+		Outcome:     game.Outcome,
+		Result:      pgnResult(game.Winner),
+		CompletedAt: game.CompletedAt,
+	}
+}
+
+// endGame completes the game and records the result. The caller must hold
+// game.mutex.
+func (gs *GameService) endGame(game *GameState, winner string, outcome Outcome) {
+	game.finish(winner, outcome)
+	game.DrawOfferedBy = chess.NoColor
+	gs.persistGame(game)
+	gs.notifyGameOver(game)
+	log.Printf("Game %s ended: winner=%s outcome=%s", game.ID, winner, outcome)
+}
+
+// AddGameOverHandler registers a callback for the result of every game
+// that ends, however it ends, e.g. to score a tournament.
+func (gs *GameService) AddGameOverHandler(handler func(GameResult)) {
+	gs.gameOverMutex.Lock()
+	defer gs.gameOverMutex.Unlock()
+	gs.gameOverHandlers = append(gs.gameOverHandlers, handler)
+}
+
+// notifyGameOver hands the game's result to the game over handlers. They
+// run on their own goroutines, so they are free to call back into the
+// service. The caller must hold game.mutex.
+func (gs *GameService) notifyGameOver(game *GameState) {
+	gs.gameOverMutex.RLock()
+	handlers := gs.gameOverHandlers
+	gs.gameOverMutex.RUnlock()
+
+	result := game.result()
+	for _, handler := range handlers {
+		go handler(*result)
+	}
+}
+
+// AbandonGame ends a game whose player left and never came back. In human
+// games the opponent who stayed is awarded the win.
+func (gs *GameService) AbandonGame(gameID, playerID string) error {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+
+	if game.Status != StatusInProgress && game.Status != StatusWaiting {
+		return fmt.Errorf("game %s is already over", gameID)
+	}
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		return err
+	}
+
+	gs.abandon(game, color)
+	return nil
+}
+
+// abandon ends the game as abandoned by leaver. The caller must hold
+// game.mutex.
+func (gs *GameService) abandon(game *GameState, leaver chess.Color) {
+	winner := ""
+	if opponent, seated := game.Players[leaver.Other()]; seated && !opponent.IsAI && game.Status == StatusInProgress {
+		winner = colorName(leaver.Other())
+	}
+	game.finish(winner, OutcomeAbandoned)
+	game.Status = StatusAbandoned
+	game.DrawOfferedBy = chess.NoColor
+	gs.persistGame(game)
+	gs.notifyGameOver(game)
+	log.Printf("Game %s abandoned by %s", game.ID, leaver.Name())
+}
+
+// seatOf returns the color playerID is seated at. The caller must hold
+// game.mutex.
+func (game *GameState) seatOf(playerID string) (chess.Color, error) {
+	for color, player := range game.Players {
+		if player.ID == playerID && !player.IsAI {
+			return color, nil
+		}
+	}
+	return chess.NoColor, fmt.Errorf("player %s is not seated in game %s", playerID, game.ID)
+}
+
+// lockInProgress looks up a game that is still being played and locks it.
+func (gs *GameService) lockInProgress(gameID string) (*GameState, error) {
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return nil, fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.Lock()
+	if game.Status != StatusInProgress {
+		game.mutex.Unlock()
+		return nil, fmt.Errorf("game %s is not in progress (status: %s)", gameID, game.Status)
+	}
+	return game, nil
+}
+
+// Resign ends the game as a loss for playerID.
+func (gs *GameService) Resign(gameID, playerID string) error {
+	game, err := gs.lockInProgress(gameID)
+	if err != nil {
+		return err
+	}
+	defer game.mutex.Unlock()
+
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		return err
+	}
+
+	game.ChessGame.Resign(color)
+	gs.endGame(game, colorName(color.Other()), OutcomeResignation)
+	return nil
+}
+
+// OfferDraw records a draw offer from playerID. The offer stands until the
+// opponent answers it or the offering side moves again. Offers to an AI
+// opponent are declined straight away and reported through declinedByAI.
+func (gs *GameService) OfferDraw(gameID, playerID string) (declinedByAI bool, err error) {
+	game, err := gs.lockInProgress(gameID)
+	if err != nil {
+		return false, err
+	}
+	defer game.mutex.Unlock()
+
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		return false, err
+	}
+	if game.DrawOfferedBy != chess.NoColor {
+		return false, fmt.Errorf("a draw offer is already pending")
+	}
+
+	if opponent, exists := game.Players[color.Other()]; exists && opponent.IsAI {
+		return true, nil
+	}
+
+	game.DrawOfferedBy = color
+	game.drawOfferPly = len(game.MoveHistory)
+	return false, nil
+}
+
+// AcceptDraw accepts the opponent's pending draw offer.
+func (gs *GameService) AcceptDraw(gameID, playerID string) error {
+	game, err := gs.lockInProgress(gameID)
+	if err != nil {
+		return err
+	}
+	defer game.mutex.Unlock()
+
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		return err
+	}
+	if game.DrawOfferedBy != color.Other() {
+		return fmt.Errorf("no draw offer from your opponent is pending")
+	}
+
+	if err := game.ChessGame.Draw(chess.DrawOffer); err != nil {
+		return fmt.Errorf("failed to draw game: %v", err)
+	}
+	gs.endGame(game, WinnerDraw, OutcomeDrawAgreement)
+	return nil
+}
+
+// DeclineDraw declines the opponent's pending draw offer.
+func (gs *GameService) DeclineDraw(gameID, playerID string) error {
+	game, err := gs.lockInProgress(gameID)
+	if err != nil {
+		return err
+	}
+	defer game.mutex.Unlock()
+
+	color, err := game.seatOf(playerID)
+	if err != nil {
+		return err
+	}
+	if game.DrawOfferedBy != color.Other() {
+		return fmt.Errorf("no draw offer from your opponent is pending")
+	}
+
+	game.DrawOfferedBy = chess.NoColor
+	return nil
+}
+
+// expireDrawOffer drops a pending offer once the offering side moves again.
+// An offer made on your own turn goes with the move you are about to play,
+// so only moves after that one expire it. The caller must hold game.mutex.
+func (game *GameState) expireDrawOffer(mover chess.Color) {
+	if game.DrawOfferedBy == mover && len(game.MoveHistory) > game.drawOfferPly {
+		log.Printf("Game %s: draw offer by %s expired", game.ID, mover.Name())
+		game.DrawOfferedBy = chess.NoColor
+	}
+}
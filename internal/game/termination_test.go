@@ -0,0 +1,164 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameService_Resign(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "resign-game")
+
+	assert.Error(t, gs.Resign("resign-game", "spectator"))
+	require.NoError(t, gs.Resign("resign-game", "black-client"))
+
+	state, err := gs.GetGameState("resign-game")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, state.Status)
+	assert.Equal(t, "white", state.Winner)
+	assert.Equal(t, OutcomeResignation, state.Outcome)
+
+	assert.Error(t, gs.Resign("resign-game", "white-client"), "game is already over")
+}
+
+func TestGameService_DrawOffer(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "draw-game")
+
+	_, err := gs.OfferDraw("draw-game", "white-client")
+	require.NoError(t, err)
+	assert.Error(t, gs.AcceptDraw("draw-game", "white-client"), "cannot accept your own offer")
+
+	require.NoError(t, gs.DeclineDraw("draw-game", "black-client"))
+	assert.Error(t, gs.AcceptDraw("draw-game", "black-client"), "offer was declined")
+
+	_, err = gs.OfferDraw("draw-game", "black-client")
+	require.NoError(t, err)
+	require.NoError(t, gs.AcceptDraw("draw-game", "white-client"))
+
+	state, err := gs.GetGameState("draw-game")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, state.Status)
+	assert.Equal(t, WinnerDraw, state.Winner)
+	assert.Equal(t, OutcomeDrawAgreement, state.Outcome)
+}
+
+func TestGameService_DrawOfferExpires(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "expire-game")
+
+	// An offer made on your own turn survives the move that goes with it
+	_, err := gs.OfferDraw("expire-game", "white-client")
+	require.NoError(t, err)
+	_, err = gs.MakeMove("expire-game", "white-client", "e2e4")
+	require.NoError(t, err)
+
+	game, _ := gs.GetGame("expire-game")
+	assert.Equal(t, chess.White, game.DrawOfferedBy)
+
+	// ...but not the offering side's next move
+	_, err = gs.MakeMove("expire-game", "black-client", "e7e5")
+	require.NoError(t, err)
+	_, err = gs.MakeMove("expire-game", "white-client", "g1f3")
+	require.NoError(t, err)
+
+	assert.Equal(t, chess.NoColor, game.DrawOfferedBy)
+	assert.Error(t, gs.AcceptDraw("expire-game", "black-client"))
+}
+
+// playMoves alternates moves between the two seats created by createHumanGame
+func playMoves(t *testing.T, gs *GameService, gameID string, moves ...string) {
+	t.Helper()
+	players := []string{"white-client", "black-client"}
+	game, _ := gs.GetGame(gameID)
+	for _, move := range moves {
+		mover := players[0]
+		if game.ChessGame.Position().Turn() == chess.Black {
+			mover = players[1]
+		}
+		_, err := gs.MakeMove(gameID, mover, move)
+		require.NoError(t, err, "move %s", move)
+	}
+}
+
+func TestGameService_CheckmateEndsGame(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "mate-game")
+
+	playMoves(t, gs, "mate-game", "f2f3", "e7e5", "g2g4", "d8h4")
+
+	result, over := gs.GameResult("mate-game")
+	require.True(t, over)
+	assert.Equal(t, "black", result.Winner)
+	assert.Equal(t, OutcomeCheckmate, result.Outcome)
+	assert.Equal(t, "0-1", result.Result)
+}
+
+func TestGameService_ClaimThreefold(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "repeat-game")
+
+	assert.Error(t, gs.ClaimDraw("repeat-game", "white-client", OutcomeThreefoldRepetition))
+
+	playMoves(t, gs, "repeat-game",
+		"g1f3", "g8f6", "f3g1", "f6g8",
+		"g1f3", "g8f6", "f3g1", "f6g8")
+
+	state, err := gs.GetGameState("repeat-game")
+	require.NoError(t, err)
+	assert.Equal(t, StatusInProgress, state.Status, "threefold is not automatic")
+	assert.Contains(t, state.ClaimableDraws, OutcomeThreefoldRepetition)
+
+	assert.Error(t, gs.ClaimDraw("repeat-game", "black-client", OutcomeThreefoldRepetition), "not on move")
+	require.NoError(t, gs.ClaimDraw("repeat-game", "white-client", OutcomeThreefoldRepetition))
+
+	result, over := gs.GameResult("repeat-game")
+	require.True(t, over)
+	assert.Equal(t, WinnerDraw, result.Winner)
+	assert.Equal(t, OutcomeThreefoldRepetition, result.Outcome)
+}
+
+func TestGameService_AbandonGame(t *testing.T) {
+	gs := NewGameService(nil)
+	createHumanGame(t, gs, "abandon-game")
+
+	assert.Error(t, gs.AbandonGame("abandon-game", "spectator"))
+	require.NoError(t, gs.AbandonGame("abandon-game", "white-client"))
+
+	result, over := gs.GameResult("abandon-game")
+	require.True(t, over)
+	assert.Equal(t, "black", result.Winner, "the player who stayed wins")
+	assert.Equal(t, OutcomeAbandoned, result.Outcome)
+	assert.Equal(t, "0-1", result.Result)
+
+	assert.Error(t, gs.AbandonGame("abandon-game", "black-client"), "game is already over")
+}
+
+func TestGameService_GameOverHandlers(t *testing.T) {
+	gs := NewGameService(nil)
+	results := make(chan GameResult, 2)
+	gs.AddGameOverHandler(func(result GameResult) { results <- result })
+	createHumanGame(t, gs, "resigned")
+	createHumanGame(t, gs, "left")
+
+	require.NoError(t, gs.Resign("resigned", "black-client"))
+	require.NoError(t, gs.AbandonGame("left", "white-client"))
+
+	got := map[string]GameResult{}
+	for range 2 {
+		select {
+		case result := <-results:
+			got[result.GameID] = result
+		case <-time.After(time.Second):
+			t.Fatal("game over handler was not called")
+		}
+	}
+	assert.Equal(t, "white", got["resigned"].Winner)
+	assert.Equal(t, OutcomeResignation, got["resigned"].Outcome)
+	assert.Equal(t, "black", got["left"].Winner)
+	assert.Equal(t, OutcomeAbandoned, got["left"].Outcome)
+}
@@ -0,0 +1,280 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+	"github.com/hunterMotko/chess-game/internal/engine"
+)
+
+// Variant is the set of rules a game is played under.
+type Variant string
+
+const (
+	VariantStandard      Variant = "standard"
+	VariantChess960      Variant = "chess960"
+	VariantThreeCheck    Variant = "three_check"
+	VariantKingOfTheHill Variant = "king_of_the_hill"
+	VariantRacingKings   Variant = "racing_kings"
+	// VariantFogOfWar is standard chess where each player only sees the
+	// squares their own pieces stand on or can move to. Moves follow the
+	// usual rules, check included, as the chess library enforces them.
+	VariantFogOfWar Variant = "fog_of_war"
+)
+
+// pgnVariantNames are the values of the PGN Variant tag.
+var pgnVariantNames = map[Variant]string{
+	VariantChess960:      "Chess960",
+	VariantThreeCheck:    "Three-check",
+	VariantKingOfTheHill: "King of the Hill",
+	VariantRacingKings:   "Racing Kings",
+	VariantFogOfWar:      "Fog of War",
+}
+
+// ParseVariant maps a client's variant name onto a Variant. An empty name
+// means standard chess.
+func ParseVariant(name string) (Variant, error) {
+	variant := Variant(strings.ToLower(name))
+	if variant == "" || variant == VariantStandard {
+		return VariantStandard, nil
+	}
+	if _, known := pgnVariantNames[variant]; known {
+		return variant, nil
+	}
+	return "", fmt.Errorf("unknown variant %q", name)
+}
+
+// SetVariant sets up a game that hasn't started yet to be played under
+// variant. Chess960 games start from chess960Position, 0-959, or from a
+// random position when it is nil.
+func (gs *GameService) SetVariant(gameID string, variant Variant, chess960Position *int) error {
+	startFEN := ""
+	switch variant {
+	case VariantStandard, VariantFogOfWar:
+	case VariantChess960:
+		n := rand.Intn(960)
+		if chess960Position != nil {
+			n = *chess960Position
+		}
+		fen, err := Chess960StartPosition(n)
+		if err != nil {
+			return err
+		}
+		startFEN = fen
+	default:
+		variantRules, ok := ruleVariants[variant]
+		if !ok {
+			return fmt.Errorf("unknown variant %q", variant)
+		}
+		startFEN = variantRules.startFEN()
+	}
+
+	game, exists := gs.GetGame(gameID)
+	if !exists {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+
+	if game.Status != StatusWaiting {
+		return fmt.Errorf("cannot change the variant once game %s has started", gameID)
+	}
+
+	replay, _, err := replayGame(variant, startFEN, nil)
+	if err != nil {
+		return err
+	}
+	game.Variant = variant
+	game.StartFEN = startFEN
+	game.Opening = nil
+	game.ChessGame = replay.ChessGame
+	game.castling = replay.castling
+	game.VariantState = replay.VariantState
+	game.MoveHistory = []string{}
+	game.setupPlies = 0
+	game.CurrentTurn = game.ChessGame.Position().Turn()
+	game.configureEngines()
+
+	gs.persistStartPosition(game)
+	log.Printf("Game %s set up as %s from %q", gameID, variant, game.fen())
+	return nil
+}
+
+// fen returns the game's position. Chess960 games use Shredder-FEN, whose
+// castling field names the files of the castling rooks. The caller must
+// hold game.mutex.
+func (game *GameState) fen() string {
+	if game.Variant == VariantChess960 {
+		return withCastling(game.ChessGame.FEN(), game.castling)
+	}
+	return game.ChessGame.FEN()
+}
+
+// playMove plays moveStr, in UCI, SAN or LAN, for the side to move under
+// the game's variant. It returns the move as UCI and SAN, or a *MoveError.
+// The caller must hold game.mutex.
+func (game *GameState) playMove(moveStr string) (string, string, error) {
+	if game.Variant == VariantChess960 {
+		if c, ok := game.findCastle(strings.ReplaceAll(strings.TrimSpace(moveStr), "0", "O")); ok {
+			return game.playCastle(c)
+		}
+	}
+
+	pos := game.ChessGame.Position()
+	move, err := decodeMove(pos, game.allowedMoves(), moveStr)
+	if err != nil {
+		return "", "", err
+	}
+	variantRules, hasRules := ruleVariants[game.Variant]
+	san := chess.AlgebraicNotation{}.Encode(pos, move)
+	if err := game.ChessGame.Move(move, nil); err != nil {
+		return "", "", fmt.Errorf("failed to apply move: %v", err)
+	}
+	if game.Variant == VariantChess960 {
+		game.castling = updateCastling(game.castling, pos, move)
+	}
+	if hasRules {
+		variantRules.update(game.VariantState, pos, game.ChessGame.Position())
+	}
+	return move.String(), san, nil
+}
+
+// allowedMoves returns the legal moves the variant allows. The caller must
+// hold game.mutex.
+func (game *GameState) allowedMoves() []chess.Move {
+	validMoves := game.ChessGame.ValidMoves()
+	variantRules, ok := ruleVariants[game.Variant]
+	if !ok {
+		return validMoves
+	}
+	pos := game.ChessGame.Position()
+	allowed := make([]chess.Move, 0, len(validMoves))
+	for i := range validMoves {
+		if variantRules.allows(pos, pos.Update(&validMoves[i])) {
+			allowed = append(allowed, validMoves[i])
+		}
+	}
+	return allowed
+}
+
+// hasVariantMove reports whether the side to move has a move the variant
+// allows. Positions without any legal move are left to the chess library's
+// mate and stalemate detection. The caller must hold game.mutex.
+func (game *GameState) hasVariantMove() bool {
+	variantRules, ok := ruleVariants[game.Variant]
+	if !ok {
+		return true
+	}
+	pos := game.ChessGame.Position()
+	validMoves := pos.ValidMoves()
+	for i := range validMoves {
+		if variantRules.allows(pos, pos.Update(&validMoves[i])) {
+			return true
+		}
+	}
+	return len(validMoves) == 0 // Mate and stalemate are the library's call
+}
+
+// playedMove is one ply of a replayed game.
+type playedMove struct {
+	before   *chess.Position
+	uci, san string
+	fen      string // Position after the move
+}
+
+// replayGame plays a move history from startFEN, or from the standard
+// starting position when it is empty, under variant's rules. It returns a
+// game holding the final position, and every ply played.
+func replayGame(variant Variant, startFEN string, history []string) (*GameState, []playedMove, error) {
+	replay := &GameState{Variant: variant, StartFEN: startFEN}
+	fen := startFEN
+	if variant == VariantChess960 {
+		fen, replay.castling = splitCastling(startFEN)
+	}
+	chessGame, err := newChessGame(fen)
+	if err != nil {
+		return nil, nil, err
+	}
+	replay.ChessGame = chessGame
+	if variantRules, ok := ruleVariants[variant]; ok {
+		replay.VariantState = variantRules.newState()
+	}
+
+	plies := make([]playedMove, 0, len(history))
+	for _, moveStr := range history {
+		before := replay.ChessGame.Position()
+		uci, san, err := replay.playMove(moveStr)
+		var moveErr *MoveError
+		if errors.As(err, &moveErr) && moveErr.Code == MoveAmbiguous {
+			// Older histories hold promotions without their piece, which
+			// were played as the first legal match
+			uci, san, err = replay.playMove(moveErr.Alternatives[0].UCI)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid move in history: %s", moveStr)
+		}
+		plies = append(plies, playedMove{before: before, uci: uci, san: san, fen: replay.fen()})
+	}
+	replay.MoveHistory = append([]string{}, history...)
+	return replay, plies, nil
+}
+
+// pgn exports the game as PGN. Variant games get the Variant, SetUp and FEN
+// tags. The caller must hold game.mutex.
+func (game *GameState) pgn() string {
+	name, isVariant := pgnVariantNames[game.Variant]
+	if !isVariant {
+		return game.ChessGame.String()
+	}
+
+	_, plies, err := replayGame(game.Variant, game.StartFEN, game.MoveHistory)
+	if err != nil {
+		log.Printf("Warning: Could not export game %s: %v", game.ID, err)
+		return ""
+	}
+
+	result := pgnResult(game.Winner)
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Variant \"%s\"]\n", name)
+	if game.StartFEN != "" {
+		fmt.Fprintf(&b, "[SetUp \"1\"]\n[FEN \"%s\"]\n", game.StartFEN)
+	}
+	fmt.Fprintf(&b, "[Result \"%s\"]\n\n", result)
+	for i, ply := range plies {
+		if ply.before.Turn() == chess.White {
+			fmt.Fprintf(&b, "%d. ", fullMoveNumber(ply.before))
+		} else if i == 0 {
+			fmt.Fprintf(&b, "%d... ", fullMoveNumber(ply.before))
+		}
+		b.WriteString(ply.san + " ")
+	}
+	b.WriteString(result)
+	return b.String()
+}
+
+// configureEngines sets the game's variant on each of its engines. The
+// caller must hold game.mutex.
+func (game *GameState) configureEngines() {
+	engines := []*engine.StockfishEngine{game.AIEngine}
+	for _, aiEngine := range game.AIEngines {
+		engines = append(engines, aiEngine)
+	}
+	for _, aiEngine := range engines {
+		if aiEngine == nil {
+			continue
+		}
+		if err := aiEngine.SetChess960(game.Variant == VariantChess960); err != nil {
+			log.Printf("Warning: Could not configure engine for game %s: %v", game.ID, err)
+		}
+		if variantRules, ok := ruleVariants[game.Variant]; ok {
+			if err := aiEngine.SetVariant(variantRules.uciVariant()); err != nil {
+				log.Printf("Warning: Game %s: %v, the AI plays by standard rules", game.ID, err)
+			}
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/corentings/chess/v2"
 	"github.com/hunterMotko/chess-game/internal/database"
+	"github.com/hunterMotko/chess-game/internal/game"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
@@ -16,9 +19,23 @@ func (s *Server) RegisterRoutes() http.Handler {
 	e.Use(middleware.CORS())
 
 	e.GET("/ws/:gameId", s.manager.ServeWS)
+	e.GET("/ws/tournaments/:tournamentId", s.manager.ServeTournamentWS)
 	e.GET("/check-h", s.healthHandler)
 	e.GET("/api/openings/random", s.randomOpeningHandler)
 	e.GET("/api/openings/:id", s.openingsHandler)
+	e.POST("/api/games", s.createGameHandler)
+	e.GET("/api/games", s.listGamesHandler)
+	e.POST("/api/games/:id/join", s.joinGameHandler)
+	e.POST("/api/games/:id/moves", s.correspondenceMoveHandler)
+	e.GET("/api/players/:playerId/turns", s.correspondenceTurnsHandler)
+	e.POST("/api/tournaments", s.createTournamentHandler)
+	e.GET("/api/tournaments/:id", s.tournamentHandler)
+	e.POST("/api/tournaments/:id/register", s.registerTournamentHandler)
+	e.POST("/api/tournaments/:id/withdraw", s.withdrawTournamentHandler)
+	e.POST("/api/tournaments/:id/start", s.startTournamentHandler)
+	e.GET("/api/tournaments/:id/standings", s.standingsHandler)
+	e.GET("/api/tournaments/:id/pairings", s.pairingsHandler)
+	e.GET("/api/tournaments/:id/leaderboard", s.leaderboardHandler)
 
 	e.Logger.Fatal(e.Start(s.addr))
 	return e
@@ -71,3 +88,168 @@ func (s *Server) randomOpeningHandler(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, res)
 }
+
+// createGameHandler opens a game in the lobby under a server-issued ID. The
+// creator then connects to /ws/:gameId with the playerId they sent; the
+// creator of a correspondence game also gets the token to move over HTTP.
+func (s *Server) createGameHandler(c echo.Context) error {
+	var req game.LobbyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	res, err := s.manager.OpenGame(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, res)
+}
+
+// listGamesHandler lists games by status, open seeks by default.
+func (s *Server) listGamesHandler(c echo.Context) error {
+	status := game.GameStatus(c.QueryParam("status"))
+	switch status {
+	case "":
+		status = game.StatusWaiting
+	case game.StatusWaiting, game.StatusInProgress, game.StatusCompleted, game.StatusAbandoned, game.StatusAdjourned:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "unknown status " + string(status),
+		})
+	}
+
+	limit := 0
+	if l := c.QueryParam("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": err.Error(),
+			})
+		}
+	}
+
+	res, err := s.manager.GameService().ListGames(c.Request().Context(), status, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// joinGameHandler takes a seat in a game found in the lobby.
+func (s *Server) joinGameHandler(c echo.Context) error {
+	var req struct {
+		PlayerID   string `json:"playerId"`
+		PlayerName string `json:"playerName"`
+		Color      string `json:"color"` // "white", "black" or empty for the free seat
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	gameService := s.manager.GameService()
+	id := c.Param("id")
+	if err := gameService.LoadCorrespondenceGame(c.Request().Context(), id); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": err.Error(),
+		})
+	}
+	if _, exists := gameService.GetGame(id); !exists {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "game " + id + " not found",
+		})
+	}
+
+	color := chess.NoColor
+	switch req.Color {
+	case "white":
+		color = chess.White
+	case "black":
+		color = chess.Black
+	}
+	res, err := s.manager.JoinOpenGame(id, req.PlayerID, req.PlayerName, color)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// correspondenceMoveHandler plays a move in a correspondence game, so
+// players can move without keeping a connection open. The move is made by
+// whoever the seat token sent was issued to when they took their seat.
+func (s *Server) correspondenceMoveHandler(c echo.Context) error {
+	var req struct {
+		Token string `json:"token"`
+		game.MoveInput
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+	moveStr, err := req.Notation()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	gameService := s.manager.GameService()
+	ctx := c.Request().Context()
+	id := c.Param("id")
+	if err := gameService.LoadCorrespondenceGame(ctx, id); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": err.Error(),
+		})
+	}
+	if _, exists := gameService.GetGame(id); !exists {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "game " + id + " not found",
+		})
+	}
+
+	res, err := s.manager.CorrespondenceMove(ctx, id, req.Token, moveStr)
+	if err != nil {
+		if errors.Is(err, game.ErrSeatToken) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"message": err.Error(),
+			})
+		}
+		// A rejected move comes back with the legal moves the player may
+		// have meant
+		var moveErr *game.MoveError
+		if errors.As(err, &moveErr) {
+			return c.JSON(http.StatusUnprocessableEntity, moveErr)
+		}
+		return c.JSON(http.StatusConflict, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// correspondenceTurnsHandler lists the correspondence games waiting on a
+// player's move, the most urgent first.
+func (s *Server) correspondenceTurnsHandler(c echo.Context) error {
+	res, err := s.manager.GameService().CorrespondenceTurns(c.Request().Context(), c.Param("playerId"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
@@ -1,12 +1,20 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/hunterMotko/chess-game/internal/database"
+	"github.com/hunterMotko/chess-game/internal/game"
 	"github.com/hunterMotko/chess-game/internal/websockets"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestServer_healthHandler(t *testing.T) {
@@ -105,4 +113,108 @@ func createTestServer() *Server {
 		db:      &database.Service{},
 		manager: &websockets.Manager{},
 	}
-}
\ No newline at end of file
+}
+
+func TestServer_lobbyHandlers(t *testing.T) {
+	s := &Server{manager: websockets.NewManager(context.Background(), nil)}
+	e := echo.New()
+	call := func(handler echo.HandlerFunc, method, target, body string, params ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if len(params) == 2 {
+			c.SetParamNames(params[0])
+			c.SetParamValues(params[1])
+		}
+		require.NoError(t, handler(c))
+		return rec
+	}
+
+	rec := call(s.createGameHandler, http.MethodPost, "/api/games", `{"playerId":"alice","playerName":"Alice","color":"white"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var opened game.LobbyGame
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &opened))
+	assert.Equal(t, "black", opened.OpenSeat)
+
+	rec = call(s.listGamesHandler, http.MethodGet, "/api/games?status=waiting", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var seeks []game.LobbyGame
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &seeks))
+	require.Len(t, seeks, 1)
+	assert.Equal(t, opened.ID, seeks[0].ID)
+
+	rec = call(s.joinGameHandler, http.MethodPost, "/api/games/"+opened.ID+"/join", `{"playerId":"bob","playerName":"Bob"}`, "id", opened.ID)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = call(s.joinGameHandler, http.MethodPost, "/api/games/"+opened.ID+"/join", `{"playerId":"carol"}`, "id", opened.ID)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = call(s.joinGameHandler, http.MethodPost, "/api/games/missing/join", `{"playerId":"carol"}`, "id", "missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = call(s.createGameHandler, http.MethodPost, "/api/games", `{"playerId":"dave","type":"ai_vs_ai"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = call(s.listGamesHandler, http.MethodGet, "/api/games?status=lost", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestServer_correspondenceHandlers(t *testing.T) {
+	s := &Server{manager: websockets.NewManager(context.Background(), nil)}
+	e := echo.New()
+	call := func(handler echo.HandlerFunc, method, target, body string, params ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if len(params) == 2 {
+			c.SetParamNames(params[0])
+			c.SetParamValues(params[1])
+		}
+		require.NoError(t, handler(c))
+		return rec
+	}
+
+	rec := call(s.createGameHandler, http.MethodPost, "/api/games", `{"playerId":"alice","color":"white","daysPerMove":3}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var opened game.LobbyGame
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &opened))
+	assert.Equal(t, 3, opened.DaysPerMove)
+	require.NotEmpty(t, opened.Token, "the creator is told their seat token")
+	rec = call(s.joinGameHandler, http.MethodPost, "/api/games/"+opened.ID+"/join", `{"playerId":"bob"}`, "id", opened.ID)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var joined game.LobbyGame
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &joined))
+	require.NotEmpty(t, joined.Token)
+	assert.NotEqual(t, opened.Token, joined.Token)
+
+	rec = call(s.correspondenceTurnsHandler, http.MethodGet, "/api/players/alice/turns", "", "playerId", "alice")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var turns []game.LobbyGame
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &turns))
+	require.Len(t, turns, 1)
+	assert.NotNil(t, turns[0].MoveDeadline)
+	assert.Empty(t, turns[0].Token, "tokens are never listed")
+
+	moves := "/api/games/" + opened.ID + "/moves"
+	rec = call(s.correspondenceMoveHandler, http.MethodPost, moves, `{"playerId":"alice","from":"e2","to":"e4"}`, "id", opened.ID)
+	assert.Equal(t, http.StatusForbidden, rec.Code, "a player ID is no token")
+	rec = call(s.correspondenceMoveHandler, http.MethodPost, moves, `{"token":"`+opened.Token+`","from":"e2","to":"e4"}`, "id", opened.ID)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var result game.MoveResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, "e2e4", result.Move)
+
+	rec = call(s.correspondenceMoveHandler, http.MethodPost, moves, `{"token":"`+joined.Token+`","move":"e4"}`, "id", opened.ID)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	var moveErr game.MoveError
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &moveErr))
+	assert.Equal(t, game.MoveIllegal, moveErr.Code)
+	rec = call(s.correspondenceMoveHandler, http.MethodPost, moves, `{"token":"`+opened.Token+`","move":"d4"}`, "id", opened.ID)
+	assert.Equal(t, http.StatusConflict, rec.Code, "not alice's turn")
+	rec = call(s.correspondenceMoveHandler, http.MethodPost, moves, `{"token":"`+joined.Token+`"}`, "id", opened.ID)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = call(s.correspondenceMoveHandler, http.MethodPost, "/api/games/missing/moves", `{"token":"`+joined.Token+`","move":"e5"}`, "id", "missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = call(s.createGameHandler, http.MethodPost, "/api/games", `{"playerId":"carol","daysPerMove":3,"timeControl":{"baseMs":60000}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -3,20 +3,25 @@ package server
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hunterMotko/chess-game/internal/database"
+	"github.com/hunterMotko/chess-game/internal/game"
+	"github.com/hunterMotko/chess-game/internal/tournament"
 	"github.com/hunterMotko/chess-game/internal/websockets"
 	_ "github.com/joho/godotenv/autoload"
 )
 
 type Server struct {
-	addr    string
-	db      *database.Service
-	manager *websockets.Manager
+	addr        string
+	db          *database.Service
+	manager     *websockets.Manager
+	tournaments *tournament.Service
 }
 
 func NewServer() *http.Server {
@@ -32,10 +37,16 @@ func NewServer() *http.Server {
 	ctx := context.Background()
 	db := database.New()
 	manager := websockets.NewManager(ctx, db)
+	manager.StartSweeper(ctx, sweepConfig())
+	// Comma-separated words masked in chat, e.g. for student clubs
+	if filter := websockets.NewWordFilter(strings.Split(os.Getenv("CHAT_BLOCKED_WORDS"), ",")); filter != nil {
+		manager.AddChatFilter(filter)
+	}
 	NewServer := &Server{
-		addr:    fmt.Sprintf(":%d", port),
-		db:      db,
-		manager: manager,
+		addr:        fmt.Sprintf(":%d", port),
+		db:          db,
+		manager:     manager,
+		tournaments: manager.Tournaments(),
 	}
 
 	server := &http.Server{
@@ -48,3 +59,26 @@ func NewServer() *http.Server {
 
 	return server
 }
+
+// sweepConfig reads the idle game sweeper's settings from the environment,
+// as durations such as "45m", falling back to the defaults.
+func sweepConfig() game.SweepConfig {
+	cfg := game.DefaultSweepConfig
+	for env, setting := range map[string]*time.Duration{
+		"GAME_IDLE_TIMEOUT": &cfg.IdleAfter,
+		"GAME_EVICT_AFTER":  &cfg.EvictAfter,
+		"GAME_SWEEP_EVERY":  &cfg.Interval,
+	} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			log.Printf("Warning: Ignoring %s=%q, not a positive duration", env, value)
+			continue
+		}
+		*setting = d
+	}
+	return cfg
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/hunterMotko/chess-game/internal/tournament"
+	"github.com/labstack/echo/v4"
+)
+
+// createTournamentHandler opens a Swiss, round robin or arena tournament
+// for registration. The organizerId sent is the one that may start it.
+func (s *Server) createTournamentHandler(c echo.Context) error {
+	var req tournament.Request
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	res, err := s.tournaments.Create(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+	return c.JSON(http.StatusCreated, res)
+}
+
+func (s *Server) tournamentHandler(c echo.Context) error {
+	res, err := s.tournaments.Get(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// registerTournamentHandler enters a player in a tournament that hasn't
+// started yet, or in a running arena.
+func (s *Server) registerTournamentHandler(c echo.Context) error {
+	var req struct {
+		PlayerID   string `json:"playerId"`
+		PlayerName string `json:"playerName"`
+		Rating     int    `json:"rating"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	return s.tournamentAction(c, func(id string) error {
+		return s.tournaments.Register(id, req.PlayerID, req.PlayerName, req.Rating)
+	})
+}
+
+// withdrawTournamentHandler takes a player out of a tournament, forfeiting
+// the game they are playing.
+func (s *Server) withdrawTournamentHandler(c echo.Context) error {
+	return s.playerTournamentAction(c, s.tournaments.Withdraw)
+}
+
+// startTournamentHandler closes registration and pairs the first round.
+// Only the organizer may start a tournament.
+func (s *Server) startTournamentHandler(c echo.Context) error {
+	return s.playerTournamentAction(c, s.tournaments.Start)
+}
+
+// playerTournamentAction runs action for the playerId in the request body.
+func (s *Server) playerTournamentAction(c echo.Context, action func(id, playerID string) error) error {
+	var req struct {
+		PlayerID string `json:"playerId"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	return s.tournamentAction(c, func(id string) error {
+		return action(id, req.PlayerID)
+	})
+}
+
+// tournamentAction runs action on the tournament in the path and responds
+// with the tournament as it stands after it.
+func (s *Server) tournamentAction(c echo.Context, action func(id string) error) error {
+	id := c.Param("id")
+	if _, err := s.tournaments.Get(id); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": err.Error(),
+		})
+	}
+	if err := action(id); err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	res, err := s.tournaments.Get(id)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// standingsHandler ranks a tournament's players, with the Buchholz and
+// Sonneborn-Berger tiebreaks.
+func (s *Server) standingsHandler(c echo.Context) error {
+	res, err := s.tournaments.Standings(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// leaderboardHandler ranks an arena's players by score, with their win
+// streaks and whether they are playing.
+func (s *Server) leaderboardHandler(c echo.Context) error {
+	id := c.Param("id")
+	if _, err := s.tournaments.Get(id); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	res, err := s.tournaments.Leaderboard(id)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// pairingsHandler lists the pairings of ?round=N, the current round by
+// default.
+func (s *Server) pairingsHandler(c echo.Context) error {
+	id := c.Param("id")
+	if _, err := s.tournaments.Get(id); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	round := 0
+	if r := c.QueryParam("round"); r != "" {
+		var err error
+		if round, err = strconv.Atoi(r); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": err.Error(),
+			})
+		}
+	}
+
+	res, err := s.tournaments.Pairings(id, round)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
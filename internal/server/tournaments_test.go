@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hunterMotko/chess-game/internal/tournament"
+	"github.com/hunterMotko/chess-game/internal/websockets"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_tournamentHandlers(t *testing.T) {
+	manager := websockets.NewManager(context.Background(), nil)
+	s := &Server{manager: manager, tournaments: manager.Tournaments()}
+	e := echo.New()
+	call := func(handler echo.HandlerFunc, method, target, body string, params ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if len(params) == 2 {
+			c.SetParamNames(params[0])
+			c.SetParamValues(params[1])
+		}
+		require.NoError(t, handler(c))
+		return rec
+	}
+
+	rec := call(s.createTournamentHandler, http.MethodPost, "/api/tournaments", `{"name":"Club night","format":"swiss","rounds":3,"timeControl":{"baseMs":600000},"organizerId":"org"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created tournament.Summary
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	id := created.ID
+
+	for _, body := range []string{`{"playerId":"alice","playerName":"Alice","rating":1800}`, `{"playerId":"bob","playerName":"Bob","rating":1600}`} {
+		rec = call(s.registerTournamentHandler, http.MethodPost, "/api/tournaments/"+id+"/register", body, "id", id)
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.NotContains(t, rec.Body.String(), "alice", "player IDs stay private")
+
+	rec = call(s.startTournamentHandler, http.MethodPost, "/api/tournaments/"+id+"/start", `{"playerId":"alice"}`, "id", id)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = call(s.startTournamentHandler, http.MethodPost, "/api/tournaments/"+id+"/start", `{"playerId":"org"}`, "id", id)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = call(s.pairingsHandler, http.MethodGet, "/api/tournaments/"+id+"/pairings", "", "id", id)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var pairings []tournament.Pairing
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pairings))
+	require.Len(t, pairings, 1)
+	assert.Equal(t, "Alice", pairings[0].WhiteName)
+	assert.NotEmpty(t, pairings[0].GameID)
+	rec = call(s.pairingsHandler, http.MethodGet, "/api/tournaments/"+id+"/pairings?round=2", "", "id", id)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = call(s.standingsHandler, http.MethodGet, "/api/tournaments/"+id+"/standings", "", "id", id)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var standings []tournament.Standing
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &standings))
+	assert.Len(t, standings, 2)
+	rec = call(s.leaderboardHandler, http.MethodGet, "/api/tournaments/"+id+"/leaderboard", "", "id", id)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "only arenas have a leaderboard")
+
+	rec = call(s.createTournamentHandler, http.MethodPost, "/api/tournaments", `{"name":"Arena","format":"arena","minutes":30,"timeControl":{"baseMs":180000},"organizerId":"org"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	rec = call(s.registerTournamentHandler, http.MethodPost, "/api/tournaments/"+created.ID+"/register", `{"playerId":"carol","playerName":"Carol"}`, "id", created.ID)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = call(s.leaderboardHandler, http.MethodGet, "/api/tournaments/"+created.ID+"/leaderboard", "", "id", created.ID)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var leaderboard []tournament.ArenaStanding
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &leaderboard))
+	require.Len(t, leaderboard, 1)
+	assert.Equal(t, "Carol", leaderboard[0].Name)
+	rec = call(s.leaderboardHandler, http.MethodGet, "/api/tournaments/missing/leaderboard", "", "id", "missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = call(s.tournamentHandler, http.MethodGet, "/api/tournaments/missing", "", "id", "missing")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = call(s.createTournamentHandler, http.MethodPost, "/api/tournaments", `{"name":"Club night","format":"knockout","organizerId":"org"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,256 @@
+package tournament
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	// maxArenaMinutes caps how long an arena may run.
+	maxArenaMinutes = 720
+	// defaultArenaInterval is how often an arena pairs the players who are
+	// waiting for a game.
+	defaultArenaInterval = 3 * time.Second
+	// streakLength is how many wins in a row put a player on fire, doubling
+	// what their games score until they fail to win one.
+	streakLength = 2
+)
+
+// ArenaStanding is an entrant's place on an arena's leaderboard.
+type ArenaStanding struct {
+	Rank int `json:"rank"`
+	Entrant
+	Score  int `json:"score"`
+	Played int `json:"played"`
+	Wins   int `json:"wins"`
+	// Streak is the player's current run of wins; OnFire is set once it is
+	// long enough to double their next game's score
+	Streak  int  `json:"streak"`
+	OnFire  bool `json:"onFire"`
+	Playing bool `json:"playing"`
+}
+
+// Leaderboard ranks an arena's entrants by score, breaking ties on wins.
+func (s *Service) Leaderboard(id string) ([]ArenaStanding, error) {
+	t, err := s.tournament(id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.Format != FormatArena {
+		return nil, fmt.Errorf("tournament %s is not an arena", id)
+	}
+	return t.leaderboard(), nil
+}
+
+// leaderboard scores the arena's games in the order they were paired: a
+// win is worth 2, a draw 1, and both count double while the player is on
+// fire. The caller must hold t.mutex.
+func (t *Tournament) leaderboard() []ArenaStanding {
+	standings := make(map[int]*ArenaStanding, len(t.Entrants))
+	for _, entrant := range t.Entrants {
+		standings[entrant.Number] = &ArenaStanding{Entrant: *entrant}
+	}
+
+	for _, pairing := range t.ArenaGames {
+		white, black := standings[pairing.White], standings[pairing.Black]
+		if pairing.Result == "" {
+			white.Playing, black.Playing = true, true
+			continue
+		}
+		whitePoints, blackPoints := t.halfPoints(pairing.Result)
+		white.score(whitePoints)
+		black.score(blackPoints)
+	}
+
+	leaderboard := make([]ArenaStanding, 0, len(standings))
+	for _, entrant := range t.Entrants {
+		leaderboard = append(leaderboard, *standings[entrant.Number])
+	}
+	sort.SliceStable(leaderboard, func(i, j int) bool {
+		a, b := leaderboard[i], leaderboard[j]
+		switch {
+		case a.Score != b.Score:
+			return a.Score > b.Score
+		case a.Wins != b.Wins:
+			return a.Wins > b.Wins
+		}
+		return a.Number < b.Number
+	})
+	for i := range leaderboard {
+		leaderboard[i].Rank = i + 1
+	}
+	return leaderboard
+}
+
+// score adds a finished game, in which the player scored halfPoints, to
+// the standing. Only a win keeps a streak going.
+func (a *ArenaStanding) score(halfPoints int) {
+	a.Played++
+	if a.OnFire {
+		a.Score += 2 * halfPoints
+	} else {
+		a.Score += halfPoints
+	}
+	if halfPoints == 2 {
+		a.Wins++
+		a.Streak++
+	} else {
+		a.Streak = 0
+	}
+	a.OnFire = a.Streak >= streakLength
+}
+
+// runArena pairs the arena's waiting players every arenaInterval until its
+// time is up. Games still being played then are played out and count.
+func (s *Service) runArena(t *Tournament) {
+	ticker := time.NewTicker(s.arenaInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.mutex.Lock()
+		if t.Status == StatusRunning && time.Now().Before(t.EndsAt) {
+			s.pairArena(t)
+			t.mutex.Unlock()
+			continue
+		}
+		if t.Status == StatusRunning {
+			t.Status = StatusFinished
+			log.Printf("🏆 Arena %s finished after %d games", t.ID, len(t.ArenaGames))
+			s.notifyUpdate(t.ID)
+		}
+		t.mutex.Unlock()
+		return
+	}
+}
+
+// pairArena pairs the players who are present and aren't in a game, each
+// with the closest in score they didn't just play, and creates their games.
+// A player who leaves is left out until they come back. The caller must
+// hold t.mutex.
+func (s *Service) pairArena(t *Tournament) {
+	records := t.records()
+	scores := make(map[int]int, len(t.Entrants))
+	lastOpponent := make(map[int]int, len(t.Entrants))
+	busy := make(map[int]bool)
+	for _, standing := range t.leaderboard() {
+		scores[standing.Number] = standing.Score
+		busy[standing.Number] = standing.Playing
+	}
+	for _, pairing := range t.ArenaGames {
+		lastOpponent[pairing.White], lastOpponent[pairing.Black] = pairing.Black, pairing.White
+	}
+
+	var waiting []*Entrant
+	for _, entrant := range t.active() {
+		if !busy[entrant.Number] && s.present(t.ID, entrant.ID) {
+			waiting = append(waiting, entrant)
+		}
+	}
+	sort.SliceStable(waiting, func(i, j int) bool {
+		a, b := waiting[i], waiting[j]
+		switch {
+		case scores[a.Number] != scores[b.Number]:
+			return scores[a.Number] > scores[b.Number]
+		case a.Rating != b.Rating:
+			return a.Rating > b.Rating
+		}
+		return a.Number < b.Number
+	})
+
+	paired := 0
+	for len(waiting) >= 2 {
+		a := waiting[0]
+		opponent := 1
+		for i := 1; i < len(waiting); i++ {
+			if lastOpponent[a.Number] != waiting[i].Number {
+				opponent = i
+				break
+			}
+		}
+		b := waiting[opponent]
+		waiting = append(waiting[1:opponent:opponent], waiting[opponent+1:]...)
+
+		board := len(t.ArenaGames) + 1
+		white, black := allocateColors(records[a.Number], records[b.Number], board)
+		pairing := Pairing{
+			Board:     board,
+			White:     white.entrant.Number,
+			WhiteName: white.entrant.Name,
+			Black:     black.entrant.Number,
+			BlackName: black.entrant.Name,
+		}
+		gameID, err := s.createGame(t, &pairing)
+		if err != nil {
+			log.Printf("Warning: Arena %s could not pair %s and %s: %v", t.ID, white.entrant.ID, black.entrant.ID, err)
+			continue
+		}
+		pairing.GameID = gameID
+		t.ArenaGames = append(t.ArenaGames, pairing)
+		s.notifyPaired(t, pairing)
+		paired++
+	}
+	if paired > 0 {
+		log.Printf("🏆 Arena %s: %d games paired", t.ID, paired)
+		s.notifyUpdate(t.ID)
+	}
+}
+
+// SetUpdateHandler sets the function called whenever a tournament's
+// entrants, games or results change.
+func (s *Service) SetUpdateHandler(handler func(tournamentID string)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.onUpdate = handler
+}
+
+// SetPairingHandler sets the function called with each game a tournament
+// pairs, along with its players' IDs.
+func (s *Service) SetPairingHandler(handler func(tournamentID string, pairing Pairing, whiteID, blackID string)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.onPaired = handler
+}
+
+// SetPresenceCheck sets the function that tells whether a player is
+// following a tournament, and so can be paired in an arena.
+func (s *Service) SetPresenceCheck(check func(tournamentID, playerID string) bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.isPresent = check
+}
+
+// present reports whether a player can be paired in an arena now. The
+// caller must hold the tournament's lock.
+func (s *Service) present(tournamentID, playerID string) bool {
+	s.mutex.RLock()
+	check := s.isPresent
+	s.mutex.RUnlock()
+	return check == nil || check(tournamentID, playerID)
+}
+
+// notifyUpdate runs the update handler in its own goroutine, as the caller
+// holds the tournament's lock.
+func (s *Service) notifyUpdate(tournamentID string) {
+	s.mutex.RLock()
+	handler := s.onUpdate
+	s.mutex.RUnlock()
+	if handler != nil {
+		go handler(tournamentID)
+	}
+}
+
+// notifyPaired runs the pairing handler in its own goroutine. The caller
+// must hold t.mutex.
+func (s *Service) notifyPaired(t *Tournament, pairing Pairing) {
+	s.mutex.RLock()
+	handler := s.onPaired
+	s.mutex.RUnlock()
+	if handler != nil {
+		go handler(t.ID, pairing, t.byNumber(pairing.White).ID, t.byNumber(pairing.Black).ID)
+	}
+}
@@ -0,0 +1,156 @@
+package tournament
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/hunterMotko/chess-game/internal/game"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTournament_ArenaLeaderboard(t *testing.T) {
+	tour := swissTournament(1500, 1500, 1500)
+	tour.Format = FormatArena
+	tour.ArenaGames = []Pairing{
+		{Board: 1, White: 1, Black: 2, GameID: "g1", Result: ResultWhiteWins},
+		{Board: 2, White: 2, Black: 1, GameID: "g2", Result: ResultBlackWins},
+		{Board: 3, White: 1, Black: 3, GameID: "g3", Result: ResultWhiteWins},
+		{Board: 4, White: 3, Black: 1, GameID: "g4", Result: ResultDraw},
+		{Board: 5, White: 2, Black: 3, GameID: "g5", Result: ResultDraw},
+		{Board: 6, White: 1, Black: 2, GameID: "g6"},
+	}
+
+	leaderboard := tour.leaderboard()
+	require.Len(t, leaderboard, 3)
+
+	first := leaderboard[0]
+	assert.Equal(t, 1, first.Number)
+	// 2 + 2, then on fire: 4 for the third win and 2 for the draw
+	assert.Equal(t, 10, first.Score)
+	assert.Equal(t, 4, first.Played)
+	assert.Equal(t, 3, first.Wins)
+	assert.Equal(t, 0, first.Streak, "the draw ended the streak")
+	assert.False(t, first.OnFire)
+	assert.True(t, first.Playing)
+
+	assert.Equal(t, 3, leaderboard[1].Number, "two draws beat one")
+	assert.Equal(t, 2, leaderboard[1].Score)
+	assert.Equal(t, 2, leaderboard[2].Number)
+	assert.Equal(t, 1, leaderboard[2].Score)
+	assert.False(t, leaderboard[1].Playing)
+}
+
+func TestTournament_ArenaStreak(t *testing.T) {
+	var standing ArenaStanding
+	standing.score(2)
+	assert.False(t, standing.OnFire)
+	standing.score(2)
+	assert.True(t, standing.OnFire, "two wins in a row")
+	standing.score(2)
+	assert.Equal(t, 8, standing.Score)
+	standing.score(0)
+	assert.False(t, standing.OnFire)
+	standing.score(2)
+	assert.Equal(t, 10, standing.Score, "a loss resets the bonus")
+}
+
+func TestService_Arena(t *testing.T) {
+	gs := game.NewGameService(nil)
+	s := NewService(gs)
+	s.arenaInterval = 10 * time.Millisecond
+	tc := game.TimeControl{BaseMs: int64(3 * time.Minute / time.Millisecond)}
+
+	_, err := s.Create(Request{Name: "Arena", Format: FormatArena, Minutes: 30, OrganizerID: "organizer"})
+	assert.Error(t, err, "an arena needs a time control")
+	_, err = s.Create(Request{Name: "Arena", Format: FormatArena, TimeControl: &tc, OrganizerID: "organizer"})
+	assert.Error(t, err, "an arena needs a duration")
+
+	id := openTournament(t, s, Request{Format: FormatArena, Minutes: 30, TimeControl: &tc}, "alice", "bob", "carol", "dave")
+	require.NoError(t, s.Start(id, "organizer"))
+	_, err = s.Standings(id)
+	assert.Error(t, err, "arenas have a leaderboard")
+
+	first, err := s.Pairings(id, 0)
+	require.NoError(t, err)
+	require.Len(t, first, 2, "everybody plays straight away")
+	met := map[[2]int]bool{}
+	for _, pairing := range first {
+		met[[2]int{pairing.White, pairing.Black}] = true
+		met[[2]int{pairing.Black, pairing.White}] = true
+		state, err := gs.GetGameState(pairing.GameID)
+		require.NoError(t, err)
+		require.NoError(t, gs.Resign(pairing.GameID, state.Players[chess.Black].ID))
+	}
+
+	// Players who finish are re-paired, with somebody new
+	require.Eventually(t, func() bool {
+		pairings, err := s.Pairings(id, 0)
+		return err == nil && len(pairings) == 4
+	}, time.Second, 5*time.Millisecond)
+	pairings, err := s.Pairings(id, 0)
+	require.NoError(t, err)
+	for _, pairing := range pairings[2:] {
+		assert.False(t, met[[2]int{pairing.White, pairing.Black}], "board %d is a rematch", pairing.Board)
+	}
+
+	leaderboard, err := s.Leaderboard(id)
+	require.NoError(t, err)
+	assert.Equal(t, 2, leaderboard[0].Score)
+	assert.Equal(t, 2, leaderboard[1].Score)
+	for _, standing := range leaderboard {
+		assert.True(t, standing.Playing)
+		assert.Equal(t, 1, standing.Played)
+	}
+
+	require.NoError(t, s.Register(id, "erin", "erin", 1500), "arenas take late entries")
+
+	arena, err := s.tournament(id)
+	require.NoError(t, err)
+	arena.mutex.Lock()
+	arena.EndsAt = time.Now()
+	arena.mutex.Unlock()
+	require.Eventually(t, func() bool {
+		summary, err := s.Get(id)
+		return err == nil && summary.Status == StatusFinished
+	}, time.Second, 5*time.Millisecond)
+	assert.Error(t, s.Register(id, "frank", "frank", 1500))
+}
+
+func TestService_ArenaPairsPresentPlayers(t *testing.T) {
+	s := NewService(game.NewGameService(nil))
+	s.arenaInterval = 10 * time.Millisecond
+	tc := game.TimeControl{BaseMs: int64(3 * time.Minute / time.Millisecond)}
+
+	var mutex sync.Mutex
+	present := map[string]bool{"alice": true, "bob": true, "carol": true}
+	s.SetPresenceCheck(func(tournamentID, playerID string) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return present[playerID]
+	})
+
+	id := openTournament(t, s, Request{Format: FormatArena, Minutes: 30, TimeControl: &tc}, "alice", "bob", "carol", "dave")
+	require.NoError(t, s.Start(id, "organizer"))
+	time.Sleep(30 * time.Millisecond)
+	pairings, err := s.Pairings(id, 0)
+	require.NoError(t, err)
+	require.Len(t, pairings, 1, "carol waits for somebody who is there")
+	for _, pairing := range pairings {
+		assert.NotContains(t, []string{pairing.WhiteName, pairing.BlackName}, "dave")
+	}
+
+	// Once dave follows the arena again they are paired
+	mutex.Lock()
+	present["dave"] = true
+	mutex.Unlock()
+	require.Eventually(t, func() bool {
+		pairings, err := s.Pairings(id, 0)
+		return err == nil && len(pairings) == 2
+	}, time.Second, 5*time.Millisecond)
+	pairings, err = s.Pairings(id, 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"carol", "dave"}, []string{pairings[1].WhiteName, pairings[1].BlackName})
+}
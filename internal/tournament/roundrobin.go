@@ -0,0 +1,43 @@
+package tournament
+
+// roundRobinSchedule pairs every player with every other once, by the
+// circle method: the first player stays put while the others rotate around
+// them. With an odd number of players, whoever would meet the missing
+// player sits the round out with a bye.
+func roundRobinSchedule(numbers []int) [][]Pairing {
+	circle := append([]int{}, numbers...)
+	if len(circle)%2 == 1 {
+		circle = append(circle, 0) // Zero stands for the bye
+	}
+	n := len(circle)
+
+	rounds := make([][]Pairing, 0, n-1)
+	for round := 0; round < n-1; round++ {
+		var pairings, byes []Pairing
+		for i := 0; i < n/2; i++ {
+			white, black := circle[i], circle[n-1-i]
+			// The rotation balances everybody's colors but the first
+			// player's, who alternates
+			if i == 0 && round%2 == 1 {
+				white, black = black, white
+			}
+			switch {
+			case white == 0:
+				byes = append(byes, Pairing{White: black, Result: ResultBye})
+			case black == 0:
+				byes = append(byes, Pairing{White: white, Result: ResultBye})
+			default:
+				pairings = append(pairings, Pairing{White: white, Black: black})
+			}
+		}
+		pairings = append(pairings, byes...)
+		for i := range pairings {
+			pairings[i].Board = i + 1
+		}
+		rounds = append(rounds, pairings)
+
+		// Rotate everybody but the first player one place
+		circle = append([]int{circle[0], circle[n-1]}, circle[1:n-1]...)
+	}
+	return rounds
+}
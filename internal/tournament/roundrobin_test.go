@@ -0,0 +1,57 @@
+package tournament
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundRobinSchedule(t *testing.T) {
+	for _, players := range []int{4, 5, 8, 11} {
+		numbers := make([]int, players)
+		for i := range numbers {
+			numbers[i] = i + 1
+		}
+		rounds := roundRobinSchedule(numbers)
+
+		wantRounds := players - 1
+		if players%2 == 1 {
+			wantRounds = players
+		}
+		require.Len(t, rounds, wantRounds)
+
+		met := make(map[[2]int]int)
+		byes := make(map[int]int)
+		whites := make(map[int]int)
+		for _, round := range rounds {
+			seen := make(map[int]bool)
+			for _, pairing := range round {
+				assert.False(t, seen[pairing.White], "%d plays twice in a round", pairing.White)
+				seen[pairing.White] = true
+				if pairing.Black == 0 {
+					assert.Equal(t, ResultBye, pairing.Result)
+					byes[pairing.White]++
+					continue
+				}
+				assert.False(t, seen[pairing.Black], "%d plays twice in a round", pairing.Black)
+				seen[pairing.Black] = true
+				whites[pairing.White]++
+				met[[2]int{min(pairing.White, pairing.Black), max(pairing.White, pairing.Black)}]++
+			}
+			assert.Len(t, seen, players)
+		}
+
+		assert.Len(t, met, players*(players-1)/2, "everybody meets everybody")
+		for pair, times := range met {
+			assert.Equal(t, 1, times, "%v meet more than once", pair)
+		}
+		for _, number := range numbers {
+			if players%2 == 1 {
+				assert.Equal(t, 1, byes[number], "everybody sits out once")
+			}
+			blacks := players - 1 - whites[number]
+			assert.LessOrEqual(t, max(whites[number]-blacks, blacks-whites[number]), 1, "%d's colors are balanced", number)
+		}
+	}
+}
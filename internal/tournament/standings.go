@@ -0,0 +1,146 @@
+package tournament
+
+import (
+	"fmt"
+	"sort"
+)
+
+// record is an entrant's tournament so far.
+type record struct {
+	entrant   *Entrant
+	points    int          // In half points
+	opponents map[int]bool // Entrant numbers met, over the board or by forfeit
+	games     []scoredGame // Against opponents, for the tiebreaks
+	colorDiff int          // Games with white minus games with black
+	lastColor int          // +1 for white, -1 for black, 0 before any game
+	hadBye    bool
+}
+
+type scoredGame struct {
+	opponent int
+	points   int // In half points
+}
+
+// halfPoints returns what white and black score from a result, in half
+// points. A Swiss bye scores a win; in a round robin everybody sits out
+// the same number of rounds, so byes score nothing.
+func (t *Tournament) halfPoints(result Result) (white, black int) {
+	switch result {
+	case ResultWhiteWins, ResultWhiteForfeit:
+		return 2, 0
+	case ResultBlackWins, ResultBlackForfeit:
+		return 0, 2
+	case ResultDraw:
+		return 1, 1
+	case ResultBye:
+		if t.Format == FormatSwiss {
+			return 2, 0
+		}
+	}
+	return 0, 0
+}
+
+// records tallies every entrant's results so far, by entrant number. The
+// caller must hold t.mutex.
+func (t *Tournament) records() map[int]*record {
+	records := make(map[int]*record, len(t.Entrants))
+	for _, entrant := range t.Entrants {
+		records[entrant.Number] = &record{entrant: entrant, opponents: make(map[int]bool)}
+	}
+
+	rounds := t.Rounds
+	if t.Format == FormatArena {
+		rounds = [][]Pairing{t.ArenaGames}
+	}
+	for _, round := range rounds {
+		for _, pairing := range round {
+			if pairing.Result == "" {
+				continue
+			}
+			white, black := records[pairing.White], records[pairing.Black]
+			whitePoints, blackPoints := t.halfPoints(pairing.Result)
+			white.points += whitePoints
+			if black == nil {
+				white.hadBye = true
+				continue
+			}
+			black.points += blackPoints
+			white.opponents[black.entrant.Number] = true
+			black.opponents[white.entrant.Number] = true
+			white.games = append(white.games, scoredGame{black.entrant.Number, whitePoints})
+			black.games = append(black.games, scoredGame{white.entrant.Number, blackPoints})
+			if pairing.GameID != "" {
+				white.colorDiff++
+				white.lastColor = 1
+				black.colorDiff--
+				black.lastColor = -1
+			}
+		}
+	}
+	return records
+}
+
+// Standing is an entrant's place in a tournament.
+type Standing struct {
+	Rank int `json:"rank"`
+	Entrant
+	Points float64 `json:"points"`
+	// Buchholz adds up the opponents' points; Sonneborn-Berger adds up the
+	// points of the opponents beaten and half those of the opponents drawn
+	Buchholz        float64 `json:"buchholz"`
+	SonnebornBerger float64 `json:"sonnebornBerger"`
+	Played          int     `json:"played"`
+}
+
+// Standings ranks a tournament's entrants by points, breaking ties on
+// Buchholz, then Sonneborn-Berger. Arenas have a leaderboard instead.
+func (s *Service) Standings(id string) ([]Standing, error) {
+	t, err := s.tournament(id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.Format == FormatArena {
+		return nil, fmt.Errorf("tournament %s is an arena; see its leaderboard", id)
+	}
+	return t.standings(), nil
+}
+
+// standings ranks the entrants. The caller must hold t.mutex.
+func (t *Tournament) standings() []Standing {
+	records := t.records()
+	standings := make([]Standing, 0, len(t.Entrants))
+	for _, entrant := range t.Entrants {
+		r := records[entrant.Number]
+		standing := Standing{
+			Entrant: *entrant,
+			Points:  float64(r.points) / 2,
+			Played:  len(r.games),
+		}
+		for _, g := range r.games {
+			opponentPoints := float64(records[g.opponent].points) / 2
+			standing.Buchholz += opponentPoints
+			standing.SonnebornBerger += opponentPoints * float64(g.points) / 2
+		}
+		standings = append(standings, standing)
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		switch {
+		case a.Points != b.Points:
+			return a.Points > b.Points
+		case a.Buchholz != b.Buchholz:
+			return a.Buchholz > b.Buchholz
+		case a.SonnebornBerger != b.SonnebornBerger:
+			return a.SonnebornBerger > b.SonnebornBerger
+		}
+		return a.Number < b.Number
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
@@ -0,0 +1,55 @@
+package tournament
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTournament_Standings(t *testing.T) {
+	tour := swissTournament(1500, 1500, 1500, 1500, 1500)
+	tour.Rounds = [][]Pairing{
+		{
+			{Board: 1, White: 1, Black: 2, GameID: "g1", Result: ResultWhiteWins},
+			{Board: 2, White: 3, Black: 4, GameID: "g2", Result: ResultDraw},
+			{Board: 3, White: 5, Result: ResultBye},
+		},
+		{
+			{Board: 1, White: 5, Black: 1, GameID: "g3", Result: ResultBlackWins},
+			{Board: 2, White: 4, Black: 2, GameID: "g4", Result: ResultBlackWins},
+			{Board: 3, White: 3, Result: ResultBye},
+		},
+	}
+
+	standings := tour.standings()
+	require.Len(t, standings, 5)
+	numbers := make([]int, len(standings))
+	for i, standing := range standings {
+		numbers[i] = standing.Number
+		assert.Equal(t, i+1, standing.Rank)
+	}
+	// 1 has 2 points and 3 has 1.5; 2 and 5 both have 1, but 2 met
+	// stronger opposition; 4 has 0.5
+	assert.Equal(t, []int{1, 3, 2, 5, 4}, numbers)
+	assert.Equal(t, 2.5, standings[2].Buchholz)
+	assert.Equal(t, 2.0, standings[3].Buchholz)
+
+	first := standings[0]
+	assert.Equal(t, 2.0, first.Points)
+	assert.Equal(t, 2, first.Played)
+	assert.Equal(t, 2.0, first.Buchholz, "2 and 5 scored 1 each")
+	assert.Equal(t, 2.0, first.SonnebornBerger, "both were beaten")
+
+	third := standings[1]
+	assert.Equal(t, 1.5, third.Points, "a draw and a Swiss bye")
+	assert.Equal(t, 0.5, third.Buchholz, "byes don't count towards Buchholz")
+	assert.Equal(t, 0.25, third.SonnebornBerger)
+}
+
+func TestTournament_RoundRobinByesScoreNothing(t *testing.T) {
+	tour := &Tournament{Format: FormatRoundRobin, Entrants: []*Entrant{{Number: 1}}}
+	tour.Rounds = [][]Pairing{{{Board: 1, White: 1, Result: ResultBye}}}
+
+	assert.Equal(t, 0.0, tour.standings()[0].Points)
+}
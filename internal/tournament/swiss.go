@@ -0,0 +1,141 @@
+package tournament
+
+import "sort"
+
+// maxPairingSteps bounds the search for a Swiss round without rematches.
+const maxPairingSteps = 100000
+
+// pairSwiss pairs the next Swiss round, Dutch-style. Players are ranked by
+// score, then rating; within each score group the top half plays the
+// bottom half, players left without an opponent in their group float down
+// to the next, and nobody meets the same opponent twice while that can be
+// avoided. With an odd number of players the lowest ranked player who
+// hasn't had a bye yet gets one.
+func pairSwiss(active []*Entrant, records map[int]*record) []Pairing {
+	ranked := make([]*record, 0, len(active))
+	for _, entrant := range active {
+		ranked = append(ranked, records[entrant.Number])
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		switch {
+		case a.points != b.points:
+			return a.points > b.points
+		case a.entrant.Rating != b.entrant.Rating:
+			return a.entrant.Rating > b.entrant.Rating
+		}
+		return a.entrant.Number < b.entrant.Number
+	})
+
+	var bye *record
+	if len(ranked)%2 == 1 {
+		byeAt := len(ranked) - 1
+		for i := len(ranked) - 1; i >= 0; i-- {
+			if !ranked[i].hadBye {
+				byeAt = i
+				break
+			}
+		}
+		bye = ranked[byeAt]
+		ranked = append(ranked[:byeAt:byeAt], ranked[byeAt+1:]...)
+	}
+
+	steps := maxPairingSteps
+	pairs, ok := pairGroups(ranked, false, &steps)
+	if !ok {
+		// Too few fresh opponents are left; a rematch beats sitting out
+		pairs, _ = pairGroups(ranked, true, &steps)
+	}
+
+	pairings := make([]Pairing, 0, len(pairs)+1)
+	for i, pair := range pairs {
+		board := i + 1
+		white, black := allocateColors(pair[0], pair[1], board)
+		pairings = append(pairings, Pairing{
+			Board: board,
+			White: white.entrant.Number,
+			Black: black.entrant.Number,
+		})
+	}
+	if bye != nil {
+		pairings = append(pairings, Pairing{
+			Board:  len(pairings) + 1,
+			White:  bye.entrant.Number,
+			Result: ResultBye,
+		})
+	}
+	return pairings
+}
+
+// pairGroups pairs ranked players, the best first, backtracking when a
+// choice leaves the players below it without a legal pairing. steps
+// bounds the search; it gives up once they run out.
+func pairGroups(ranked []*record, allowRematches bool, steps *int) ([][2]*record, bool) {
+	if len(ranked) == 0 {
+		return nil, true
+	}
+	if *steps <= 0 {
+		return nil, false
+	}
+	*steps--
+
+	top, rest := ranked[0], ranked[1:]
+	for _, i := range opponentOrder(top, rest) {
+		opponent := rest[i]
+		if !allowRematches && top.opponents[opponent.entrant.Number] {
+			continue
+		}
+		remaining := append(append([]*record{}, rest[:i]...), rest[i+1:]...)
+		if pairs, ok := pairGroups(remaining, allowRematches, steps); ok {
+			return append([][2]*record{{top, opponent}}, pairs...), true
+		}
+	}
+	return nil, false
+}
+
+// opponentOrder lists the indexes in rest of top's opponents, the best
+// first. The Dutch system pairs the top half of a score group with its
+// bottom half, so the best opponent is the player half a group below top;
+// then come the rest of the group, lower first, then the groups below.
+func opponentOrder(top *record, rest []*record) []int {
+	group := 0
+	for group < len(rest) && rest[group].points == top.points {
+		group++
+	}
+
+	order := make([]int, 0, len(rest))
+	if group > 0 {
+		ideal := (group+1)/2 - 1
+		for i := ideal; i < group; i++ {
+			order = append(order, i)
+		}
+		for i := ideal - 1; i >= 0; i-- {
+			order = append(order, i)
+		}
+	}
+	for i := group; i < len(rest); i++ {
+		order = append(order, i)
+	}
+	return order
+}
+
+// allocateColors gives white to the player who has had it less, or who had
+// black last time. When neither decides, the higher ranked player a gets
+// white on odd boards and black on even ones.
+func allocateColors(a, b *record, board int) (white, black *record) {
+	switch {
+	case a.colorDiff != b.colorDiff:
+		if a.colorDiff < b.colorDiff {
+			return a, b
+		}
+		return b, a
+	case a.lastColor != b.lastColor:
+		if a.lastColor < b.lastColor {
+			return a, b
+		}
+		return b, a
+	case board%2 == 0:
+		return b, a
+	}
+	return a, b
+}
@@ -0,0 +1,85 @@
+package tournament
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// swissTournament registers players with the given ratings, in order.
+func swissTournament(ratings ...int) *Tournament {
+	t := &Tournament{Format: FormatSwiss, Status: StatusRunning}
+	for i, rating := range ratings {
+		t.Entrants = append(t.Entrants, &Entrant{Number: i + 1, ID: string(rune('a' + i)), Rating: rating})
+	}
+	return t
+}
+
+func TestPairSwiss_FirstRound(t *testing.T) {
+	tour := swissTournament(1500, 1900, 1700, 1600, 1800)
+
+	pairings := pairSwiss(tour.active(), tour.records())
+	require.Len(t, pairings, 3)
+
+	// Ranked 1900, 1800, 1700, 1600, 1500: the lowest rated sits out, and
+	// the top half plays the bottom half with colors alternating by board
+	assert.Equal(t, Pairing{Board: 1, White: 2, Black: 3}, pairings[0])
+	assert.Equal(t, Pairing{Board: 2, White: 4, Black: 5}, pairings[1])
+	assert.Equal(t, Pairing{Board: 3, White: 1, Result: ResultBye}, pairings[2])
+}
+
+func TestPairSwiss_LaterRounds(t *testing.T) {
+	tour := swissTournament(2000, 1900, 1800, 1700)
+	tour.Rounds = [][]Pairing{{
+		{Board: 1, White: 1, Black: 3, GameID: "g1", Result: ResultWhiteWins},
+		{Board: 2, White: 4, Black: 2, GameID: "g2", Result: ResultBlackWins},
+	}}
+
+	// The winners meet, and so do the losers, each with the color they
+	// didn't have
+	pairings := pairSwiss(tour.active(), tour.records())
+	require.Len(t, pairings, 2)
+	assert.ElementsMatch(t, [][2]int{{2, 1}, {3, 4}}, [][2]int{
+		{pairings[0].White, pairings[0].Black},
+		{pairings[1].White, pairings[1].Black},
+	})
+
+	tour.Rounds = append(tour.Rounds, []Pairing{
+		{Board: 1, White: 2, Black: 1, GameID: "g3", Result: ResultDraw},
+		{Board: 2, White: 3, Black: 4, GameID: "g4", Result: ResultDraw},
+	})
+
+	// Nobody plays the same opponent twice
+	pairings = pairSwiss(tour.active(), tour.records())
+	require.Len(t, pairings, 2)
+	records := tour.records()
+	for _, pairing := range pairings {
+		assert.False(t, records[pairing.White].opponents[pairing.Black], "%d and %d have met", pairing.White, pairing.Black)
+	}
+}
+
+func TestPairSwiss_ByeGoesToSomeoneWithout(t *testing.T) {
+	tour := swissTournament(2000, 1900, 1800)
+	tour.Rounds = [][]Pairing{{
+		{Board: 1, White: 1, Black: 2, GameID: "g1", Result: ResultDraw},
+		{Board: 2, White: 3, Result: ResultBye},
+	}}
+
+	pairings := pairSwiss(tour.active(), tour.records())
+	require.Len(t, pairings, 2)
+	bye := pairings[1]
+	assert.Equal(t, ResultBye, bye.Result)
+	assert.NotEqual(t, 3, bye.White, "3 has had the bye already")
+}
+
+func TestPairSwiss_AllowsRematchesAsALastResort(t *testing.T) {
+	tour := swissTournament(2000, 1900)
+	tour.Rounds = [][]Pairing{{
+		{Board: 1, White: 1, Black: 2, GameID: "g1", Result: ResultDraw},
+	}}
+
+	pairings := pairSwiss(tour.active(), tour.records())
+	require.Len(t, pairings, 1)
+	assert.Equal(t, Pairing{Board: 1, White: 2, Black: 1}, pairings[0])
+}
@@ -0,0 +1,620 @@
+package tournament
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+	"github.com/hunterMotko/chess-game/internal/game"
+)
+
+// Format is how a tournament pairs its players.
+type Format string
+
+const (
+	// FormatSwiss pairs players on equal scores who haven't met, round by
+	// round, Dutch-style
+	FormatSwiss Format = "swiss"
+	// FormatRoundRobin has everybody play everybody else once
+	FormatRoundRobin Format = "round_robin"
+	// FormatArena re-pairs players as soon as they finish a game, for as
+	// long as the arena runs
+	FormatArena Format = "arena"
+)
+
+// maxSwissRounds caps how many rounds a Swiss tournament may have.
+const maxSwissRounds = 15
+
+type Status string
+
+const (
+	StatusRegistering Status = "registering"
+	StatusRunning     Status = "running"
+	StatusFinished    Status = "finished"
+)
+
+// Result is the result of a pairing, from white's side as in PGN.
+type Result string
+
+const (
+	ResultWhiteWins Result = "1-0"
+	ResultBlackWins Result = "0-1"
+	ResultDraw      Result = "1/2-1/2"
+	// Forfeits are scored like wins and losses without a game being played,
+	// e.g. against a player who withdrew
+	ResultWhiteForfeit  Result = "+-"
+	ResultBlackForfeit  Result = "-+"
+	ResultDoubleForfeit Result = "0-0"
+	// ResultBye is a round sat out by the player left over, who plays white
+	ResultBye Result = "bye"
+)
+
+// Request opens a tournament.
+type Request struct {
+	Name   string `json:"name"`
+	Format Format `json:"format"`
+	// Rounds is how many rounds a Swiss tournament has; a round robin has
+	// one per opponent
+	Rounds int `json:"rounds"`
+	// TimeControl is required, so a board whose players never turn up
+	// still ends when the clock runs out
+	TimeControl *game.TimeControl `json:"timeControl"`
+	// OrganizerID is the player who may start the tournament
+	OrganizerID string `json:"organizerId"`
+	// Minutes is how long an arena runs
+	Minutes int `json:"minutes"`
+}
+
+// Entrant is a registered player. Their ID binds them to their seats in
+// the tournament's games and stays private; Number identifies them
+// publicly.
+type Entrant struct {
+	Number    int    `json:"number"` // Registration order, from 1
+	ID        string `json:"-"`
+	Name      string `json:"name"`
+	Rating    int    `json:"rating"`
+	Withdrawn bool   `json:"withdrawn,omitempty"`
+}
+
+// Pairing is one board of a round.
+type Pairing struct {
+	Board     int    `json:"board"`
+	White     int    `json:"white"` // Entrant number
+	WhiteName string `json:"whiteName"`
+	Black     int    `json:"black,omitempty"` // Zero for a bye
+	BlackName string `json:"blackName,omitempty"`
+	GameID    string `json:"gameId,omitempty"`
+	Result    Result `json:"result,omitempty"` // Empty while the game is played
+}
+
+// Tournament is a club event played out in rounds of games created on the
+// game service.
+type Tournament struct {
+	ID          string
+	Name        string
+	Format      Format
+	TotalRounds int
+	TimeControl *game.TimeControl
+	OrganizerID string
+	Status      Status
+	Entrants    []*Entrant
+	// Rounds holds the pairings of every round begun so far
+	Rounds [][]Pairing
+	// schedule is a round robin's pairings for every round, made up front
+	schedule [][]Pairing
+	// An arena runs for Duration until EndsAt; ArenaGames holds its games
+	// in the order they were paired
+	Duration   time.Duration
+	EndsAt     time.Time
+	ArenaGames []Pairing
+	CreatedAt  time.Time
+	mutex      sync.Mutex
+}
+
+// Summary is a tournament as the API shows it.
+type Summary struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Format       Format            `json:"format"`
+	Status       Status            `json:"status"`
+	TimeControl  *game.TimeControl `json:"timeControl,omitempty"`
+	TotalRounds  int               `json:"totalRounds"`
+	CurrentRound int               `json:"currentRound"` // Zero until it starts
+	Entrants     []Entrant         `json:"entrants"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	Minutes      int               `json:"minutes,omitempty"` // Arenas only
+	EndsAt       *time.Time        `json:"endsAt,omitempty"`  // Once an arena has started
+}
+
+// Service runs tournaments on top of the game service. Results come in
+// from the game service's game over handler. A tournament's lock comes
+// before the service's.
+//
+// Tournaments live in memory only and don't survive a restart. Their games
+// are marked with the tournament, so the game service abandons them rather
+// than restoring games nothing would score.
+type Service struct {
+	games       *game.GameService
+	tournaments map[string]*Tournament
+	boards      map[string]string // gameID -> tournament ID
+	mutex       sync.RWMutex
+	// arenaInterval is how often arenas pair their waiting players
+	arenaInterval time.Duration
+	// onUpdate and onPaired are called when a tournament changes and when
+	// it pairs a game, so the caller can broadcast them
+	onUpdate func(tournamentID string)
+	onPaired func(tournamentID string, pairing Pairing, whiteID, blackID string)
+	// isPresent reports whether a player is following a tournament, so
+	// arenas only pair players who are there to play; without it everybody
+	// is taken to be
+	isPresent func(tournamentID, playerID string) bool
+}
+
+func NewService(games *game.GameService) *Service {
+	s := &Service{
+		games:         games,
+		tournaments:   make(map[string]*Tournament),
+		boards:        make(map[string]string),
+		arenaInterval: defaultArenaInterval,
+	}
+	games.AddGameOverHandler(s.recordResult)
+	return s
+}
+
+// Create opens a tournament for registration.
+func (s *Service) Create(req Request) (*Summary, error) {
+	if req.OrganizerID == "" {
+		return nil, fmt.Errorf("organizerId is required")
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	switch req.Format {
+	case FormatSwiss:
+		if req.Rounds < 1 || req.Rounds > maxSwissRounds {
+			return nil, fmt.Errorf("a Swiss tournament needs between 1 and %d rounds", maxSwissRounds)
+		}
+	case FormatRoundRobin:
+	case FormatArena:
+		if req.Minutes < 1 || req.Minutes > maxArenaMinutes {
+			return nil, fmt.Errorf("an arena runs for between 1 and %d minutes", maxArenaMinutes)
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q", req.Format)
+	}
+	if req.TimeControl == nil {
+		return nil, fmt.Errorf("a tournament needs a time control")
+	}
+	if err := req.TimeControl.Validate(); err != nil {
+		return nil, err
+	}
+
+	t := &Tournament{
+		ID:          uuid.NewString(),
+		Name:        strings.TrimSpace(req.Name),
+		Format:      req.Format,
+		TotalRounds: req.Rounds,
+		TimeControl: req.TimeControl,
+		OrganizerID: req.OrganizerID,
+		Status:      StatusRegistering,
+		Duration:    time.Duration(req.Minutes) * time.Minute,
+		CreatedAt:   time.Now(),
+	}
+	s.mutex.Lock()
+	s.tournaments[t.ID] = t
+	s.mutex.Unlock()
+
+	log.Printf("🏆 Tournament %s (%s) opened by %s", t.ID, t.Format, t.OrganizerID)
+	return s.Get(t.ID)
+}
+
+func (s *Service) tournament(id string) (*Tournament, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	t, exists := s.tournaments[id]
+	if !exists {
+		return nil, fmt.Errorf("tournament %s not found", id)
+	}
+	return t, nil
+}
+
+// Get returns a tournament's summary.
+func (s *Service) Get(id string) (*Summary, error) {
+	t, err := s.tournament(id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.summary(), nil
+}
+
+// summary renders the tournament. The caller must hold t.mutex.
+func (t *Tournament) summary() *Summary {
+	summary := &Summary{
+		ID:           t.ID,
+		Name:         t.Name,
+		Format:       t.Format,
+		Status:       t.Status,
+		TimeControl:  t.TimeControl,
+		TotalRounds:  t.TotalRounds,
+		CurrentRound: len(t.Rounds),
+		Entrants:     make([]Entrant, 0, len(t.Entrants)),
+		CreatedAt:    t.CreatedAt,
+	}
+	for _, entrant := range t.Entrants {
+		summary.Entrants = append(summary.Entrants, *entrant)
+	}
+	if t.Format == FormatArena {
+		summary.Minutes = int(t.Duration / time.Minute)
+		if !t.EndsAt.IsZero() {
+			endsAt := t.EndsAt
+			summary.EndsAt = &endsAt
+		}
+	}
+	return summary
+}
+
+// Register enters playerID in a tournament that hasn't started yet, or in
+// an arena that is still running. Registering again just updates the
+// player's name and rating.
+func (s *Service) Register(id, playerID, name string, rating int) error {
+	if playerID == "" {
+		return fmt.Errorf("playerId is required")
+	}
+	t, err := s.tournament(id)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	lateEntry := t.Format == FormatArena && t.Status == StatusRunning
+	if t.Status != StatusRegistering && !lateEntry {
+		return fmt.Errorf("registration for tournament %s is closed", id)
+	}
+	if name == "" {
+		name = "Player"
+	}
+	if entrant := t.entrant(playerID); entrant != nil {
+		entrant.Name, entrant.Rating = name, rating
+		return nil
+	}
+	t.Entrants = append(t.Entrants, &Entrant{
+		Number: len(t.Entrants) + 1,
+		ID:     playerID,
+		Name:   name,
+		Rating: rating,
+	})
+	log.Printf("🏆 %s registered for tournament %s", playerID, id)
+	s.notifyUpdate(t.ID)
+	return nil
+}
+
+// Withdraw takes playerID out of a tournament. Before it starts they are
+// simply struck off; once it is running they forfeit the game they are
+// playing, are no longer paired, and lose their remaining round robin
+// games by forfeit.
+func (s *Service) Withdraw(id, playerID string) error {
+	t, err := s.tournament(id)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	entrant := t.entrant(playerID)
+	if entrant == nil || entrant.Withdrawn {
+		return fmt.Errorf("player %s is not playing in tournament %s", playerID, id)
+	}
+	switch t.Status {
+	case StatusFinished:
+		return fmt.Errorf("tournament %s is over", id)
+	case StatusRegistering:
+		t.unregister(entrant)
+		s.notifyUpdate(t.ID)
+		return nil
+	}
+
+	entrant.Withdrawn = true
+	log.Printf("🏆 %s withdrew from tournament %s", playerID, id)
+	for _, pairing := range t.inPlay() {
+		if pairing.Result == "" && pairing.GameID != "" && (pairing.White == entrant.Number || pairing.Black == entrant.Number) {
+			// The result comes back through the game over handler
+			if err := s.games.AbandonGame(pairing.GameID, playerID); err != nil {
+				log.Printf("Warning: Could not forfeit game %s: %v", pairing.GameID, err)
+			}
+		}
+	}
+	s.notifyUpdate(t.ID)
+	return nil
+}
+
+// unregister strikes entrant off and renumbers the players after them.
+// The caller must hold t.mutex.
+func (t *Tournament) unregister(entrant *Entrant) {
+	entrants := t.Entrants[:0]
+	for _, e := range t.Entrants {
+		if e != entrant {
+			e.Number = len(entrants) + 1
+			entrants = append(entrants, e)
+		}
+	}
+	t.Entrants = entrants
+}
+
+// Start closes registration and pairs the first round, or starts an
+// arena's clock. Only the organizer may start a tournament.
+func (s *Service) Start(id, playerID string) error {
+	t, err := s.tournament(id)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if playerID != t.OrganizerID {
+		return fmt.Errorf("only the organizer can start tournament %s", id)
+	}
+	if t.Status != StatusRegistering {
+		return fmt.Errorf("tournament %s has already started", id)
+	}
+	if len(t.Entrants) < 2 {
+		return fmt.Errorf("tournament %s needs at least 2 players", id)
+	}
+
+	if t.Format == FormatRoundRobin {
+		numbers := make([]int, len(t.Entrants))
+		for i, entrant := range t.Entrants {
+			numbers[i] = entrant.Number
+		}
+		t.schedule = roundRobinSchedule(numbers)
+		t.TotalRounds = len(t.schedule)
+	}
+	t.Status = StatusRunning
+	log.Printf("🏆 Tournament %s started with %d players", id, len(t.Entrants))
+	if t.Format == FormatArena {
+		t.EndsAt = time.Now().Add(t.Duration)
+		s.pairArena(t)
+		go s.runArena(t)
+	} else {
+		s.advance(t)
+	}
+	s.notifyUpdate(t.ID)
+	return nil
+}
+
+// Pairings returns the pairings of a round, counted from 1. Round zero is
+// the current round. An arena has no rounds; all its games are returned.
+func (s *Service) Pairings(id string, round int) ([]Pairing, error) {
+	t, err := s.tournament(id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.Format == FormatArena {
+		return append([]Pairing{}, t.ArenaGames...), nil
+	}
+	if round == 0 {
+		round = len(t.Rounds)
+	}
+	if round < 1 || round > len(t.Rounds) {
+		return nil, fmt.Errorf("tournament %s has no round %d yet", id, round)
+	}
+	return append([]Pairing{}, t.Rounds[round-1]...), nil
+}
+
+// recordResult scores a finished tournament game and moves the tournament
+// on once its round is complete. Arena players are re-paired by the arena
+// loop instead.
+func (s *Service) recordResult(result game.GameResult) {
+	s.mutex.Lock()
+	id, inTournament := s.boards[result.GameID]
+	delete(s.boards, result.GameID)
+	s.mutex.Unlock()
+	if !inTournament {
+		return
+	}
+	t, err := s.tournament(id)
+	if err != nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	pairings := t.inPlay()
+	for i := range pairings {
+		if pairings[i].GameID == result.GameID && pairings[i].Result == "" {
+			pairings[i].Result = resultOf(result.Winner)
+			log.Printf("🏆 Tournament %s, board %d: %s", id, pairings[i].Board, pairings[i].Result)
+		}
+	}
+	if t.Format != FormatArena {
+		s.advance(t)
+	}
+	s.notifyUpdate(t.ID)
+}
+
+func resultOf(winner string) Result {
+	switch winner {
+	case "white":
+		return ResultWhiteWins
+	case "black":
+		return ResultBlackWins
+	case game.WinnerDraw:
+		return ResultDraw
+	}
+	return ResultDoubleForfeit // Abandoned before anyone could win
+}
+
+// advance pairs the next round once the current one is complete, or ends
+// the tournament after its last round. The caller must hold t.mutex.
+func (s *Service) advance(t *Tournament) {
+	for t.Status == StatusRunning && t.roundComplete() {
+		if len(t.Rounds) == t.TotalRounds || len(t.active()) < 2 {
+			t.Status = StatusFinished
+			log.Printf("🏆 Tournament %s finished", t.ID)
+			return
+		}
+		s.startRound(t)
+	}
+}
+
+// startRound pairs the next round and creates its games. Boards that
+// need no game, byes and forfeits, are scored straight away. The caller
+// must hold t.mutex.
+func (s *Service) startRound(t *Tournament) {
+	var pairings []Pairing
+	if t.Format == FormatRoundRobin {
+		pairings = t.scheduledRound(len(t.Rounds))
+	} else {
+		pairings = pairSwiss(t.active(), t.records())
+	}
+
+	for i := range pairings {
+		pairing := &pairings[i]
+		pairing.WhiteName = t.byNumber(pairing.White).Name
+		if pairing.Black != 0 {
+			pairing.BlackName = t.byNumber(pairing.Black).Name
+		}
+		if pairing.Result != "" {
+			continue
+		}
+		gameID, err := s.createGame(t, pairing)
+		if err != nil {
+			log.Printf("Warning: Tournament %s could not set up board %d: %v", t.ID, pairing.Board, err)
+			pairing.Result = ResultDoubleForfeit
+			continue
+		}
+		pairing.GameID = gameID
+		s.notifyPaired(t, *pairing)
+	}
+	t.Rounds = append(t.Rounds, pairings)
+	log.Printf("🏆 Tournament %s: round %d paired", t.ID, len(t.Rounds))
+}
+
+// createGame sets up a pairing's game with the tournament's time control.
+// The caller must hold t.mutex.
+func (s *Service) createGame(t *Tournament, pairing *Pairing) (string, error) {
+	white, black := t.byNumber(pairing.White), t.byNumber(pairing.Black)
+	gameID := uuid.NewString()
+	if _, err := s.games.CreateGame(gameID, white.ID, game.HumanVsHuman, 0); err != nil {
+		return "", err
+	}
+	err := s.games.SetTournament(gameID, t.ID)
+	if err == nil {
+		err = s.seat(gameID, t.TimeControl, white, black)
+	}
+	if err != nil {
+		s.games.DeleteGame(gameID)
+		return "", err
+	}
+
+	s.mutex.Lock()
+	s.boards[gameID] = t.ID
+	s.mutex.Unlock()
+	return gameID, nil
+}
+
+// seat sets a new game's time control and seats its players, which starts
+// it.
+func (s *Service) seat(gameID string, tc *game.TimeControl, white, black *Entrant) error {
+	if err := s.games.SetTimeControl(gameID, *tc); err != nil {
+		return err
+	}
+	if err := s.games.JoinGame(gameID, white.ID, white.Name, chess.White); err != nil {
+		return err
+	}
+	return s.games.JoinGame(gameID, black.ID, black.Name, chess.Black)
+}
+
+// scheduledRound takes a round robin round from the schedule, scoring the
+// games of players who withdrew as forfeits. The caller must hold t.mutex.
+func (t *Tournament) scheduledRound(round int) []Pairing {
+	pairings := append([]Pairing{}, t.schedule[round]...)
+	for i := range pairings {
+		pairing := &pairings[i]
+		if pairing.Black == 0 {
+			pairing.Result = ResultBye
+			continue
+		}
+		whiteOut, blackOut := t.byNumber(pairing.White).Withdrawn, t.byNumber(pairing.Black).Withdrawn
+		switch {
+		case whiteOut && blackOut:
+			pairing.Result = ResultDoubleForfeit
+		case whiteOut:
+			pairing.Result = ResultBlackForfeit
+		case blackOut:
+			pairing.Result = ResultWhiteForfeit
+		}
+	}
+	return pairings
+}
+
+// inPlay returns the pairings that may still have a game being played:
+// the current round's, or all of an arena's. The caller must hold t.mutex.
+func (t *Tournament) inPlay() []Pairing {
+	if t.Format == FormatArena {
+		return t.ArenaGames
+	}
+	return t.currentRound()
+}
+
+// currentRound returns the pairings of the round being played. The caller
+// must hold t.mutex.
+func (t *Tournament) currentRound() []Pairing {
+	if len(t.Rounds) == 0 {
+		return nil
+	}
+	return t.Rounds[len(t.Rounds)-1]
+}
+
+// roundComplete reports whether every board of the current round has a
+// result. The caller must hold t.mutex.
+func (t *Tournament) roundComplete() bool {
+	for _, pairing := range t.currentRound() {
+		if pairing.Result == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// active returns the entrants still playing. The caller must hold t.mutex.
+func (t *Tournament) active() []*Entrant {
+	var active []*Entrant
+	for _, entrant := range t.Entrants {
+		if !entrant.Withdrawn {
+			active = append(active, entrant)
+		}
+	}
+	return active
+}
+
+// entrant finds playerID among the entrants. The caller must hold t.mutex.
+func (t *Tournament) entrant(playerID string) *Entrant {
+	for _, entrant := range t.Entrants {
+		if entrant.ID == playerID {
+			return entrant
+		}
+	}
+	return nil
+}
+
+// byNumber finds an entrant by number. The caller must hold t.mutex.
+func (t *Tournament) byNumber(number int) *Entrant {
+	return t.Entrants[number-1]
+}
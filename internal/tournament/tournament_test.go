@@ -0,0 +1,145 @@
+package tournament
+
+import (
+	"testing"
+	"time"
+
+	"github.com/corentings/chess/v2"
+	"github.com/hunterMotko/chess-game/internal/game"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTournament(t *testing.T, s *Service, req Request, players ...string) string {
+	t.Helper()
+	req.Name, req.OrganizerID = "Club night", "organizer"
+	summary, err := s.Create(req)
+	require.NoError(t, err)
+	for _, player := range players {
+		require.NoError(t, s.Register(summary.ID, player, player, 1500))
+	}
+	return summary.ID
+}
+
+// waitForRound waits for the result handler to pair the given round.
+func waitForRound(t *testing.T, s *Service, id string, round int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		summary, err := s.Get(id)
+		return err == nil && summary.CurrentRound >= round
+	}, time.Second, 5*time.Millisecond, "round %d was never paired", round)
+}
+
+func TestService_Create(t *testing.T) {
+	s := NewService(game.NewGameService(nil))
+
+	_, err := s.Create(Request{Name: "Club night", Format: FormatSwiss, Rounds: 0, OrganizerID: "organizer"})
+	assert.Error(t, err, "a Swiss needs rounds")
+	_, err = s.Create(Request{Name: "Club night", Format: "knockout", OrganizerID: "organizer"})
+	assert.Error(t, err)
+	_, err = s.Create(Request{Name: "Club night", Format: FormatRoundRobin})
+	assert.Error(t, err, "somebody has to organize it")
+	_, err = s.Create(Request{Name: "Club night", Format: FormatRoundRobin, OrganizerID: "organizer"})
+	assert.Error(t, err, "a board whose players never turn up would hold up the round")
+
+	tc := game.TimeControl{BaseMs: int64(10 * time.Minute / time.Millisecond)}
+	summary, err := s.Create(Request{Name: "Club night", Format: FormatRoundRobin, TimeControl: &tc, OrganizerID: "organizer"})
+	require.NoError(t, err)
+	assert.Equal(t, StatusRegistering, summary.Status)
+	assert.Error(t, s.Start(summary.ID, "organizer"), "nobody has registered")
+}
+
+func TestService_RoundRobin(t *testing.T) {
+	gs := game.NewGameService(nil)
+	s := NewService(gs)
+	tc := game.TimeControl{BaseMs: int64(10 * time.Minute / time.Millisecond)}
+	id := openTournament(t, s, Request{Format: FormatRoundRobin, TimeControl: &tc}, "alice", "bob", "carol")
+
+	assert.Error(t, s.Start(id, "alice"), "only the organizer starts it")
+	require.NoError(t, s.Start(id, "organizer"))
+	assert.Error(t, s.Register(id, "dave", "Dave", 1500), "registration is closed")
+
+	summary, err := s.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.TotalRounds)
+
+	for round := 1; round <= 3; round++ {
+		waitForRound(t, s, id, round)
+		pairings, err := s.Pairings(id, round)
+		require.NoError(t, err)
+		require.Len(t, pairings, 2, "one game and a bye")
+		assert.Equal(t, ResultBye, pairings[1].Result)
+
+		state, err := gs.GetGameState(pairings[0].GameID)
+		require.NoError(t, err)
+		assert.Equal(t, game.StatusInProgress, state.Status)
+		require.NotNil(t, state.Clock, "games get the tournament's time control")
+		board, _ := gs.GetGame(pairings[0].GameID)
+		assert.Equal(t, id, board.TournamentID, "games are marked so they aren't restored without it")
+
+		// Black resigns every game
+		require.NoError(t, gs.Resign(pairings[0].GameID, state.Players[chess.Black].ID))
+	}
+
+	require.Eventually(t, func() bool {
+		summary, err := s.Get(id)
+		return err == nil && summary.Status == StatusFinished
+	}, time.Second, 5*time.Millisecond)
+
+	standings, err := s.Standings(id)
+	require.NoError(t, err)
+	total := 0.0
+	for _, standing := range standings {
+		assert.Equal(t, 2, standing.Played)
+		total += standing.Points
+	}
+	assert.Equal(t, 3.0, total, "three games were won")
+}
+
+func TestService_WithdrawFromSwiss(t *testing.T) {
+	gs := game.NewGameService(nil)
+	s := NewService(gs)
+	tc := game.TimeControl{BaseMs: int64(10 * time.Minute / time.Millisecond)}
+	id := openTournament(t, s, Request{Format: FormatSwiss, Rounds: 2, TimeControl: &tc}, "alice", "bob", "carol", "dave", "erin")
+
+	// Withdrawing before the start strikes the player off
+	require.NoError(t, s.Withdraw(id, "erin"))
+	summary, err := s.Get(id)
+	require.NoError(t, err)
+	require.Len(t, summary.Entrants, 4)
+
+	require.NoError(t, s.Start(id, "organizer"))
+	pairings, err := s.Pairings(id, 1)
+	require.NoError(t, err)
+	require.Len(t, pairings, 2)
+
+	// Alice walks out mid-game and forfeits it
+	require.NoError(t, s.Withdraw(id, "alice"))
+	assert.Error(t, s.Withdraw(id, "alice"))
+	for _, pairing := range pairings {
+		if pairing.WhiteName == "alice" || pairing.BlackName == "alice" {
+			continue
+		}
+		state, err := gs.GetGameState(pairing.GameID)
+		require.NoError(t, err)
+		require.NoError(t, gs.Resign(pairing.GameID, state.Players[chess.White].ID))
+	}
+
+	waitForRound(t, s, id, 2)
+	pairings, err = s.Pairings(id, 0)
+	require.NoError(t, err)
+	require.Len(t, pairings, 2, "three players left: one game and a bye")
+	for _, pairing := range pairings {
+		assert.NotEqual(t, "alice", pairing.WhiteName)
+		assert.NotEqual(t, "alice", pairing.BlackName)
+	}
+
+	standings, err := s.Standings(id)
+	require.NoError(t, err)
+	for _, standing := range standings {
+		if standing.Name == "alice" {
+			assert.True(t, standing.Withdrawn)
+			assert.Equal(t, 0.0, standing.Points)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package websockets
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hunterMotko/chess-game/internal/game"
+)
+
+const (
+	// defaultAIMatchPace is the pause between moves of an AI vs AI match
+	defaultAIMatchPace = time.Second
+	minAIMatchPace     = 100 * time.Millisecond
+)
+
+// NewAIMatchHandler creates an engine vs engine game on the client's gameId
+// and starts the server-side move loop. Everyone connected to the game,
+// including spectators, watches the moves as they are broadcast.
+func (m *Manager) NewAIMatchHandler(e Event, c *Client) error {
+	var matchData struct {
+		White          game.AISettings   `json:"white"`
+		Black          game.AISettings   `json:"black"`
+		PaceMs         int64             `json:"paceMs"`         // Pause between moves
+		SpectatorDelay int               `json:"spectatorDelay"` // Seconds spectators lag behind
+		TimeControl    *game.TimeControl `json:"timeControl"`
+		FEN            string            `json:"fen"` // Position to start from
+		PGN            string            `json:"pgn"` // Game to continue from
+		// Variant to play; Chess960 matches start from Chess960Position, or
+		// a random position when it is left out
+		Variant          string `json:"variant"`
+		Chess960Position *int   `json:"chess960Position"`
+	}
+	if err := json.Unmarshal(e.Payload, &matchData); err != nil {
+		return fmt.Errorf("invalid AI match data: %v", err)
+	}
+
+	pace := defaultAIMatchPace
+	if matchData.PaceMs > 0 {
+		pace = max(time.Duration(matchData.PaceMs)*time.Millisecond, minAIMatchPace)
+	}
+
+	log.Printf("Starting AI match %s (white level %d, black level %d, pace %s)",
+		c.currentGameId(), matchData.White.Difficulty, matchData.Black.Difficulty, pace)
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping AI match creation (test environment)")
+		return nil
+	}
+
+	if err := m.claimGameId(c); err != nil {
+		return fmt.Errorf("failed to create AI match: %v", err)
+	}
+
+	if _, err := m.gameService.CreateAIMatch(c.currentGameId(), c.clientId, matchData.White, matchData.Black, pace); err != nil {
+		return fmt.Errorf("failed to create AI match: %v", err)
+	}
+
+	if err := m.setVariant(c.currentGameId(), matchData.Variant, matchData.Chess960Position); err != nil {
+		return fmt.Errorf("invalid variant: %v", err)
+	}
+
+	if err := m.gameService.SetStartPosition(c.currentGameId(), matchData.FEN, matchData.PGN); err != nil {
+		return fmt.Errorf("invalid starting position: %v", err)
+	}
+
+	if err := m.gameService.SetSpectatorDelay(c.currentGameId(), time.Duration(matchData.SpectatorDelay)*time.Second); err != nil {
+		return fmt.Errorf("failed to set spectator delay: %v", err)
+	}
+
+	if matchData.TimeControl != nil {
+		if err := m.gameService.SetTimeControl(c.currentGameId(), *matchData.TimeControl); err != nil {
+			return fmt.Errorf("invalid time control: %v", err)
+		}
+	}
+
+	if err := m.gameService.StartGame(c.currentGameId()); err != nil {
+		return fmt.Errorf("failed to start AI match: %v", err)
+	}
+
+	m.broadcastGameState(c.currentGameId())
+	go m.runAIMatch(c.currentGameId(), pace)
+	return nil
+}
+
+// runAIMatch plays an AI vs AI game to the end, one move every pace. It
+// stops early if the game is replaced or deleted.
+func (m *Manager) runAIMatch(gameId string, pace time.Duration) {
+	match, exists := m.gameService.GetGame(gameId)
+	if !exists {
+		return
+	}
+
+	for {
+		time.Sleep(pace)
+
+		if current, exists := m.gameService.GetGame(gameId); !exists || current != match {
+			log.Printf("AI match %s was replaced, stopping its move loop", gameId)
+			return
+		}
+
+		result, err := m.playAIMove(gameId)
+		if err != nil {
+			log.Printf("AI match %s stopped: %v", gameId, err)
+			return
+		}
+		if result.GameStatus != game.StatusInProgress {
+			log.Printf("🏁 AI match %s finished", gameId)
+			return
+		}
+	}
+}
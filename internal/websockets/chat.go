@@ -0,0 +1,306 @@
+package websockets
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// chatHistorySize bounds the messages kept per game and channel
+	chatHistorySize = 100
+	// maxChatLength caps a message, in characters
+	maxChatLength = 500
+	// chatBurst messages may be sent back to back; after that a client
+	// earns another every chatRefill
+	chatBurst  = 5
+	chatRefill = 3 * time.Second
+)
+
+// ChatChannel separates the players' chat from the spectators'. Each side
+// only sees its own channel.
+type ChatChannel string
+
+const (
+	ChatPlayers    ChatChannel = "players"
+	ChatSpectators ChatChannel = "spectators"
+)
+
+// ChatMessage is a message sent in a game's chat.
+type ChatMessage struct {
+	ID      uint64      `json:"id"`
+	Channel ChatChannel `json:"channel"`
+	From    string      `json:"from"`            // Sender's display name
+	Color   string      `json:"color,omitempty"` // Sender's seat, for players
+	Text    string      `json:"text"`
+	SentAt  time.Time   `json:"sentAt"`
+	// GameID and ClientID identify the sender to filters; client IDs bind
+	// connections to seats, so they are never sent out
+	GameID   string `json:"-"`
+	ClientID string `json:"-"`
+}
+
+// ChatFilter screens chat messages before they are sent and stored, e.g. to
+// mask words or silence a muted player. It may rewrite msg.Text; an error
+// rejects the message and is shown to the sender.
+type ChatFilter interface {
+	FilterChat(msg *ChatMessage) error
+}
+
+// chatLog keeps a game's recent messages for late joiners.
+type chatLog struct {
+	seq      uint64
+	messages []ChatMessage
+}
+
+// chatLimiter is a token bucket limiting how fast one connection chats.
+type chatLimiter struct {
+	tokens    float64
+	checkedAt time.Time
+}
+
+// allow takes a token if one is left at now.
+func (l *chatLimiter) allow(now time.Time) bool {
+	if l.checkedAt.IsZero() {
+		l.tokens = chatBurst
+	} else {
+		l.tokens += float64(now.Sub(l.checkedAt)) / float64(chatRefill)
+		if l.tokens > chatBurst {
+			l.tokens = chatBurst
+		}
+	}
+	l.checkedAt = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// AddChatFilter screens every chat message through f, after the filters
+// added before it.
+func (m *Manager) AddChatFilter(f ChatFilter) {
+	m.chatMutex.Lock()
+	defer m.chatMutex.Unlock()
+	m.chatFilters = append(m.chatFilters, f)
+}
+
+// ChatHandler sends a chat message to the sender's side of the game: the
+// players, or the spectators.
+func (m *Manager) ChatHandler(e Event, c *Client) error {
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid chat payload: %v", err)
+	}
+
+	msg := ChatMessage{
+		Channel:  m.chatChannelOf(c),
+		From:     c.userName,
+		Text:     strings.TrimSpace(payload.Text),
+		SentAt:   time.Now(),
+		GameID:   c.currentGameId(),
+		ClientID: c.clientId,
+	}
+	if msg.Channel == ChatPlayers && m.gameService != nil {
+		if color, seated := m.gameService.PlayerColor(c.currentGameId(), c.clientId); seated {
+			msg.Color = strings.ToLower(color.Name())
+		}
+	}
+
+	if err := m.screenChat(c, &msg); err != nil {
+		log.Printf("💬 Chat from %s in game %s rejected: %v", c.clientId, c.currentGameId(), err)
+		payloadBytes, _ := json.Marshal(map[string]string{
+			"reason": err.Error(),
+		})
+		m.deliver(c, Event{
+			Type:    ChatRejected,
+			Payload: json.RawMessage(payloadBytes),
+		}, 0)
+		return err
+	}
+
+	m.recordChat(&msg)
+	payloadBytes, _ := json.Marshal(msg)
+	event := Event{
+		Type:    Chat,
+		Payload: json.RawMessage(payloadBytes),
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+	for client := range m.clients {
+		if client.currentGameId() == msg.GameID && m.chatChannelOf(client) == msg.Channel {
+			m.deliver(client, event, 0)
+		}
+	}
+	return nil
+}
+
+// screenChat checks a message against the rate limit and the chat filters.
+func (m *Manager) screenChat(c *Client, msg *ChatMessage) error {
+	if msg.Text == "" {
+		return fmt.Errorf("message is empty")
+	}
+	if len([]rune(msg.Text)) > maxChatLength {
+		return fmt.Errorf("message is longer than %d characters", maxChatLength)
+	}
+	if !c.chatLimit.allow(msg.SentAt) {
+		return fmt.Errorf("sending messages too fast")
+	}
+
+	m.chatMutex.Lock()
+	filters := append([]ChatFilter{}, m.chatFilters...)
+	m.chatMutex.Unlock()
+	for _, filter := range filters {
+		if err := filter.FilterChat(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordChat numbers a message and adds it to its game's history.
+func (m *Manager) recordChat(msg *ChatMessage) {
+	m.chatMutex.Lock()
+	defer m.chatMutex.Unlock()
+
+	if m.chatLogs == nil {
+		m.chatLogs = make(map[string]*chatLog)
+	}
+	gameChat, ok := m.chatLogs[msg.GameID]
+	if !ok {
+		gameChat = &chatLog{}
+		m.chatLogs[msg.GameID] = gameChat
+	}
+
+	gameChat.seq++
+	msg.ID = gameChat.seq
+	gameChat.messages = append(gameChat.messages, *msg)
+
+	// Each channel keeps its own chatHistorySize messages, so a busy
+	// spectator channel can't push the players' messages out
+	inChannel := 0
+	for _, logged := range gameChat.messages {
+		if logged.Channel == msg.Channel {
+			inChannel++
+		}
+	}
+	if inChannel > chatHistorySize {
+		for i, logged := range gameChat.messages {
+			if logged.Channel == msg.Channel {
+				gameChat.messages = append(gameChat.messages[:i], gameChat.messages[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// sendChatHistory sends a joining client their channel's messages so far.
+func (m *Manager) sendChatHistory(c *Client) {
+	channel := m.chatChannelOf(c)
+
+	m.chatMutex.Lock()
+	messages := []ChatMessage{}
+	if gameChat, ok := m.chatLogs[c.currentGameId()]; ok {
+		for _, msg := range gameChat.messages {
+			if msg.Channel == channel {
+				messages = append(messages, msg)
+			}
+		}
+	}
+	m.chatMutex.Unlock()
+
+	payloadBytes, _ := json.Marshal(map[string]interface{}{
+		"channel":  channel,
+		"messages": messages,
+	})
+	m.deliver(c, Event{
+		Type:    ChatHistory,
+		Payload: json.RawMessage(payloadBytes),
+	}, 0)
+}
+
+// chatChannelOf is the channel a client talks and listens in: the players'
+// for those seated in the game, the spectators' for everyone else.
+func (m *Manager) chatChannelOf(c *Client) ChatChannel {
+	if m.holdsSeat(c) {
+		return ChatPlayers
+	}
+	return ChatSpectators
+}
+
+// WordFilter masks blocked words in chat messages with asterisks. Words
+// match whole and case-insensitively.
+type WordFilter struct {
+	pattern *regexp.Regexp
+}
+
+// NewWordFilter returns a filter masking words. It returns nil when there
+// are no words to block.
+func NewWordFilter(words []string) *WordFilter {
+	quoted := []string{}
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	return &WordFilter{
+		pattern: regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`),
+	}
+}
+
+func (f *WordFilter) FilterChat(msg *ChatMessage) error {
+	msg.Text = f.pattern.ReplaceAllStringFunc(msg.Text, func(word string) string {
+		return strings.Repeat("*", len([]rune(word)))
+	})
+	return nil
+}
+
+// MuteList silences clients, in one game or in all of them.
+type MuteList struct {
+	mu    sync.RWMutex
+	muted map[string]bool // clientId, or gameId/clientId
+}
+
+func NewMuteList() *MuteList {
+	return &MuteList{muted: make(map[string]bool)}
+}
+
+// Mute silences clientId in gameId, or everywhere when gameId is empty.
+func (l *MuteList) Mute(gameId, clientId string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.muted[muteKey(gameId, clientId)] = true
+}
+
+// Unmute lifts a mute set with the same arguments.
+func (l *MuteList) Unmute(gameId, clientId string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.muted, muteKey(gameId, clientId))
+}
+
+func (l *MuteList) FilterChat(msg *ChatMessage) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.muted[msg.ClientID] || l.muted[sessionKey(msg.GameID, msg.ClientID)] {
+		return fmt.Errorf("you have been muted")
+	}
+	return nil
+}
+
+func muteKey(gameId, clientId string) string {
+	if gameId == "" {
+		return clientId
+	}
+	return sessionKey(gameId, clientId)
+}
@@ -0,0 +1,107 @@
+package websockets
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chatEvent(text string) Event {
+	payload, _ := json.Marshal(map[string]string{"text": text})
+	return Event{Type: Chat, Payload: json.RawMessage(payload)}
+}
+
+func TestManager_ChatHandler_Channels(t *testing.T) {
+	manager := createSessionTestManager(t)
+	manager.setupHandlers()
+	white := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "white-client", userName: "Alice", clientType: ClientTypePlayer}
+	black := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "black-client", userName: "Bob", clientType: ClientTypePlayer}
+	spectator := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "spectator", userName: "Carol", clientType: ClientTypeSpectator}
+	manager.addClient(white)
+	manager.addClient(black)
+	manager.addClient(spectator)
+
+	require.NoError(t, manager.routeEvent(chatEvent(" good luck "), white))
+	require.Len(t, black.egress, 1)
+	assert.Empty(t, spectator.egress, "spectators don't see the players' chat")
+
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal((<-black.egress).Payload, &msg))
+	assert.Equal(t, "good luck", msg["text"])
+	assert.Equal(t, "Alice", msg["from"])
+	assert.Equal(t, "white", msg["color"])
+	assert.Equal(t, "players", msg["channel"])
+	assert.NotContains(t, msg, "clientId")
+
+	require.NoError(t, manager.routeEvent(chatEvent("nice opening"), spectator))
+	assert.Equal(t, Chat, (<-spectator.egress).Type)
+	assert.Len(t, white.egress, 1, "only white's own message")
+
+	// A late spectator sees the spectators' history only
+	late := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "late", clientType: ClientTypeSpectator}
+	manager.sendChatHistory(late)
+	history := <-late.egress
+	assert.Equal(t, ChatHistory, history.Type)
+	assert.Contains(t, string(history.Payload), "nice opening")
+	assert.NotContains(t, string(history.Payload), "good luck")
+
+	// Leaving out type=spectator doesn't get an unseated client into the
+	// players' chat
+	lurker := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "lurker", clientType: ClientTypePlayer}
+	manager.addClient(lurker)
+	require.NoError(t, manager.routeEvent(chatEvent("psst"), lurker))
+	assert.Contains(t, string((<-spectator.egress).Payload), "psst")
+	assert.Len(t, white.egress, 1, "the players don't hear it")
+	<-lurker.egress
+	manager.sendChatHistory(lurker)
+	assert.NotContains(t, string((<-lurker.egress).Payload), "good luck")
+}
+
+func TestManager_ChatHandler_RateLimit(t *testing.T) {
+	manager := createTestManager()
+	c := &Client{egress: make(chan Event, 20), gameId: "chat-game", clientId: "chatty", clientType: ClientTypePlayer}
+	manager.addClient(c)
+
+	for i := 0; i < chatBurst; i++ {
+		require.NoError(t, manager.ChatHandler(chatEvent("hi"), c))
+	}
+	assert.Error(t, manager.ChatHandler(chatEvent("hi"), c))
+
+	var rejected Event
+	for len(c.egress) > 0 {
+		rejected = <-c.egress
+	}
+	assert.Equal(t, ChatRejected, rejected.Type)
+
+	// A token comes back after chatRefill
+	c.chatLimit.checkedAt = c.chatLimit.checkedAt.Add(-chatRefill)
+	assert.NoError(t, manager.ChatHandler(chatEvent("hi"), c))
+	assert.Error(t, manager.ChatHandler(chatEvent(""), c), "empty messages are rejected")
+}
+
+func TestManager_ChatFilters(t *testing.T) {
+	manager := createTestManager()
+	mutes := NewMuteList()
+	manager.AddChatFilter(NewWordFilter([]string{"darn", " heck "}))
+	manager.AddChatFilter(mutes)
+
+	c := &Client{egress: make(chan Event, 10), gameId: "chat-game", clientId: "student", clientType: ClientTypePlayer}
+	manager.addClient(c)
+
+	require.NoError(t, manager.ChatHandler(chatEvent("Darn, what the heck? darnation"), c))
+	var msg ChatMessage
+	require.NoError(t, json.Unmarshal((<-c.egress).Payload, &msg))
+	assert.Equal(t, "****, what the ****? darnation", msg.Text)
+
+	mutes.Mute("chat-game", "student")
+	assert.Error(t, manager.ChatHandler(chatEvent("hello"), c))
+	assert.Equal(t, ChatRejected, (<-c.egress).Type)
+
+	mutes.Unmute("chat-game", "student")
+	c.chatLimit.checkedAt = time.Now().Add(-time.Minute)
+	assert.NoError(t, manager.ChatHandler(chatEvent("hello"), c))
+	assert.Nil(t, NewWordFilter([]string{"", " "}))
+}
@@ -3,6 +3,8 @@ package websockets
 import (
 	"encoding/json"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/corentings/chess/v2"
 	"github.com/gorilla/websocket"
@@ -17,16 +19,31 @@ const (
 	ClientTypeSpectator ClientType = "spectator"
 )
 
+// delayedEvent is an event held back from a spectator until due.
+type delayedEvent struct {
+	event Event
+	due   time.Time
+}
+
 type Client struct {
 	conn    *websocket.Conn
 	manager *Manager
 	// egress is used to avoid concurrent writes on the websocket conn
-	egress     chan Event
+	egress chan Event
+	// delayed holds spectator events until the game's broadcast delay passes
+	delayed    chan delayedEvent
+	done       chan struct{}
 	gameState  *chess.Game
 	gameId     string
+	gameMutex  sync.RWMutex // guards gameId, which moveClients changes
 	clientId   string
 	userName   string
 	clientType ClientType
+	// chatLimit rate-limits this connection's chat messages
+	chatLimit chatLimiter
+	// tournamentId is set on connections following a tournament rather
+	// than playing or watching a game
+	tournamentId string
 }
 
 func NewClient(
@@ -40,6 +57,8 @@ func NewClient(
 		conn:       conn,
 		manager:    manager,
 		egress:     make(chan Event, 16), // Increased buffer for better performance
+		delayed:    make(chan delayedEvent, 64),
+		done:       make(chan struct{}),
 		gameState:  &chess.Game{},
 		gameId:     gameId,
 		clientId:   clientId,
@@ -48,6 +67,20 @@ func NewClient(
 	}
 }
 
+// currentGameId returns the game the client is playing or watching.
+func (c *Client) currentGameId() string {
+	c.gameMutex.RLock()
+	defer c.gameMutex.RUnlock()
+	return c.gameId
+}
+
+// setGameId moves the client to another game.
+func (c *Client) setGameId(gameId string) {
+	c.gameMutex.Lock()
+	defer c.gameMutex.Unlock()
+	c.gameId = gameId
+}
+
 func (c *Client) readMessages() {
 	defer func() {
 		// cleanup connection
@@ -110,3 +143,27 @@ func (c *Client) writeMessages() {
 		}
 	}
 }
+
+// relayDelayed forwards spectator events to egress once they are due.
+// Every event in a game is held for the same delay, so FIFO order is kept.
+func (c *Client) relayDelayed() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case d := <-c.delayed:
+			if wait := time.Until(d.due); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-c.done:
+					return
+				}
+			}
+			select {
+			case c.egress <- d.event:
+			default:
+				log.Printf("❌ Could not send delayed event to client %s, channel full", c.clientId)
+			}
+		}
+	}
+}
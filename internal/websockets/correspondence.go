@@ -0,0 +1,88 @@
+package websockets
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/corentings/chess/v2"
+	"github.com/hunterMotko/chess-game/internal/game"
+)
+
+// CorrespondenceMove plays a move sent over HTTP in a correspondence game,
+// by whoever holds the seat token, for players who aren't connected, and
+// tells whoever is.
+func (m *Manager) CorrespondenceMove(ctx context.Context, gameId, token, moveStr string) (*game.MoveResult, error) {
+	result, err := m.gameService.MakeCorrespondenceMove(ctx, gameId, token, moveStr)
+	if err != nil {
+		m.releaseGame(gameId)
+		return nil, err
+	}
+
+	log.Printf("✉️ Correspondence move %s played in game %s", result.Move, gameId)
+	m.broadcastGameState(gameId)
+	m.broadcastGameOver(gameId)
+	m.releaseGame(gameId)
+	return result, nil
+}
+
+// OpenGame opens a game in the lobby. Nobody is connected to it yet, so a
+// correspondence game goes straight back to the database.
+func (m *Manager) OpenGame(req game.LobbyRequest) (*game.LobbyGame, error) {
+	listed, err := m.gameService.OpenGame(req)
+	if err != nil {
+		return nil, err
+	}
+	m.releaseGame(listed.ID)
+	return listed, nil
+}
+
+// JoinOpenGame seats a player in a game found in the lobby over HTTP, then
+// hands a correspondence game back to the database unless someone is
+// connected to it.
+func (m *Manager) JoinOpenGame(gameId, playerId, playerName string, color chess.Color) (*game.LobbyGame, error) {
+	defer m.releaseGame(gameId)
+	return m.gameService.JoinOpenGame(gameId, playerId, playerName, color)
+}
+
+// sendSeatToken hands a client seated in a correspondence game its seat
+// token, unless the seat was already issued one.
+func (m *Manager) sendSeatToken(c *Client) {
+	if m.gameService == nil || !m.holdsSeat(c) {
+		return
+	}
+	token, err := m.gameService.IssueSeatToken(c.currentGameId(), c.clientId)
+	if err != nil || token == "" {
+		return
+	}
+
+	payloadBytes, _ := json.Marshal(map[string]string{
+		"gameId": c.currentGameId(),
+		"token":  token,
+	})
+	m.deliver(c, Event{
+		Type:    SeatToken,
+		Payload: json.RawMessage(payloadBytes),
+	}, 0)
+}
+
+// releaseGame hands a correspondence game back to the database once no
+// client is connected to it.
+func (m *Manager) releaseGame(gameId string) {
+	if m.gameService == nil || m.gameHasClients(gameId) {
+		return
+	}
+	m.gameService.ReleaseCorrespondenceGame(gameId)
+}
+
+func (m *Manager) gameHasClients(gameId string) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	for client := range m.clients {
+		if client.currentGameId() == gameId {
+			return true
+		}
+	}
+	return false
+}
@@ -5,13 +5,77 @@ import "encoding/json"
 const (
 	NewAIGame = "new_ai_game"
 	NewGame   = "new_game"
+	JoinGame  = "join_game"
 	Move      = "move"
 	GameState = "game_state"
 	AIMove    = "ai_move"
 	LoadPGN   = "load_pgn"
 	GameOver  = "game_over"
+
+	Resign      = "resign"
+	OfferDraw   = "offer_draw"
+	AcceptDraw  = "accept_draw"
+	DeclineDraw = "decline_draw"
+	ClaimDraw   = "claim_draw"
+
+	TakebackRequest  = "takeback_request"
+	TakebackResponse = "takeback_response"
+
+	// MoveRejected answers an invalid move with the reason and the legal
+	// moves the player may have meant
+	MoveRejected = "move_rejected"
+
+	Premove       = "premove"
+	CancelPremove = "cancel_premove"
+
+	RematchOffer  = "rematch_offer"
+	RematchAccept = "rematch_accept"
+
+	AdjournRequest = "adjourn_request"
+	ResumeRequest  = "resume_request"
+
+	PlayerDisconnected = "player_disconnected"
+	PlayerReconnected  = "player_reconnected"
+
+	NewAIMatch = "new_ai_match"
+
+	Chat         = "chat"
+	ChatHistory  = "chat_history"
+	ChatRejected = "chat_rejected"
+
+	// GameCreated moves a connection whose game had finished to the new
+	// game it set up
+	GameCreated = "game_created"
+
+	// SeatToken hands a correspondence player the secret they move over
+	// HTTP with, once
+	SeatToken = "seat_token"
+)
+
+const (
+	// TournamentUpdate carries a tournament's live standings to the
+	// connections following it
+	TournamentUpdate = "tournament_update"
+	// TournamentPaired tells a tournament player which game to join
+	TournamentPaired = "tournament_paired"
 )
 
+// spectatorEvents are the only events a spectator connection may send.
+// Everything else mutates the game and is rejected in routeEvent.
+var spectatorEvents = map[string]bool{
+	GameState: true,
+	Chat:      true,
+}
+
+// setupEvents set up a game or take a seat in one, so a player connection
+// may send them before it holds a seat.
+var setupEvents = map[string]bool{
+	NewGame:    true,
+	NewAIGame:  true,
+	NewAIMatch: true,
+	JoinGame:   true,
+}
+
 // TODO: Add enum types for each expected event type
 type Event struct {
 	Type    string          `json:"type"`
@@ -3,9 +3,11 @@ package websockets
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/hunterMotko/chess-game/internal/database"
 	"github.com/hunterMotko/chess-game/internal/game"
+	"github.com/hunterMotko/chess-game/internal/tournament"
 	"github.com/labstack/echo/v4"
 )
 
@@ -58,6 +61,20 @@ type Manager struct {
 	gameService *game.GameService
 	sync.RWMutex
 	handlers map[string]EventHandler
+
+	// Reconnect sessions, see session.go
+	sessionMutex   sync.Mutex
+	eventLogs      map[string]*eventLog         // gameId -> recent broadcasts
+	pending        map[string]*pendingReconnect // gameId/clientId -> grace window
+	reconnectGrace time.Duration
+
+	// Chat, see chat.go
+	chatMutex   sync.Mutex
+	chatLogs    map[string]*chatLog // gameId -> recent messages
+	chatFilters []ChatFilter
+
+	// Tournaments, see tournament.go
+	tournaments *tournament.Service
 }
 
 func NewManager(ctx context.Context, db *database.Service) *Manager {
@@ -67,19 +84,113 @@ func NewManager(ctx context.Context, db *database.Service) *Manager {
 		handlers:    make(map[string]EventHandler),
 	}
 	m.setupHandlers()
+	m.gameService.SetStateChangeHandler(m.onGameUpdated)
+	m.tournaments = tournament.NewService(m.gameService)
+	m.tournaments.SetUpdateHandler(m.onTournamentUpdated)
+	m.tournaments.SetPairingHandler(m.onTournamentPaired)
+	m.tournaments.SetPresenceCheck(m.followsTournament)
+
+	restored, err := m.gameService.RestoreGames(ctx)
+	if err != nil {
+		log.Printf("Warning: Could not restore games: %v", err)
+	}
+	for _, gameId := range restored {
+		if restoredGame, exists := m.gameService.GetGame(gameId); exists && restoredGame.Type == game.AIVsAI {
+			pace := m.gameService.AIMatchPace(gameId)
+			if pace == 0 {
+				pace = defaultAIMatchPace
+			}
+			go m.runAIMatch(gameId, pace)
+			continue
+		}
+		go m.triggerAIResponseIfNeeded(gameId)
+	}
 	return m
 }
 
+// StartSweeper abandons idle games, evicts finished ones and ends the
+// correspondence games past their move deadline in the background until ctx
+// is done. Players in an abandoned or expired game hear about it through
+// the state change handler.
+func (m *Manager) StartSweeper(ctx context.Context, cfg game.SweepConfig) {
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				_, evicted := m.gameService.Sweep(now, cfg)
+				m.forgetGames(evicted)
+				for _, gameId := range m.gameService.ExpireCorrespondence(ctx, now) {
+					m.releaseGame(gameId)
+				}
+			}
+		}
+	}()
+}
+
+// forgetGames drops the event logs and chat of games evicted from memory.
+func (m *Manager) forgetGames(gameIds []string) {
+	m.sessionMutex.Lock()
+	for _, gameId := range gameIds {
+		delete(m.eventLogs, gameId)
+	}
+	m.sessionMutex.Unlock()
+
+	m.chatMutex.Lock()
+	for _, gameId := range gameIds {
+		delete(m.chatLogs, gameId)
+	}
+	m.chatMutex.Unlock()
+}
+
+// GameService is the service the manager plays its games through, for the
+// HTTP routes that work on the same games.
+func (m *Manager) GameService() *game.GameService {
+	return m.gameService
+}
+
 func (m *Manager) setupHandlers() {
 	// Core chess game handlers only
 	m.handlers[NewAIGame] = m.NewAIGameHandler
+	m.handlers[NewGame] = m.NewGameHandler
+	m.handlers[JoinGame] = m.JoinGameHandler
 	m.handlers[Move] = m.MoveHandler
 	m.handlers[AIMove] = m.AIMoveHandler
 	m.handlers[GameOver] = m.GameOverHandler
+	m.handlers[GameState] = m.GameStateHandler
+	m.handlers[Resign] = m.ResignHandler
+	m.handlers[OfferDraw] = m.OfferDrawHandler
+	m.handlers[AcceptDraw] = m.AcceptDrawHandler
+	m.handlers[DeclineDraw] = m.DeclineDrawHandler
+	m.handlers[ClaimDraw] = m.ClaimDrawHandler
+	m.handlers[TakebackRequest] = m.TakebackRequestHandler
+	m.handlers[TakebackResponse] = m.TakebackResponseHandler
+	m.handlers[NewAIMatch] = m.NewAIMatchHandler
+	m.handlers[LoadPGN] = m.LoadPGNHandler
+	m.handlers[Premove] = m.PremoveHandler
+	m.handlers[CancelPremove] = m.CancelPremoveHandler
+	m.handlers[RematchOffer] = m.RematchOfferHandler
+	m.handlers[RematchAccept] = m.RematchAcceptHandler
+	m.handlers[Chat] = m.ChatHandler
+	m.handlers[AdjournRequest] = m.AdjournRequestHandler
+	m.handlers[ResumeRequest] = m.ResumeRequestHandler
 }
 
 func (m *Manager) routeEvent(e Event, c *Client) error {
 	log.Printf("Routing event: %s from client %s", e.Type, c.clientId)
+	if c.tournamentId != "" {
+		log.Printf("Rejected %s event from tournament follower %s", e.Type, c.clientId)
+		return fmt.Errorf("Event ERROR: tournament connections cannot send events")
+	}
+	// Whoever holds no seat is a spectator, however they connected, though
+	// a player connection may still set up a game or take a seat
+	if !spectatorEvents[e.Type] && !m.holdsSeat(c) && (c.clientType == ClientTypeSpectator || !setupEvents[e.Type]) {
+		log.Printf("Rejected %s event from spectator %s", e.Type, c.clientId)
+		return fmt.Errorf("Event ERROR: spectators cannot send %s events", e.Type)
+	}
 	if handler, ok := m.handlers[e.Type]; ok {
 		if err := handler(e, c); err != nil {
 			log.Printf("Handler error for %s: %v", e.Type, err)
@@ -92,89 +203,258 @@ func (m *Manager) routeEvent(e Event, c *Client) error {
 	return fmt.Errorf("Event ERROR: Event or handler unknown")
 }
 
-func NewGameHandler(e Event, c *Client) error {
-	log.Printf("Starting new game for client %s", c.gameId)
-	c.gameState = chess.NewGame()
+// seatRequest is the payload shared by new_game and join_game.
+type seatRequest struct {
+	PlayerName     string `json:"playerName"`
+	PlayerColor    string `json:"playerColor"`    // "white", "black" or empty for any free seat
+	SpectatorDelay int    `json:"spectatorDelay"` // Seconds spectators lag behind, new_game only
+	// TimeControl puts a clock on the game, new_game only
+	TimeControl *game.TimeControl `json:"timeControl"`
+	// FEN or PGN to start the game from, new_game only
+	FEN string `json:"fen"`
+	PGN string `json:"pgn"`
+	// Variant to play, e.g. "chess960", new_game only. Chess960 games start
+	// from Chess960Position, 0-959, or a random one when it is left out.
+	Variant          string `json:"variant"`
+	Chess960Position *int   `json:"chess960Position"`
+}
 
-	// Broadcast initial game state
-	gameStatePayload := map[string]interface{}{
-		"position":    c.gameState.Position().String(),
-		"fen":         c.gameState.FEN(),
-		"turn":        c.gameState.Position().Turn().String(),
-		"isCheck":     false,
-		"isCheckmate": false,
-		"isStalemate": false,
-		"lastMove":    nil,
+// setVariant sets up a new game for the variant named in its request.
+func (m *Manager) setVariant(gameId, name string, chess960Position *int) error {
+	variant, err := game.ParseVariant(name)
+	if err != nil {
+		return err
 	}
+	if variant == game.VariantStandard {
+		return nil
+	}
+	return m.gameService.SetVariant(gameId, variant, chess960Position)
+}
 
-	payloadBytes, _ := json.Marshal(gameStatePayload)
-	gameStateEvent := Event{
-		Type:    "game_state",
-		Payload: json.RawMessage(payloadBytes),
+// claimGameId makes sure the client may set up a new game on its
+// connection. The owner of an unfinished game restarts it in place and
+// nobody else may touch it. A finished game is kept for the record: the
+// client moves to a fresh game ID and is told so in a game_created event.
+func (m *Manager) claimGameId(c *Client) error {
+	gameId, err := m.gameService.NewGameID(c.currentGameId(), c.clientId)
+	if err != nil {
+		return err
+	}
+	if gameId == c.currentGameId() {
+		return nil
 	}
 
-	c.manager.broadcastToGame(c.gameId, gameStateEvent)
+	m.moveClients([]*Client{c}, gameId)
 	return nil
 }
 
-func (m *Manager) MoveHandler(e Event, c *Client) error {
-	// Parse move from payload (support both old and new format)
-	var moveData struct {
-		From string `json:"from"`
-		To   string `json:"to"`
-		Move string `json:"move"` // UCI format
+// moveClients moves connections over to another game and tells each of
+// them in a game_created event.
+func (m *Manager) moveClients(clients []*Client, gameId string) {
+	m.Lock()
+	previousGameIds := make([]string, len(clients))
+	for i, client := range clients {
+		previousGameIds[i] = client.currentGameId()
+		client.setGameId(gameId)
 	}
+	m.Unlock()
 
-	if err := json.Unmarshal(e.Payload, &moveData); err != nil {
-		log.Printf("Error parsing move data: %v", err)
-		return fmt.Errorf("invalid move format: %v", err)
+	for i, client := range clients {
+		log.Printf("Client %s moved from game %s to game %s", client.clientId, previousGameIds[i], gameId)
+		payloadBytes, _ := json.Marshal(map[string]string{
+			"gameId":         gameId,
+			"previousGameId": previousGameIds[i],
+		})
+		// Spectators still have the end of the old game queued up
+		m.deliver(client, Event{
+			Type:    GameCreated,
+			Payload: json.RawMessage(payloadBytes),
+		}, m.spectatorDelay(previousGameIds[i]))
 	}
+}
 
-	// Use UCI format if available, otherwise construct from from/to
-	moveStr := moveData.Move
-	if moveStr == "" {
-		moveStr = moveData.From + moveData.To
+func parseColor(color string) chess.Color {
+	switch color {
+	case "white":
+		return chess.White
+	case "black":
+		return chess.Black
+	default:
+		return chess.NoColor
+	}
+}
+
+// NewGameHandler creates a human vs human game on the client's gameId and
+// seats the connecting client in it.
+func (m *Manager) NewGameHandler(e Event, c *Client) error {
+	var seat seatRequest
+	if len(e.Payload) > 0 {
+		if err := json.Unmarshal(e.Payload, &seat); err != nil {
+			return fmt.Errorf("invalid new game data: %v", err)
+		}
+	}
+	if seat.PlayerName == "" {
+		seat.PlayerName = c.userName
 	}
 
-	log.Printf("🎯 Processing move %s for game %s from client %s", moveStr, c.gameId, c.clientId)
+	log.Printf("Starting new human game %s for client %s", c.currentGameId(), c.clientId)
 
 	// Skip game service operations in test environment
 	if m.gameService == nil {
-		log.Printf("Game service is nil - skipping move processing (test environment)")
+		log.Printf("Game service is nil - skipping game creation (test environment)")
 		return nil
 	}
 
-	// Use game service to make the move
-	// Note: We need to use the actual playerId that was used when creating the game
-	// For now, let's try to find which player this client represents
-	gameState, exists := m.gameService.GetGame(c.gameId)
-	if !exists {
-		return fmt.Errorf("game not found")
+	if err := m.claimGameId(c); err != nil {
+		return fmt.Errorf("failed to create game: %v", err)
+	}
+
+	if _, err := m.gameService.CreateGame(c.currentGameId(), c.clientId, game.HumanVsHuman, 0); err != nil {
+		return fmt.Errorf("failed to create game: %v", err)
+	}
+
+	if err := m.setVariant(c.currentGameId(), seat.Variant, seat.Chess960Position); err != nil {
+		return fmt.Errorf("invalid variant: %v", err)
+	}
+
+	if err := m.gameService.SetStartPosition(c.currentGameId(), seat.FEN, seat.PGN); err != nil {
+		return fmt.Errorf("invalid starting position: %v", err)
 	}
 
-	// Find the human player in this game (non-AI player)
-	var humanPlayerId string
-	for _, player := range gameState.Players {
-		if !player.IsAI {
-			humanPlayerId = player.ID
-			break
+	if err := m.gameService.SetSpectatorDelay(c.currentGameId(), time.Duration(seat.SpectatorDelay)*time.Second); err != nil {
+		return fmt.Errorf("failed to set spectator delay: %v", err)
+	}
+
+	if seat.TimeControl != nil {
+		if err := m.gameService.SetTimeControl(c.currentGameId(), *seat.TimeControl); err != nil {
+			return fmt.Errorf("invalid time control: %v", err)
 		}
 	}
 
-	// If no human player found, the game may not be properly initialized
-	if humanPlayerId == "" {
-		return fmt.Errorf("no human player found - game may not be properly initialized")
+	if err := m.gameService.JoinGame(c.currentGameId(), c.clientId, seat.PlayerName, parseColor(seat.PlayerColor)); err != nil {
+		return fmt.Errorf("failed to join game: %v", err)
+	}
+
+	if gameState, exists := m.gameService.GetGame(c.currentGameId()); exists {
+		c.gameState = gameState.ChessGame
+	}
+
+	m.broadcastGameState(c.currentGameId())
+	return nil
+}
+
+// JoinGameHandler seats the connecting client in an existing game.
+func (m *Manager) JoinGameHandler(e Event, c *Client) error {
+	var seat seatRequest
+	if len(e.Payload) > 0 {
+		if err := json.Unmarshal(e.Payload, &seat); err != nil {
+			return fmt.Errorf("invalid join game data: %v", err)
+		}
+	}
+	if seat.PlayerName == "" {
+		seat.PlayerName = c.userName
+	}
+
+	log.Printf("Client %s joining game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping join (test environment)")
+		return nil
+	}
+
+	if err := m.gameService.JoinGame(c.currentGameId(), c.clientId, seat.PlayerName, parseColor(seat.PlayerColor)); err != nil {
+		return fmt.Errorf("failed to join game: %v", err)
+	}
+
+	if gameState, exists := m.gameService.GetGame(c.currentGameId()); exists {
+		c.gameState = gameState.ChessGame
+	}
+
+	m.broadcastGameState(c.currentGameId())
+
+	// A game opened from the lobby against the AI waits for its player to
+	// connect before the AI plays white's first move
+	go m.triggerAIResponseIfNeeded(c.currentGameId())
+	return nil
+}
+
+// LoadPGNHandler sets up a game that is still waiting for its opponent
+// from a PGN or FEN. Only a seated player may do so.
+func (m *Manager) LoadPGNHandler(e Event, c *Client) error {
+	var position struct {
+		PGN string `json:"pgn"`
+		FEN string `json:"fen"`
+	}
+	if err := json.Unmarshal(e.Payload, &position); err != nil {
+		return fmt.Errorf("invalid load_pgn data: %v", err)
+	}
+	if position.PGN == "" && position.FEN == "" {
+		return fmt.Errorf("load_pgn needs a pgn or a fen")
+	}
+
+	log.Printf("Client %s loading a position into game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping load (test environment)")
+		return nil
+	}
+
+	if _, seated := m.gameService.PlayerColor(c.currentGameId(), c.clientId); !seated {
+		return fmt.Errorf("only a player in game %s can load a position", c.currentGameId())
+	}
+	if err := m.gameService.SetStartPosition(c.currentGameId(), position.FEN, position.PGN); err != nil {
+		return fmt.Errorf("failed to load position: %v", err)
+	}
+
+	m.broadcastGameState(c.currentGameId())
+	return nil
+}
+
+func (m *Manager) MoveHandler(e Event, c *Client) error {
+	// The move comes in UCI, SAN or LAN, or as from, to and promotion
+	var moveData game.MoveInput
+	if err := json.Unmarshal(e.Payload, &moveData); err != nil {
+		log.Printf("Error parsing move data: %v", err)
+		return fmt.Errorf("invalid move format: %v", err)
+	}
+	moveStr, err := moveData.Notation()
+	if err != nil {
+		return fmt.Errorf("invalid move format: %v", err)
+	}
+
+	log.Printf("🎯 Processing move %s for game %s from client %s", moveStr, c.currentGameId(), c.clientId)
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping move processing (test environment)")
+		return nil
+	}
+
+	// Moves are authorised against the seat bound to this connection
+	if _, seated := m.gameService.PlayerColor(c.currentGameId(), c.clientId); !seated {
+		return fmt.Errorf("client %s has no seat in game %s", c.clientId, c.currentGameId())
 	}
 
-	log.Printf("🎲 Calling MakeMove with gameId=%s, playerId=%s (found from game), move=%s", c.gameId, humanPlayerId, moveStr)
-	result, err := m.gameService.MakeMove(c.gameId, humanPlayerId, moveStr)
+	log.Printf("🎲 Calling MakeMove with gameId=%s, playerId=%s, move=%s", c.currentGameId(), c.clientId, moveStr)
+	result, err := m.gameService.MakeMove(c.currentGameId(), c.clientId, moveStr)
 	if err != nil {
 		log.Printf("❌ Error making move via game service: %v", err)
+		var moveErr *game.MoveError
+		if errors.As(err, &moveErr) {
+			// Tell the player why, with the moves they may have meant
+			payloadBytes, _ := json.Marshal(moveErr)
+			m.deliver(c, Event{
+				Type:    MoveRejected,
+				Payload: json.RawMessage(payloadBytes),
+			}, 0)
+		}
 		return fmt.Errorf("failed to make move: %v", err)
 	}
 
 	// Update client's local game state to match service
-	gameState2, exists2 := m.gameService.GetGame(c.gameId)
+	gameState2, exists2 := m.gameService.GetGame(c.currentGameId())
 	if exists2 {
 		c.gameState = gameState2.ChessGame
 	}
@@ -182,14 +462,15 @@ func (m *Manager) MoveHandler(e Event, c *Client) error {
 	log.Printf("✅ Move applied via game service: %s", result.Move)
 
 	// Broadcast updated game state
-	m.broadcastGameState(c.gameId)
+	m.broadcastGameState(c.currentGameId())
 
 	// Check if this is an AI game and trigger AI response
-	if !result.IsCheckmate && !result.IsStalemate {
-		log.Printf("🤖 Triggering AI response check for game %s", c.gameId)
-		go m.triggerAIResponseIfNeeded(c.gameId)
+	if result.GameStatus != game.StatusCompleted {
+		log.Printf("🤖 Triggering AI response check for game %s", c.currentGameId())
+		go m.triggerAIResponseIfNeeded(c.currentGameId())
 	} else {
-		log.Printf("🏁 Game over, not triggering AI (checkmate: %v, stalemate: %v)", result.IsCheckmate, result.IsStalemate)
+		log.Printf("🏁 Game over, not triggering AI")
+		m.broadcastGameOver(c.currentGameId())
 	}
 
 	return nil
@@ -217,14 +498,30 @@ func (m *Manager) ServeWS(e echo.Context) error {
 		return err
 	}
 
-	client := NewClient(conn, m, gameId, clientId, userName, ClientTypePlayer)
+	clientType := ClientTypePlayer
+	if ClientType(e.QueryParam("type")) == ClientTypeSpectator {
+		clientType = ClientTypeSpectator
+	}
+
+	client := NewClient(conn, m, gameId, clientId, userName, clientType)
 	m.addClient(client)
 
-	log.Printf("Client %s connected to game %s", clientId, gameId)
+	// Correspondence games wait in the database between moves
+	if m.gameService != nil {
+		if err := m.gameService.LoadCorrespondenceGame(e.Request().Context(), gameId); err != nil {
+			log.Printf("Warning: Could not load game %s: %v", gameId, err)
+		}
+	}
+
+	log.Printf("Client %s connected to game %s as %s", clientId, gameId, clientType)
 
 	go client.readMessages()
 	go client.writeMessages()
 
+	go client.relayDelayed()
+	m.resumeSession(client)
+	m.sendSeatToken(client)
+
 	return nil
 }
 
@@ -236,33 +533,55 @@ func (m *Manager) addClient(c *Client) {
 
 func (m *Manager) removeClient(c *Client) {
 	m.Lock()
-	defer m.Unlock()
-	if _, ok := m.clients[c]; ok {
+	_, ok := m.clients[c]
+	if ok {
 		c.conn.Close()
+		if c.done != nil {
+			close(c.done)
+		}
 		delete(m.clients, c)
-		log.Printf("Client %s disconnected from game %s", c.clientId, c.gameId)
+		log.Printf("Client %s disconnected from game %s", c.clientId, c.currentGameId())
+	}
+	m.Unlock()
+
+	if ok && c.tournamentId == "" {
+		m.handleDisconnect(c)
+		m.releaseGame(c.currentGameId())
 	}
 }
 
 func (m *Manager) broadcastToGame(gameId string, event Event) {
+	m.broadcastRendered(gameId, event, nil)
+}
+
+// broadcastRendered sends an event to every client in a game. render, when
+// given, turns it into what that client may see, e.g. a fog of war player's
+// own view of the board; a client it can't render for is skipped rather
+// than sent the event as is. The session log records the event as is.
+func (m *Manager) broadcastRendered(gameId string, event Event, render func(c *Client) (Event, bool)) {
+	m.recordEvent(gameId, event)
+
 	m.RLock()
 	defer m.RUnlock()
 
 	log.Printf("📡 Broadcasting %s event to game %s", event.Type, gameId)
 	clientCount := 0
 	successCount := 0
+	spectatorDelay := m.spectatorDelay(gameId)
 
 	for client := range m.clients {
-		if client.gameId == gameId {
+		if client.currentGameId() == gameId {
 			clientCount++
 			log.Printf("🎯 Found client %s for game %s", client.clientId, gameId)
-			select {
-			case client.egress <- event:
+			clientEvent := event
+			if render != nil {
+				var ok bool
+				if clientEvent, ok = render(client); !ok {
+					continue
+				}
+			}
+			if m.deliver(client, clientEvent, spectatorDelay) {
 				successCount++
-				log.Printf("✅ Event queued for client %s", client.clientId)
-			default:
-				// Client's egress channel is full, skip this client
-				log.Printf("❌ Could not send event to client %s, channel full", client.clientId)
 			}
 		}
 	}
@@ -270,6 +589,89 @@ func (m *Manager) broadcastToGame(gameId string, event Event) {
 	log.Printf("📊 Broadcast complete: %d/%d clients in game %s received event %s", successCount, clientCount, gameId, event.Type)
 }
 
+// deliver queues an event for a single client. Spectators, meaning every
+// connection without a seat, receive it after the game's spectator delay so
+// they can't relay moves in real time.
+func (m *Manager) deliver(client *Client, event Event, spectatorDelay time.Duration) bool {
+	if spectatorDelay > 0 && client.delayed != nil && !m.holdsSeat(client) {
+		select {
+		case client.delayed <- delayedEvent{event: event, due: time.Now().Add(spectatorDelay)}:
+			log.Printf("⏳ Event delayed %s for spectator %s", spectatorDelay, client.clientId)
+			return true
+		default:
+			log.Printf("❌ Could not delay event for spectator %s, queue full", client.clientId)
+			return false
+		}
+	}
+
+	select {
+	case client.egress <- event:
+		log.Printf("✅ Event queued for client %s", client.clientId)
+		return true
+	default:
+		// Client's egress channel is full, skip this client
+		log.Printf("❌ Could not send event to client %s, channel full", client.clientId)
+		return false
+	}
+}
+
+// holdsSeat reports whether a client is seated in its game. Connections
+// without a seat are spectators, whether or not they asked to be; with no
+// game service, as in tests, the connection type decides.
+func (m *Manager) holdsSeat(c *Client) bool {
+	if c.clientType == ClientTypeSpectator {
+		return false
+	}
+	if m.gameService == nil {
+		return true
+	}
+	_, seated := m.gameService.PlayerColor(c.currentGameId(), c.clientId)
+	return seated
+}
+
+func (m *Manager) spectatorDelay(gameId string) time.Duration {
+	if m.gameService == nil {
+		return 0
+	}
+	return m.gameService.SpectatorDelay(gameId)
+}
+
+// sendGameState sends the full game state to a single client, e.g. when a
+// spectator joins mid-game.
+func (m *Manager) sendGameState(c *Client) {
+	if m.gameService == nil {
+		return
+	}
+	gameState, err := m.gameStateFor(c)
+	if err != nil {
+		log.Printf("No game state to send to client %s: %v", c.clientId, err)
+		return
+	}
+
+	payloadBytes, _ := json.Marshal(gameState)
+	m.deliver(c, Event{
+		Type:    GameState,
+		Payload: json.RawMessage(payloadBytes),
+	}, m.spectatorDelay(c.currentGameId()))
+}
+
+// gameStateFor returns the game state as a client may see it. Players see
+// their own side of a fog of war board; spectators see everything, behind
+// the spectator delay.
+func (m *Manager) gameStateFor(c *Client) (*game.GameStateResponse, error) {
+	if !m.holdsSeat(c) {
+		return m.gameService.GetGameState(c.currentGameId())
+	}
+	color, _ := m.gameService.PlayerColor(c.currentGameId(), c.clientId)
+	return m.gameService.GetGameStateFor(c.currentGameId(), color)
+}
+
+// GameStateHandler resends the current game state to the requesting client.
+func (m *Manager) GameStateHandler(e Event, c *Client) error {
+	m.sendGameState(c)
+	return nil
+}
+
 func (m *Manager) broadcastGameState(gameId string) {
 	gameState, err := m.gameService.GetGameState(gameId)
 	if err != nil {
@@ -283,15 +685,40 @@ func (m *Manager) broadcastGameState(gameId string) {
 		Payload: json.RawMessage(payloadBytes),
 	}
 
-	m.broadcastToGame(gameId, event)
+	// Players see their own premoves and, in fog of war, their own board
+	m.broadcastRendered(gameId, event, func(c *Client) (Event, bool) {
+		if !m.holdsSeat(c) {
+			return event, true
+		}
+		view, err := m.gameStateFor(c)
+		if err != nil {
+			log.Printf("Failed to get game state for client %s: %v", c.clientId, err)
+			return Event{}, false
+		}
+		viewBytes, _ := json.Marshal(view)
+		return Event{Type: GameState, Payload: json.RawMessage(viewBytes)}, true
+	})
 }
 
 func (m *Manager) NewAIGameHandler(e Event, c *Client) error {
 	var aiGameData struct {
-		Difficulty  int    `json:"difficulty"`
-		PlayerID    string `json:"playerId"`
-		PlayerName  string `json:"playerName"`
-		PlayerColor string `json:"playerColor"` // "white" or "black"
+		Difficulty     int               `json:"difficulty"`
+		PlayerID       string            `json:"playerId"` // Deprecated: seats are bound to the connection's clientId
+		PlayerName     string            `json:"playerName"`
+		PlayerColor    string            `json:"playerColor"`    // "white" or "black"
+		SpectatorDelay int               `json:"spectatorDelay"` // Seconds spectators lag behind
+		TimeControl    *game.TimeControl `json:"timeControl"`
+		// AutoAcceptTakebacks lets the AI grant every takeback request
+		AutoAcceptTakebacks bool `json:"autoAcceptTakebacks"`
+		// FEN or PGN to start the game from
+		FEN string `json:"fen"`
+		PGN string `json:"pgn"`
+		// OpeningID starts the game from the end of an opening in the openings table
+		OpeningID string `json:"openingId"`
+		// Variant to play; Chess960 games start from Chess960Position, or a
+		// random position when it is left out
+		Variant          string `json:"variant"`
+		Chess960Position *int   `json:"chess960Position"`
 	}
 
 	if err := json.Unmarshal(e.Payload, &aiGameData); err != nil {
@@ -303,7 +730,7 @@ func (m *Manager) NewAIGameHandler(e Event, c *Client) error {
 		aiGameData.PlayerColor = "white"
 	}
 
-	log.Printf("Starting new AI game for client %s (difficulty: %d, player color: %s)", c.gameId, aiGameData.Difficulty, aiGameData.PlayerColor)
+	log.Printf("Starting new AI game for client %s (difficulty: %d, player color: %s)", c.currentGameId(), aiGameData.Difficulty, aiGameData.PlayerColor)
 
 	// Skip game service operations in test environment
 	if m.gameService == nil {
@@ -311,12 +738,47 @@ func (m *Manager) NewAIGameHandler(e Event, c *Client) error {
 		return nil
 	}
 
+	if err := m.claimGameId(c); err != nil {
+		return fmt.Errorf("failed to create AI game: %v", err)
+	}
+
 	// Create AI game in game service
-	_, err := m.gameService.CreateGame(c.gameId, game.HumanVsAI, aiGameData.Difficulty)
+	_, err := m.gameService.CreateGame(c.currentGameId(), c.clientId, game.HumanVsAI, aiGameData.Difficulty)
 	if err != nil {
 		return fmt.Errorf("failed to create AI game: %v", err)
 	}
 
+	if err := m.setVariant(c.currentGameId(), aiGameData.Variant, aiGameData.Chess960Position); err != nil {
+		return fmt.Errorf("invalid variant: %v", err)
+	}
+
+	if aiGameData.OpeningID != "" {
+		if aiGameData.FEN != "" || aiGameData.PGN != "" {
+			return fmt.Errorf("give either an opening, a FEN or a PGN")
+		}
+		opening, err := m.gameService.SetOpening(context.Background(), c.currentGameId(), aiGameData.OpeningID)
+		if err != nil {
+			return fmt.Errorf("failed to load opening: %v", err)
+		}
+		log.Printf("Game %s starts from %s %s", c.currentGameId(), opening.Eco, opening.Name)
+	} else if err := m.gameService.SetStartPosition(c.currentGameId(), aiGameData.FEN, aiGameData.PGN); err != nil {
+		return fmt.Errorf("invalid starting position: %v", err)
+	}
+
+	if err := m.gameService.SetSpectatorDelay(c.currentGameId(), time.Duration(aiGameData.SpectatorDelay)*time.Second); err != nil {
+		return fmt.Errorf("failed to set spectator delay: %v", err)
+	}
+
+	if aiGameData.TimeControl != nil {
+		if err := m.gameService.SetTimeControl(c.currentGameId(), *aiGameData.TimeControl); err != nil {
+			return fmt.Errorf("invalid time control: %v", err)
+		}
+	}
+
+	if err := m.gameService.SetAutoAcceptTakebacks(c.currentGameId(), aiGameData.AutoAcceptTakebacks); err != nil {
+		return fmt.Errorf("failed to configure takebacks: %v", err)
+	}
+
 	// Determine player color
 	var humanColor chess.Color
 	if aiGameData.PlayerColor == "black" {
@@ -325,27 +787,35 @@ func (m *Manager) NewAIGameHandler(e Event, c *Client) error {
 		humanColor = chess.White
 	}
 
-	// Join as human player with chosen color
-	if err := m.gameService.JoinGame(c.gameId, aiGameData.PlayerID, aiGameData.PlayerName, humanColor); err != nil {
+	// Join as human player with chosen color. The seat is bound to the
+	// connection's clientId so moves can be authorised against it.
+	if err := m.gameService.JoinGame(c.currentGameId(), c.clientId, aiGameData.PlayerName, humanColor); err != nil {
 		return fmt.Errorf("failed to join game: %v", err)
 	}
 
 	// Sync client state with game service state
-	gameState, exists := m.gameService.GetGame(c.gameId)
+	gameState, exists := m.gameService.GetGame(c.currentGameId())
 	if exists {
 		c.gameState = gameState.ChessGame
 	}
 
-	m.broadcastGameState(c.gameId)
+	m.broadcastGameState(c.currentGameId())
 
-	// Note: AI move will be triggered by frontend after opening moves are replayed
+	// A position set up on the server goes straight to the side to move.
+	// Otherwise the frontend triggers the AI move once it has replayed the
+	// opening it picked.
+	if aiGameData.OpeningID != "" || aiGameData.FEN != "" || aiGameData.PGN != "" || aiGameData.Variant != "" {
+		go m.triggerAIResponseIfNeeded(c.currentGameId())
+		log.Printf("✅ AI game initialized from a server-side position")
+		return nil
+	}
 	log.Printf("✅ AI game initialized. Frontend will trigger AI move after position sync.")
 
 	return nil
 }
 
 func (m *Manager) AIMoveHandler(e Event, c *Client) error {
-	log.Printf("🎯 AIMoveHandler called for game %s", c.gameId)
+	log.Printf("🎯 AIMoveHandler called for game %s", c.currentGameId())
 
 	// Skip game service operations in test environment
 	if m.gameService == nil {
@@ -353,21 +823,33 @@ func (m *Manager) AIMoveHandler(e Event, c *Client) error {
 		return nil
 	}
 
-	// Get AI move from game service
-	log.Printf("🧠 Calling gameService.GetAIMove for game %s", c.gameId)
-	result, err := m.gameService.GetAIMove(c.gameId)
+	result, err := m.playAIMove(c.currentGameId())
 	if err != nil {
-		log.Printf("❌ GetAIMove failed: %v", err)
-		return fmt.Errorf("failed to get AI move: %v", err)
+		return err
 	}
-	log.Printf("🎲 AI move generated: %s", result.Move)
 
 	// Update client game state
-	gameState, exists := m.gameService.GetGame(c.gameId)
+	gameState, exists := m.gameService.GetGame(c.currentGameId())
 	if exists {
 		c.gameState = gameState.ChessGame
 	}
 
+	log.Printf("✅ AI move completed and sent: %s", result.Move)
+	return nil
+}
+
+// playAIMove has the engine move for the side to play and broadcasts the
+// move, the new state and, if it ended the game, the result.
+func (m *Manager) playAIMove(gameId string) (*game.MoveResult, error) {
+	// Get AI move from game service
+	log.Printf("🧠 Calling gameService.GetAIMove for game %s", gameId)
+	result, err := m.gameService.GetAIMove(gameId)
+	if err != nil {
+		log.Printf("❌ GetAIMove failed: %v", err)
+		return nil, fmt.Errorf("failed to get AI move: %v", err)
+	}
+	log.Printf("🎲 AI move generated: %s", result.Move)
+
 	// Send AI move event to frontend
 	// Parse the UCI move to extract from/to for backward compatibility
 	uciMove := result.Move
@@ -391,6 +873,7 @@ func (m *Manager) AIMoveHandler(e Event, c *Client) error {
 		"isCheckmate": result.IsCheckmate,
 		"isStalemate": result.IsStalemate,
 		"gameStatus":  result.GameStatus,
+		"premove":     result.Premove, // The human's queued reply, if played
 	}
 
 	payloadBytes, _ := json.Marshal(aiMovePayload)
@@ -399,15 +882,42 @@ func (m *Manager) AIMoveHandler(e Event, c *Client) error {
 		Payload: json.RawMessage(payloadBytes),
 	}
 
-	log.Printf("📤 Broadcasting AI move to game %s: %s", c.gameId, result.Move)
-	m.broadcastToGame(c.gameId, aiMoveEvent)
+	log.Printf("📤 Broadcasting AI move to game %s: %s", gameId, result.Move)
+	if m.gameService.HidesBoard(gameId) {
+		// In fog of war the players only learn whose turn it is and what
+		// they can now see
+		m.broadcastRendered(gameId, aiMoveEvent, func(c *Client) (Event, bool) {
+			if !m.holdsSeat(c) {
+				return aiMoveEvent, true
+			}
+			view, err := m.gameStateFor(c)
+			if err != nil {
+				log.Printf("Failed to get game state for client %s: %v", c.clientId, err)
+				return Event{}, false
+			}
+			viewBytes, _ := json.Marshal(map[string]interface{}{
+				"fen":        view.FEN,
+				"turn":       result.Turn.String(),
+				"gameStatus": result.GameStatus,
+			})
+			return Event{Type: AIMove, Payload: json.RawMessage(viewBytes)}, true
+		})
+	} else {
+		m.broadcastToGame(gameId, aiMoveEvent)
+	}
 
 	// Also broadcast the new game state
 	log.Printf("📤 Broadcasting updated game state")
-	m.broadcastGameState(c.gameId)
+	m.broadcastGameState(gameId)
 
-	log.Printf("✅ AI move completed and sent: %s", result.Move)
-	return nil
+	if result.GameStatus == game.StatusCompleted {
+		m.broadcastGameOver(gameId)
+	} else if result.Premove != "" {
+		// The human's premove answered at once, so it's the AI's turn again
+		go m.triggerAIResponseIfNeeded(gameId)
+	}
+
+	return result, nil
 }
 
 func (m *Manager) triggerAIResponseIfNeeded(gameId string) {
@@ -447,7 +957,7 @@ func (m *Manager) triggerAIResponseIfNeeded(gameId string) {
 			m.RLock()
 			var targetClient *Client
 			for client := range m.clients {
-				if client.gameId == gameId {
+				if client.currentGameId() == gameId {
 					targetClient = client
 					break
 				}
@@ -479,25 +989,436 @@ func (m *Manager) triggerAIResponseIfNeeded(gameId string) {
 	}
 }
 
+// GameOverHandler no longer trusts the result a client sends. Games are
+// ended by the server, so all a client can do is ask for the verdict again.
 func (m *Manager) GameOverHandler(e Event, c *Client) error {
-	log.Printf("Game over event received for game %s", c.gameId)
+	log.Printf("Game over event received from client %s for game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping game over (test environment)")
+		return nil
+	}
+
+	result, over := m.gameService.GameResult(c.currentGameId())
+	if !over {
+		return fmt.Errorf("game %s is not over", c.currentGameId())
+	}
+
+	m.deliver(c, gameOverEvent(result), m.spectatorDelay(c.currentGameId()))
+	return nil
+}
+
+func gameOverEvent(result *game.GameResult) Event {
+	payloadBytes, _ := json.Marshal(result)
+	return Event{
+		Type:    GameOver,
+		Payload: json.RawMessage(payloadBytes),
+	}
+}
+
+// broadcastGameOver sends the server's verdict to everyone in a finished game.
+func (m *Manager) broadcastGameOver(gameId string) {
+	result, over := m.gameService.GameResult(gameId)
+	if !over {
+		return
+	}
+	log.Printf("🏁 Game %s over: %s (%s)", gameId, result.Result, result.Outcome)
+	m.broadcastToGame(gameId, gameOverEvent(result))
+}
+
+// onGameUpdated broadcasts changes the game service made on its own, such
+// as a flag falling.
+func (m *Manager) onGameUpdated(gameId string) {
+	m.broadcastGameState(gameId)
+	m.broadcastGameOver(gameId)
+}
+
+func (m *Manager) ClaimDrawHandler(e Event, c *Client) error {
+	var claim struct {
+		Outcome game.Outcome `json:"outcome"` // "threefold_repetition" or "fifty_move_rule"
+	}
+	if err := json.Unmarshal(e.Payload, &claim); err != nil {
+		return fmt.Errorf("invalid draw claim: %v", err)
+	}
+
+	log.Printf("Client %s claiming %s in game %s", c.clientId, claim.Outcome, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping draw claim (test environment)")
+		return nil
+	}
+
+	if err := m.gameService.ClaimDraw(c.currentGameId(), c.clientId, claim.Outcome); err != nil {
+		return fmt.Errorf("failed to claim draw: %v", err)
+	}
+
+	m.broadcastGameState(c.currentGameId())
+	m.broadcastGameOver(c.currentGameId())
+	return nil
+}
+
+// drawEvent builds the offer_draw/decline_draw notification sent to the game.
+func drawEvent(eventType string, by chess.Color) Event {
+	payloadBytes, _ := json.Marshal(map[string]string{
+		"by": strings.ToLower(by.Name()),
+	})
+	return Event{
+		Type:    eventType,
+		Payload: json.RawMessage(payloadBytes),
+	}
+}
+
+func (m *Manager) ResignHandler(e Event, c *Client) error {
+	log.Printf("Client %s resigning game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping resignation (test environment)")
+		return nil
+	}
+
+	if err := m.gameService.Resign(c.currentGameId(), c.clientId); err != nil {
+		return fmt.Errorf("failed to resign: %v", err)
+	}
+
+	m.broadcastGameState(c.currentGameId())
+	m.broadcastGameOver(c.currentGameId())
+	return nil
+}
+
+func (m *Manager) OfferDrawHandler(e Event, c *Client) error {
+	log.Printf("Client %s offering a draw in game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping draw offer (test environment)")
+		return nil
+	}
 
-	// Parse game over data from payload
-	var gameOverData struct {
-		Result string `json:"result"`
-		Winner string `json:"winner,omitempty"`
-		Reason string `json:"reason,omitempty"`
+	color, _ := m.gameService.PlayerColor(c.currentGameId(), c.clientId)
+	declinedByAI, err := m.gameService.OfferDraw(c.currentGameId(), c.clientId)
+	if err != nil {
+		return fmt.Errorf("failed to offer draw: %v", err)
 	}
 
-	if err := json.Unmarshal(e.Payload, &gameOverData); err != nil {
-		log.Printf("Error parsing game over data: %v", err)
-		return fmt.Errorf("invalid game over data: %v", err)
+	if declinedByAI {
+		m.deliver(c, drawEvent(DeclineDraw, color.Other()), 0)
+		return nil
 	}
 
-	log.Printf("Game %s ended with result: %s", c.gameId, gameOverData.Result)
+	m.broadcastToGame(c.currentGameId(), drawEvent(OfferDraw, color))
+	m.broadcastGameState(c.currentGameId())
+	return nil
+}
+
+func (m *Manager) AcceptDrawHandler(e Event, c *Client) error {
+	log.Printf("Client %s accepting a draw in game %s", c.clientId, c.currentGameId())
 
-	// Broadcast game over event to all clients in the game
-	m.broadcastToGame(c.gameId, e)
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping draw acceptance (test environment)")
+		return nil
+	}
 
+	if err := m.gameService.AcceptDraw(c.currentGameId(), c.clientId); err != nil {
+		return fmt.Errorf("failed to accept draw: %v", err)
+	}
+
+	m.broadcastGameState(c.currentGameId())
+	m.broadcastGameOver(c.currentGameId())
 	return nil
 }
+
+func (m *Manager) DeclineDrawHandler(e Event, c *Client) error {
+	log.Printf("Client %s declining a draw in game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping draw decline (test environment)")
+		return nil
+	}
+
+	if err := m.gameService.DeclineDraw(c.currentGameId(), c.clientId); err != nil {
+		return fmt.Errorf("failed to decline draw: %v", err)
+	}
+
+	color, _ := m.gameService.PlayerColor(c.currentGameId(), c.clientId)
+	m.broadcastToGame(c.currentGameId(), drawEvent(DeclineDraw, color))
+	m.broadcastGameState(c.currentGameId())
+	return nil
+}
+
+func takebackResponseEvent(by chess.Color, accepted bool) Event {
+	payloadBytes, _ := json.Marshal(map[string]interface{}{
+		"by":       strings.ToLower(by.Name()),
+		"accepted": accepted,
+	})
+	return Event{
+		Type:    TakebackResponse,
+		Payload: json.RawMessage(payloadBytes),
+	}
+}
+
+func (m *Manager) TakebackRequestHandler(e Event, c *Client) error {
+	log.Printf("Client %s requesting a takeback in game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping takeback request (test environment)")
+		return nil
+	}
+
+	color, _ := m.gameService.PlayerColor(c.currentGameId(), c.clientId)
+	answered, accepted, err := m.gameService.RequestTakeback(c.currentGameId(), c.clientId)
+	if err != nil {
+		return fmt.Errorf("failed to request takeback: %v", err)
+	}
+
+	if answered {
+		// The AI answered on the spot
+		if gameState, exists := m.gameService.GetGame(c.currentGameId()); exists {
+			c.gameState = gameState.ChessGame
+		}
+		m.broadcastToGame(c.currentGameId(), takebackResponseEvent(color.Other(), accepted))
+	} else {
+		payloadBytes, _ := json.Marshal(map[string]string{
+			"by": strings.ToLower(color.Name()),
+		})
+		m.broadcastToGame(c.currentGameId(), Event{
+			Type:    TakebackRequest,
+			Payload: json.RawMessage(payloadBytes),
+		})
+	}
+
+	m.broadcastGameState(c.currentGameId())
+	return nil
+}
+
+func (m *Manager) TakebackResponseHandler(e Event, c *Client) error {
+	var response struct {
+		Accept bool `json:"accept"`
+	}
+	if err := json.Unmarshal(e.Payload, &response); err != nil {
+		return fmt.Errorf("invalid takeback response: %v", err)
+	}
+
+	log.Printf("Client %s answering takeback in game %s (accept: %v)", c.clientId, c.currentGameId(), response.Accept)
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping takeback response (test environment)")
+		return nil
+	}
+
+	if err := m.gameService.RespondTakeback(c.currentGameId(), c.clientId, response.Accept); err != nil {
+		return fmt.Errorf("failed to answer takeback: %v", err)
+	}
+
+	if gameState, exists := m.gameService.GetGame(c.currentGameId()); exists {
+		c.gameState = gameState.ChessGame
+	}
+
+	color, _ := m.gameService.PlayerColor(c.currentGameId(), c.clientId)
+	m.broadcastToGame(c.currentGameId(), takebackResponseEvent(color, response.Accept))
+	m.broadcastGameState(c.currentGameId())
+	return nil
+}
+
+// PremoveHandler queues moves for the client's next turns, replacing any
+// already queued. The payload is either a list of premoves or a single one.
+// Only the client itself is told; the opponent must not see them coming.
+func (m *Manager) PremoveHandler(e Event, c *Client) error {
+	var premoveData struct {
+		Premoves []game.Premove `json:"premoves"`
+		game.Premove
+	}
+	if err := json.Unmarshal(e.Payload, &premoveData); err != nil {
+		return fmt.Errorf("invalid premove: %v", err)
+	}
+
+	premoves := premoveData.Premoves
+	if len(premoves) == 0 && premoveData.Move != "" {
+		premoves = []game.Premove{premoveData.Premove}
+	}
+	if len(premoves) == 0 {
+		return fmt.Errorf("no premove given")
+	}
+
+	log.Printf("Client %s queueing %d premoves in game %s", c.clientId, len(premoves), c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping premove (test environment)")
+		return nil
+	}
+
+	if err := m.gameService.SetPremoves(c.currentGameId(), c.clientId, premoves); err != nil {
+		return fmt.Errorf("failed to queue premoves: %v", err)
+	}
+
+	m.sendGameState(c)
+	return nil
+}
+
+// CancelPremoveHandler drops all of the client's queued premoves.
+func (m *Manager) CancelPremoveHandler(e Event, c *Client) error {
+	log.Printf("Client %s cancelling premoves in game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping premove cancellation (test environment)")
+		return nil
+	}
+
+	if err := m.gameService.SetPremoves(c.currentGameId(), c.clientId, nil); err != nil {
+		return fmt.Errorf("failed to cancel premoves: %v", err)
+	}
+
+	m.sendGameState(c)
+	return nil
+}
+
+// RematchOfferHandler offers the opponent a rematch of a finished game.
+// Against the AI, or when the opponent offered first, the rematch starts
+// at once.
+func (m *Manager) RematchOfferHandler(e Event, c *Client) error {
+	log.Printf("Client %s offering a rematch of game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping rematch offer (test environment)")
+		return nil
+	}
+
+	color, _ := m.gameService.PlayerColor(c.currentGameId(), c.clientId)
+	rematchId, err := m.gameService.OfferRematch(c.currentGameId(), c.clientId)
+	if err != nil {
+		return fmt.Errorf("failed to offer rematch: %v", err)
+	}
+	if rematchId != "" {
+		m.startRematch(c.currentGameId(), rematchId)
+		return nil
+	}
+
+	payloadBytes, _ := json.Marshal(map[string]string{
+		"by": strings.ToLower(color.Name()),
+	})
+	m.broadcastToGame(c.currentGameId(), Event{
+		Type:    RematchOffer,
+		Payload: json.RawMessage(payloadBytes),
+	})
+	m.broadcastGameState(c.currentGameId())
+	return nil
+}
+
+// RematchAcceptHandler accepts the opponent's rematch offer.
+func (m *Manager) RematchAcceptHandler(e Event, c *Client) error {
+	log.Printf("Client %s accepting a rematch of game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping rematch accept (test environment)")
+		return nil
+	}
+
+	rematchId, err := m.gameService.AcceptRematch(c.currentGameId(), c.clientId)
+	if err != nil {
+		return fmt.Errorf("failed to accept rematch: %v", err)
+	}
+	m.startRematch(c.currentGameId(), rematchId)
+	return nil
+}
+
+// startRematch moves everyone watching the finished game, players and
+// spectators, over to its rematch.
+func (m *Manager) startRematch(gameId, rematchId string) {
+	m.RLock()
+	var clients []*Client
+	for client := range m.clients {
+		if client.currentGameId() == gameId {
+			clients = append(clients, client)
+		}
+	}
+	m.RUnlock()
+
+	m.moveClients(clients, rematchId)
+	if rematch, exists := m.gameService.GetGame(rematchId); exists {
+		for _, client := range clients {
+			client.gameState = rematch.ChessGame
+		}
+	}
+	for _, client := range clients {
+		m.sendSeatToken(client)
+	}
+	// Nobody is left at a finished correspondence game
+	m.releaseGame(gameId)
+
+	m.broadcastGameState(rematchId)
+	go m.triggerAIResponseIfNeeded(rematchId)
+}
+
+// AdjournRequestHandler asks to pause the game. Against a human the game
+// is adjourned once both players have asked; until then the opponent is
+// told about the request.
+func (m *Manager) AdjournRequestHandler(e Event, c *Client) error {
+	log.Printf("Client %s asking to adjourn game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping adjourn request (test environment)")
+		return nil
+	}
+
+	adjourned, err := m.gameService.RequestAdjournment(c.currentGameId(), c.clientId)
+	if err != nil {
+		return fmt.Errorf("failed to adjourn game: %v", err)
+	}
+	if !adjourned {
+		m.broadcastPauseRequest(AdjournRequest, c)
+	}
+	m.broadcastGameState(c.currentGameId())
+	return nil
+}
+
+// ResumeRequestHandler asks to carry on with an adjourned game, which may
+// have to be loaded back from the database first. Against a human the game
+// resumes once both players have asked.
+func (m *Manager) ResumeRequestHandler(e Event, c *Client) error {
+	log.Printf("Client %s asking to resume game %s", c.clientId, c.currentGameId())
+
+	// Skip game service operations in test environment
+	if m.gameService == nil {
+		log.Printf("Game service is nil - skipping resume request (test environment)")
+		return nil
+	}
+
+	resumed, err := m.gameService.RequestResume(c.currentGameId(), c.clientId)
+	if err != nil {
+		return fmt.Errorf("failed to resume game: %v", err)
+	}
+	if gameState, exists := m.gameService.GetGame(c.currentGameId()); exists {
+		c.gameState = gameState.ChessGame
+	}
+	if !resumed {
+		m.broadcastPauseRequest(ResumeRequest, c)
+	}
+	m.broadcastGameState(c.currentGameId())
+	if resumed {
+		go m.triggerAIResponseIfNeeded(c.currentGameId())
+	}
+	return nil
+}
+
+// broadcastPauseRequest tells a game who asked to adjourn or resume it.
+func (m *Manager) broadcastPauseRequest(eventType string, c *Client) {
+	color, _ := m.gameService.PlayerColor(c.currentGameId(), c.clientId)
+	payloadBytes, _ := json.Marshal(map[string]string{
+		"by": strings.ToLower(color.Name()),
+	})
+	m.broadcastToGame(c.currentGameId(), Event{
+		Type:    eventType,
+		Payload: json.RawMessage(payloadBytes),
+	})
+}
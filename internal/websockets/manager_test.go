@@ -8,6 +8,7 @@ import (
 
 	"github.com/corentings/chess/v2"
 	"github.com/gorilla/websocket"
+	"github.com/hunterMotko/chess-game/internal/game"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -36,7 +37,7 @@ func TestManager_setupHandlers(t *testing.T) {
 	manager.setupHandlers()
 
 	// Verify core handlers are registered (we may have more than originally expected)
-	expectedHandlers := []string{Move}
+	expectedHandlers := []string{Move, NewGame, JoinGame, NewAIMatch}
 
 	for _, handlerType := range expectedHandlers {
 		_, exists := manager.handlers[handlerType]
@@ -145,11 +146,11 @@ func TestNewGameHandler(t *testing.T) {
 	}
 
 	event := Event{
-		Type:    NewAIGame,
+		Type:    NewGame,
 		Payload: json.RawMessage(`{}`),
 	}
 
-	err := NewGameHandler(event, client)
+	err := manager.NewGameHandler(event, client)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, client.gameState)
@@ -339,3 +340,321 @@ func (m *MockWebSocketConn) WriteControl(messageType int, data []byte, deadline
 
 // Verify our mock implements the interface
 var _ WebSocketConnection = (*MockWebSocketConn)(nil)
+
+func TestManager_routeEvent_SpectatorReadOnly(t *testing.T) {
+	manager := createTestManager()
+	manager.setupHandlers()
+
+	spectator := &Client{
+		gameState:  &chess.Game{},
+		egress:     make(chan Event, 10),
+		manager:    manager,
+		gameId:     "test-game",
+		clientType: ClientTypeSpectator,
+	}
+
+	for _, eventType := range []string{Move, AIMove, GameOver, NewAIGame, NewGame, JoinGame} {
+		err := manager.routeEvent(Event{Type: eventType, Payload: json.RawMessage(`{}`)}, spectator)
+		assert.Error(t, err, "spectator should not be able to send %s", eventType)
+	}
+
+	assert.NoError(t, manager.routeEvent(Event{Type: GameState, Payload: json.RawMessage(`{}`)}, spectator))
+}
+
+func TestManager_deliver_SpectatorDelay(t *testing.T) {
+	manager := createTestManager()
+
+	spectator := &Client{
+		egress:     make(chan Event, 10),
+		delayed:    make(chan delayedEvent, 10),
+		done:       make(chan struct{}),
+		gameId:     "test-game",
+		clientType: ClientTypeSpectator,
+	}
+	player := &Client{
+		egress:     make(chan Event, 10),
+		gameId:     "test-game",
+		clientType: ClientTypePlayer,
+	}
+	go spectator.relayDelayed()
+	defer close(spectator.done)
+
+	event := Event{Type: GameState, Payload: json.RawMessage(`{}`)}
+	assert.True(t, manager.deliver(player, event, 50*time.Millisecond))
+	assert.True(t, manager.deliver(spectator, event, 50*time.Millisecond))
+
+	assert.Len(t, player.egress, 1, "players are never delayed")
+	assert.Len(t, spectator.egress, 0, "spectators should not see the event yet")
+
+	select {
+	case received := <-spectator.egress:
+		assert.Equal(t, GameState, received.Type)
+	case <-time.After(time.Second):
+		t.Fatal("delayed event never reached the spectator")
+	}
+}
+
+func TestManager_broadcastRendered_SkipsUnrendered(t *testing.T) {
+	manager := createTestManager()
+
+	seen := &Client{egress: make(chan Event, 10), gameId: "test-game", clientId: "seen", clientType: ClientTypePlayer}
+	skipped := &Client{egress: make(chan Event, 10), gameId: "test-game", clientId: "skipped", clientType: ClientTypePlayer}
+	manager.addClient(seen)
+	manager.addClient(skipped)
+
+	event := Event{Type: GameState, Payload: json.RawMessage(`{"fen":"full board"}`)}
+	manager.broadcastRendered("test-game", event, func(c *Client) (Event, bool) {
+		if c == skipped {
+			return Event{}, false
+		}
+		return Event{Type: GameState, Payload: json.RawMessage(`{"fen":"own view"}`)}, true
+	})
+
+	require.Len(t, seen.egress, 1)
+	assert.JSONEq(t, `{"fen":"own view"}`, string((<-seen.egress).Payload))
+	assert.Empty(t, skipped.egress, "a client that can't be rendered for never sees the full board")
+}
+
+// createSessionTestManager creates a manager backed by an in-memory game
+// service with a human game in progress
+func createSessionTestManager(t *testing.T) *Manager {
+	t.Helper()
+	manager := createTestManager()
+	manager.gameService = game.NewGameService(nil)
+	manager.reconnectGrace = 50 * time.Millisecond
+
+	_, err := manager.gameService.CreateGame("session-game", "white-client", game.HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, manager.gameService.JoinGame("session-game", "white-client", "Alice", chess.White))
+	require.NoError(t, manager.gameService.JoinGame("session-game", "black-client", "Bob", chess.Black))
+	return manager
+}
+
+func TestManager_UnseatedPlayerIsSpectator(t *testing.T) {
+	manager := createSessionTestManager(t)
+	manager.setupHandlers()
+
+	seated := &Client{egress: make(chan Event, 10), delayed: make(chan delayedEvent, 10), gameId: "session-game", clientId: "white-client", clientType: ClientTypePlayer}
+	lurker := &Client{egress: make(chan Event, 10), delayed: make(chan delayedEvent, 10), gameId: "session-game", clientId: "lurker", clientType: ClientTypePlayer}
+
+	event := Event{Type: GameState, Payload: json.RawMessage(`{}`)}
+	assert.True(t, manager.deliver(seated, event, time.Minute))
+	assert.True(t, manager.deliver(lurker, event, time.Minute))
+	assert.Len(t, seated.egress, 1)
+	assert.Empty(t, lurker.egress, "leaving out type=spectator doesn't skip the delay")
+	assert.Len(t, lurker.delayed, 1)
+
+	assert.Error(t, manager.routeEvent(Event{Type: Resign, Payload: json.RawMessage(`{}`)}, lurker))
+	assert.NoError(t, manager.routeEvent(Event{Type: GameState, Payload: json.RawMessage(`{}`)}, lurker))
+}
+
+func TestManager_resumeSession_ReplaysMissedEvents(t *testing.T) {
+	manager := createSessionTestManager(t)
+
+	opponent := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "black-client", clientType: ClientTypePlayer}
+	manager.addClient(opponent)
+
+	dropped := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "white-client", clientType: ClientTypePlayer}
+	manager.handleDisconnect(dropped)
+
+	notice := <-opponent.egress
+	assert.Equal(t, PlayerDisconnected, notice.Type)
+	assert.JSONEq(t, `{"color":"white","gracePeriodMs":50}`, string(notice.Payload))
+
+	// Broadcast while white is away
+	manager.broadcastToGame("session-game", Event{Type: GameState, Payload: json.RawMessage(`{}`)})
+	manager.broadcastToGame("session-game", Event{Type: OfferDraw, Payload: json.RawMessage(`{}`)})
+
+	resumed := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "white-client", clientType: ClientTypePlayer}
+	manager.addClient(resumed)
+	manager.resumeSession(resumed)
+
+	require.Len(t, resumed.egress, 4)
+	assert.Equal(t, GameState, (<-resumed.egress).Type, "full state first")
+	assert.Equal(t, ChatHistory, (<-resumed.egress).Type)
+	assert.Equal(t, OfferDraw, (<-resumed.egress).Type, "then missed events, without stale game states")
+	assert.Equal(t, PlayerReconnected, (<-resumed.egress).Type)
+
+	// The grace timer was cancelled
+	time.Sleep(100 * time.Millisecond)
+	_, over := manager.gameService.GameResult("session-game")
+	assert.False(t, over)
+}
+
+func TestManager_handleDisconnect_GraceExpires(t *testing.T) {
+	manager := createSessionTestManager(t)
+
+	opponent := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "black-client", clientType: ClientTypePlayer}
+	manager.addClient(opponent)
+
+	manager.handleDisconnect(&Client{gameId: "session-game", clientId: "white-client", clientType: ClientTypePlayer})
+
+	require.Eventually(t, func() bool {
+		_, over := manager.gameService.GameResult("session-game")
+		return over
+	}, time.Second, 10*time.Millisecond)
+
+	result, _ := manager.gameService.GameResult("session-game")
+	assert.Equal(t, "black", result.Winner)
+	assert.Equal(t, game.OutcomeAbandoned, result.Outcome)
+}
+
+func TestManager_NewGameHandler_KeepsOthersGames(t *testing.T) {
+	manager := createSessionTestManager(t)
+
+	intruder := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "intruder", clientType: ClientTypePlayer}
+	manager.addClient(intruder)
+	assert.Error(t, manager.NewGameHandler(Event{Type: NewGame}, intruder))
+
+	state, err := manager.gameService.GetGameState("session-game")
+	require.NoError(t, err)
+	assert.Equal(t, game.StatusInProgress, state.Status, "the game was left alone")
+
+	// Once the game is over a new game moves to a fresh ID
+	require.NoError(t, manager.gameService.Resign("session-game", "white-client"))
+	require.NoError(t, manager.NewGameHandler(Event{Type: NewGame}, intruder))
+	assert.NotEqual(t, "session-game", intruder.gameId)
+
+	created := <-intruder.egress
+	assert.Equal(t, GameCreated, created.Type)
+	assert.Contains(t, string(created.Payload), intruder.gameId)
+	_, over := manager.gameService.GameResult("session-game")
+	assert.True(t, over, "the finished game is kept")
+}
+
+func TestManager_RematchMovesClients(t *testing.T) {
+	manager := createSessionTestManager(t)
+	white := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "white-client", clientType: ClientTypePlayer}
+	black := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "black-client", clientType: ClientTypePlayer}
+	spectator := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "spectator", clientType: ClientTypeSpectator}
+	manager.addClient(white)
+	manager.addClient(black)
+	manager.addClient(spectator)
+	require.NoError(t, manager.gameService.Resign("session-game", "black-client"))
+
+	require.NoError(t, manager.RematchOfferHandler(Event{Type: RematchOffer}, white))
+	offer := <-black.egress
+	assert.Equal(t, RematchOffer, offer.Type)
+	assert.JSONEq(t, `{"by":"white"}`, string(offer.Payload))
+	assert.Equal(t, "session-game", black.gameId, "nobody moves until black accepts")
+
+	assert.Error(t, manager.RematchAcceptHandler(Event{Type: RematchAccept}, white), "cannot accept your own offer")
+	require.NoError(t, manager.RematchAcceptHandler(Event{Type: RematchAccept}, black))
+
+	rematchId := black.gameId
+	assert.NotEqual(t, "session-game", rematchId)
+	for _, client := range []*Client{white, black, spectator} {
+		assert.Equal(t, rematchId, client.gameId)
+	}
+	color, seated := manager.gameService.PlayerColor(rematchId, "black-client")
+	require.True(t, seated)
+	assert.Equal(t, chess.White, color)
+}
+
+func TestManager_RematchSendsSeatTokens(t *testing.T) {
+	manager := createTestManager()
+	manager.gameService = game.NewGameService(nil)
+	gs := manager.gameService
+	_, err := gs.CreateGame("daily", "white-client", game.HumanVsHuman, 0)
+	require.NoError(t, err)
+	require.NoError(t, gs.SetCorrespondence("daily", 3))
+	require.NoError(t, gs.JoinGame("daily", "white-client", "Alice", chess.White))
+	require.NoError(t, gs.JoinGame("daily", "black-client", "Bob", chess.Black))
+	require.NoError(t, gs.Resign("daily", "black-client"))
+
+	white := &Client{egress: make(chan Event, 10), gameId: "daily", clientId: "white-client", clientType: ClientTypePlayer}
+	spectator := &Client{egress: make(chan Event, 10), gameId: "daily", clientId: "spectator", clientType: ClientTypeSpectator}
+	manager.addClient(white)
+	manager.addClient(spectator)
+	_, err = gs.OfferRematch("daily", "black-client")
+	require.NoError(t, err)
+	require.NoError(t, manager.RematchAcceptHandler(Event{Type: RematchAccept}, white))
+
+	var token map[string]string
+	for len(white.egress) > 0 {
+		if event := <-white.egress; event.Type == SeatToken {
+			require.NoError(t, json.Unmarshal(event.Payload, &token))
+		}
+	}
+	require.NotEmpty(t, token["token"], "the connected player gets their rematch seat token")
+	assert.Equal(t, white.gameId, token["gameId"])
+	for len(spectator.egress) > 0 {
+		assert.NotEqual(t, SeatToken, (<-spectator.egress).Type)
+	}
+
+	again, err := gs.IssueSeatToken(white.gameId, "white-client")
+	require.NoError(t, err)
+	assert.Empty(t, again, "the token was handed out already")
+}
+
+func TestManager_moveClients_WhileClientReads(t *testing.T) {
+	manager := createTestManager()
+	client := &Client{egress: make(chan Event, 10), gameId: "old-game", clientId: "white-client", clientType: ClientTypePlayer}
+	manager.addClient(client)
+
+	// The client's own goroutine keeps reading its game while it's moved
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = client.currentGameId()
+		}
+	}()
+	manager.moveClients([]*Client{client}, "new-game")
+	<-done
+
+	assert.Equal(t, "new-game", client.currentGameId())
+	moved := <-client.egress
+	assert.Equal(t, GameCreated, moved.Type)
+	assert.JSONEq(t, `{"gameId":"new-game","previousGameId":"old-game"}`, string(moved.Payload))
+}
+
+func TestManager_MoveHandler_RejectsWithAlternatives(t *testing.T) {
+	manager := createSessionTestManager(t)
+	white := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "white-client", clientType: ClientTypePlayer}
+	manager.addClient(white)
+
+	assert.Error(t, manager.MoveHandler(Event{Type: Move, Payload: json.RawMessage(`{"move":"e2e5"}`)}, white))
+	rejected := <-white.egress
+	assert.Equal(t, MoveRejected, rejected.Type)
+	assert.Contains(t, string(rejected.Payload), `"code":"illegal"`)
+	assert.Contains(t, string(rejected.Payload), `"uci":"e2e4"`)
+
+	require.NoError(t, manager.MoveHandler(Event{Type: Move, Payload: json.RawMessage(`{"from":"e2","to":"e4"}`)}, white))
+	state, err := manager.gameService.GetGameState("session-game")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e2e4"}, state.MoveHistory)
+}
+
+func TestManager_AdjournAndResume(t *testing.T) {
+	manager := createSessionTestManager(t)
+	white := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "white-client", clientType: ClientTypePlayer}
+	black := &Client{egress: make(chan Event, 10), gameId: "session-game", clientId: "black-client", clientType: ClientTypePlayer}
+	manager.addClient(white)
+	manager.addClient(black)
+
+	require.NoError(t, manager.AdjournRequestHandler(Event{Type: AdjournRequest}, white))
+	request := <-black.egress
+	assert.Equal(t, AdjournRequest, request.Type)
+	assert.JSONEq(t, `{"by":"white"}`, string(request.Payload))
+
+	require.NoError(t, manager.AdjournRequestHandler(Event{Type: AdjournRequest}, black))
+	state, err := manager.gameService.GetGameState("session-game")
+	require.NoError(t, err)
+	assert.Equal(t, game.StatusAdjourned, state.Status)
+
+	// Leaving an adjourned game doesn't start the reconnect clock
+	manager.Lock()
+	delete(manager.clients, black)
+	manager.Unlock()
+	manager.handleDisconnect(black)
+	for len(white.egress) > 0 {
+		assert.NotEqual(t, PlayerDisconnected, (<-white.egress).Type)
+	}
+
+	require.NoError(t, manager.ResumeRequestHandler(Event{Type: ResumeRequest}, white))
+	require.NoError(t, manager.ResumeRequestHandler(Event{Type: ResumeRequest}, black))
+	state, err = manager.gameService.GetGameState("session-game")
+	require.NoError(t, err)
+	assert.Equal(t, game.StatusInProgress, state.Status)
+}
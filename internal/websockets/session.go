@@ -0,0 +1,206 @@
+package websockets
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hunterMotko/chess-game/internal/game"
+)
+
+const (
+	// defaultReconnectGrace is how long a dropped player keeps their seat
+	defaultReconnectGrace = 60 * time.Second
+	// eventLogSize bounds the broadcasts kept per game for catch-up
+	eventLogSize = 50
+)
+
+// replaySkipped are events a reconnecting client doesn't need replayed:
+// the full game_state it receives already covers them, or they are stale
+// connection notices.
+var replaySkipped = map[string]bool{
+	GameState:          true,
+	AIMove:             true,
+	PlayerDisconnected: true,
+	PlayerReconnected:  true,
+}
+
+type loggedEvent struct {
+	seq   uint64
+	event Event
+}
+
+// eventLog keeps the most recent broadcasts of a game.
+type eventLog struct {
+	seq    uint64
+	events []loggedEvent
+}
+
+// pendingReconnect is a seated player inside their reconnect grace window.
+type pendingReconnect struct {
+	lastSeq uint64
+	timer   *time.Timer
+}
+
+func sessionKey(gameId, clientId string) string {
+	return gameId + "/" + clientId
+}
+
+// recordEvent appends a broadcast to the game's event log.
+func (m *Manager) recordEvent(gameId string, event Event) {
+	m.sessionMutex.Lock()
+	defer m.sessionMutex.Unlock()
+
+	if m.eventLogs == nil {
+		m.eventLogs = make(map[string]*eventLog)
+	}
+	gameLog, ok := m.eventLogs[gameId]
+	if !ok {
+		gameLog = &eventLog{}
+		m.eventLogs[gameId] = gameLog
+	}
+
+	gameLog.seq++
+	gameLog.events = append(gameLog.events, loggedEvent{seq: gameLog.seq, event: event})
+	if len(gameLog.events) > eventLogSize {
+		gameLog.events = gameLog.events[len(gameLog.events)-eventLogSize:]
+	}
+}
+
+// hasConnection reports whether clientId still has a live connection to
+// the game, e.g. from a second tab or a reconnect that beat the old
+// connection's teardown.
+func (m *Manager) hasConnection(gameId, clientId string) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	for client := range m.clients {
+		if client.currentGameId() == gameId && client.clientId == clientId {
+			return true
+		}
+	}
+	return false
+}
+
+// handleDisconnect starts the reconnect grace window for a seated player
+// whose connection dropped.
+func (m *Manager) handleDisconnect(c *Client) {
+	if m.gameService == nil || c.clientType != ClientTypePlayer {
+		return
+	}
+	color, seated := m.gameService.PlayerColor(c.currentGameId(), c.clientId)
+	if !seated || m.hasConnection(c.currentGameId(), c.clientId) {
+		return
+	}
+	if _, over := m.gameService.GameResult(c.currentGameId()); over {
+		return
+	}
+	// Nobody is expected back at an adjourned or correspondence game any
+	// time soon
+	if state, err := m.gameService.GetGameState(c.currentGameId()); err != nil || state.Status == game.StatusAdjourned || state.DaysPerMove > 0 {
+		return
+	}
+
+	grace := m.reconnectGrace
+	if grace == 0 {
+		grace = defaultReconnectGrace
+	}
+
+	m.sessionMutex.Lock()
+	if m.pending == nil {
+		m.pending = make(map[string]*pendingReconnect)
+	}
+	key := sessionKey(c.currentGameId(), c.clientId)
+	var lastSeq uint64
+	if gameLog, ok := m.eventLogs[c.currentGameId()]; ok {
+		lastSeq = gameLog.seq
+	}
+	if previous, ok := m.pending[key]; ok {
+		previous.timer.Stop()
+	}
+	gameId, clientId := c.currentGameId(), c.clientId
+	m.pending[key] = &pendingReconnect{
+		lastSeq: lastSeq,
+		timer: time.AfterFunc(grace, func() {
+			m.expireReconnect(gameId, clientId)
+		}),
+	}
+	m.sessionMutex.Unlock()
+
+	log.Printf("⏳ Player %s dropped from game %s, holding seat for %s", c.clientId, c.currentGameId(), grace)
+	m.broadcastToGame(c.currentGameId(), playerConnectionEvent(PlayerDisconnected, strings.ToLower(color.Name()), grace))
+}
+
+// expireReconnect abandons the game once a dropped player's grace window
+// runs out without them coming back.
+func (m *Manager) expireReconnect(gameId, clientId string) {
+	m.sessionMutex.Lock()
+	delete(m.pending, sessionKey(gameId, clientId))
+	m.sessionMutex.Unlock()
+
+	if m.hasConnection(gameId, clientId) {
+		return
+	}
+
+	log.Printf("⌛ Reconnect grace for %s in game %s ran out", clientId, gameId)
+	if err := m.gameService.AbandonGame(gameId, clientId); err != nil {
+		log.Printf("Could not abandon game %s: %v", gameId, err)
+		return
+	}
+	m.onGameUpdated(gameId)
+}
+
+// resumeSession catches a reconnecting player up: the full game state and
+// chat, then every event broadcast while they were gone.
+func (m *Manager) resumeSession(c *Client) {
+	m.sendGameState(c)
+	m.sendChatHistory(c)
+
+	if m.gameService == nil || c.clientType != ClientTypePlayer {
+		return
+	}
+
+	m.sessionMutex.Lock()
+	key := sessionKey(c.currentGameId(), c.clientId)
+	pending, ok := m.pending[key]
+	var missed []Event
+	if ok {
+		pending.timer.Stop()
+		delete(m.pending, key)
+		if gameLog, exists := m.eventLogs[c.currentGameId()]; exists {
+			for _, logged := range gameLog.events {
+				if logged.seq > pending.lastSeq && !replaySkipped[logged.event.Type] {
+					missed = append(missed, logged.event)
+				}
+			}
+		}
+	}
+	m.sessionMutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	log.Printf("🔁 Player %s resumed game %s, replaying %d missed events", c.clientId, c.currentGameId(), len(missed))
+	for _, event := range missed {
+		m.deliver(c, event, 0)
+	}
+
+	color, _ := m.gameService.PlayerColor(c.currentGameId(), c.clientId)
+	m.broadcastToGame(c.currentGameId(), playerConnectionEvent(PlayerReconnected, strings.ToLower(color.Name()), 0))
+}
+
+func playerConnectionEvent(eventType, color string, grace time.Duration) Event {
+	payload := map[string]interface{}{
+		"color": color,
+	}
+	if grace > 0 {
+		payload["gracePeriodMs"] = grace.Milliseconds()
+	}
+	payloadBytes, _ := json.Marshal(payload)
+	return Event{
+		Type:    eventType,
+		Payload: json.RawMessage(payloadBytes),
+	}
+}
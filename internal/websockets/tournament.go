@@ -0,0 +1,175 @@
+package websockets
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/hunterMotko/chess-game/internal/tournament"
+	"github.com/labstack/echo/v4"
+)
+
+// tournamentUpdate is the payload of a tournament_update event: the
+// tournament, with the leaderboard of an arena or the standings of any
+// other tournament.
+type tournamentUpdate struct {
+	Tournament  *tournament.Summary        `json:"tournament"`
+	Standings   []tournament.Standing      `json:"standings,omitempty"`
+	Leaderboard []tournament.ArenaStanding `json:"leaderboard,omitempty"`
+}
+
+// tournamentPairing is the payload of a tournament_paired event, telling a
+// player which game to connect to.
+type tournamentPairing struct {
+	TournamentID string `json:"tournamentId"`
+	GameID       string `json:"gameId"`
+	Color        string `json:"color"`
+	Opponent     string `json:"opponent"`
+	Board        int    `json:"board"`
+}
+
+// Tournaments is the service the manager broadcasts tournaments for, for
+// the HTTP routes that run them.
+func (m *Manager) Tournaments() *tournament.Service {
+	return m.tournaments
+}
+
+// ServeTournamentWS follows a tournament: the connection receives its
+// live standings and, when its clientId is a player's, that player's
+// pairings. It sends nothing; games are played on /ws/:gameId.
+func (m *Manager) ServeTournamentWS(e echo.Context) error {
+	tournamentId := e.Param("tournamentId")
+	if m.tournaments != nil {
+		if _, err := m.tournaments.Get(tournamentId); err != nil {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+	}
+
+	clientId := e.QueryParam("clientId")
+	userName := e.QueryParam("userName")
+	if clientId == "" {
+		clientId = uuid.New().String()
+	}
+	if userName == "" {
+		userName = "Player"
+	}
+
+	conn, err := upgrader.Upgrade(e.Response(), e.Request(), nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return err
+	}
+
+	client := NewClient(conn, m, "", clientId, userName, ClientTypeSpectator)
+	client.tournamentId = tournamentId
+	m.addClient(client)
+
+	log.Printf("Client %s following tournament %s", clientId, tournamentId)
+
+	go client.readMessages()
+	go client.writeMessages()
+
+	m.sendTournamentUpdate(client)
+	return nil
+}
+
+// broadcastToTournament sends an event to every connection following a
+// tournament. Unlike a game's spectators, followers aren't delayed.
+func (m *Manager) broadcastToTournament(tournamentId string, event Event) {
+	m.RLock()
+	defer m.RUnlock()
+
+	log.Printf("📡 Broadcasting %s event to tournament %s", event.Type, tournamentId)
+	for client := range m.clients {
+		if client.tournamentId == tournamentId {
+			m.deliver(client, event, 0)
+		}
+	}
+}
+
+// followsTournament reports whether a player has a connection following a
+// tournament, so an arena only pairs players who are there to hear of it.
+func (m *Manager) followsTournament(tournamentId, clientId string) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	for client := range m.clients {
+		if client.tournamentId == tournamentId && client.clientId == clientId {
+			return true
+		}
+	}
+	return false
+}
+
+// tournamentUpdateEvent renders a tournament as it stands.
+func (m *Manager) tournamentUpdateEvent(tournamentId string) (Event, error) {
+	summary, err := m.tournaments.Get(tournamentId)
+	if err != nil {
+		return Event{}, err
+	}
+	update := tournamentUpdate{Tournament: summary}
+	if summary.Format == tournament.FormatArena {
+		update.Leaderboard, err = m.tournaments.Leaderboard(tournamentId)
+	} else {
+		update.Standings, err = m.tournaments.Standings(tournamentId)
+	}
+	if err != nil {
+		return Event{}, err
+	}
+
+	payloadBytes, _ := json.Marshal(update)
+	return Event{
+		Type:    TournamentUpdate,
+		Payload: json.RawMessage(payloadBytes),
+	}, nil
+}
+
+// sendTournamentUpdate sends a new follower the tournament as it stands.
+func (m *Manager) sendTournamentUpdate(c *Client) {
+	if m.tournaments == nil {
+		return
+	}
+	event, err := m.tournamentUpdateEvent(c.tournamentId)
+	if err != nil {
+		log.Printf("No tournament to send to client %s: %v", c.clientId, err)
+		return
+	}
+	m.deliver(c, event, 0)
+}
+
+// onTournamentUpdated broadcasts a tournament's standings whenever its
+// entrants, games or results change.
+func (m *Manager) onTournamentUpdated(tournamentId string) {
+	event, err := m.tournamentUpdateEvent(tournamentId)
+	if err != nil {
+		log.Printf("Failed to get tournament %s: %v", tournamentId, err)
+		return
+	}
+	m.broadcastToTournament(tournamentId, event)
+}
+
+// onTournamentPaired tells both players of a new tournament game, on
+// their connections following the tournament, which game to join.
+func (m *Manager) onTournamentPaired(tournamentId string, pairing tournament.Pairing, whiteId, blackId string) {
+	seats := map[string]tournamentPairing{
+		whiteId: {TournamentID: tournamentId, GameID: pairing.GameID, Color: "white", Opponent: pairing.BlackName, Board: pairing.Board},
+		blackId: {TournamentID: tournamentId, GameID: pairing.GameID, Color: "black", Opponent: pairing.WhiteName, Board: pairing.Board},
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	for client := range m.clients {
+		seat, isPlayer := seats[client.clientId]
+		if client.tournamentId != tournamentId || !isPlayer {
+			continue
+		}
+		payloadBytes, _ := json.Marshal(seat)
+		m.deliver(client, Event{
+			Type:    TournamentPaired,
+			Payload: json.RawMessage(payloadBytes),
+		}, 0)
+	}
+	log.Printf("🏆 Tournament %s: board %d paired in game %s", tournamentId, pairing.Board, pairing.GameID)
+}
@@ -0,0 +1,88 @@
+package websockets
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hunterMotko/chess-game/internal/game"
+	"github.com/hunterMotko/chess-game/internal/tournament"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextEvent waits for the next event of the given type on a client,
+// skipping any others.
+func nextEvent(t *testing.T, c *Client, eventType string) Event {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-c.egress:
+			if event.Type == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("no %s event for client %s", eventType, c.clientId)
+			return Event{}
+		}
+	}
+}
+
+func TestManager_TournamentChannel(t *testing.T) {
+	manager := createTestManager()
+	manager.setupHandlers()
+	manager.gameService = game.NewGameService(nil)
+	manager.tournaments = tournament.NewService(manager.gameService)
+	manager.tournaments.SetUpdateHandler(manager.onTournamentUpdated)
+	manager.tournaments.SetPairingHandler(manager.onTournamentPaired)
+	manager.tournaments.SetPresenceCheck(manager.followsTournament)
+
+	tc := game.TimeControl{BaseMs: 180000}
+	summary, err := manager.tournaments.Create(tournament.Request{
+		Name: "Arena", Format: tournament.FormatArena, Minutes: 30, TimeControl: &tc, OrganizerID: "organizer",
+	})
+	require.NoError(t, err)
+
+	follower := &Client{egress: make(chan Event, 16), clientId: "alice", tournamentId: summary.ID, clientType: ClientTypeSpectator}
+	opponent := &Client{egress: make(chan Event, 16), clientId: "bob", tournamentId: summary.ID, clientType: ClientTypeSpectator}
+	watcher := &Client{egress: make(chan Event, 16), clientId: "watcher", tournamentId: summary.ID, clientType: ClientTypeSpectator}
+	player := &Client{egress: make(chan Event, 16), clientId: "alice", gameId: "some-game", clientType: ClientTypePlayer}
+	for _, c := range []*Client{follower, watcher, player} {
+		manager.addClient(c)
+	}
+
+	manager.sendTournamentUpdate(watcher)
+	event := nextEvent(t, watcher, TournamentUpdate)
+	assert.Contains(t, string(event.Payload), `"status":"registering"`)
+
+	require.NoError(t, manager.tournaments.Register(summary.ID, "alice", "Alice", 1500))
+	require.NoError(t, manager.tournaments.Register(summary.ID, "bob", "Bob", 1500))
+	// Arenas only pair players who follow them
+	assert.True(t, manager.followsTournament(summary.ID, "alice"))
+	assert.False(t, manager.followsTournament(summary.ID, "bob"))
+	manager.addClient(opponent)
+	require.NoError(t, manager.tournaments.Start(summary.ID, "organizer"))
+
+	paired := nextEvent(t, follower, TournamentPaired)
+	var seat tournamentPairing
+	require.NoError(t, json.Unmarshal(paired.Payload, &seat))
+	assert.Equal(t, "Bob", seat.Opponent)
+	assert.NotEmpty(t, seat.GameID)
+	_, seated := manager.gameService.PlayerColor(seat.GameID, "alice")
+	assert.True(t, seated)
+
+	require.Eventually(t, func() bool {
+		event := nextEvent(t, watcher, TournamentUpdate)
+		var update tournamentUpdate
+		return json.Unmarshal(event.Payload, &update) == nil && update.Tournament.Status == tournament.StatusRunning && len(update.Leaderboard) == 2
+	}, time.Second, time.Millisecond, "the leaderboard is broadcast")
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, player.egress, "game connections don't hear from the tournament")
+	for len(watcher.egress) > 0 {
+		assert.NotEqual(t, TournamentPaired, (<-watcher.egress).Type, "only the players hear of their pairing")
+	}
+
+	assert.Error(t, manager.routeEvent(Event{Type: GameState, Payload: json.RawMessage(`{}`)}, follower), "followers only listen")
+}
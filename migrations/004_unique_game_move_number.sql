@@ -0,0 +1,3 @@
+-- Moves are written one ply at a time, upserting the full-move row
+ALTER TABLE game_moves
+    ADD CONSTRAINT game_moves_game_id_move_number_key UNIQUE (game_id, move_number);
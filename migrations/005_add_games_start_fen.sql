@@ -0,0 +1,2 @@
+-- Games can start from a custom position; NULL means the standard start
+ALTER TABLE games ADD COLUMN IF NOT EXISTS start_fen TEXT;
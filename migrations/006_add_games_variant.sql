@@ -0,0 +1,3 @@
+-- Rule variant a game is played under; Chess960 games keep their
+-- Shredder-FEN start position in start_fen
+ALTER TABLE games ADD COLUMN IF NOT EXISTS variant VARCHAR(20) NOT NULL DEFAULT 'standard';
@@ -0,0 +1,2 @@
+-- State rule variants keep beyond the position, e.g. three-check counters
+ALTER TABLE games ADD COLUMN IF NOT EXISTS variant_state JSONB;
@@ -0,0 +1,2 @@
+-- Player who created the game; only they may restart it while it is unfinished
+ALTER TABLE games ADD COLUMN IF NOT EXISTS owner_id VARCHAR(255);
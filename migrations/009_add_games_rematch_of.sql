@@ -0,0 +1,2 @@
+-- Game a rematch follows, so a series of rematches can be walked back
+ALTER TABLE games ADD COLUMN IF NOT EXISTS rematch_of VARCHAR(255);
@@ -0,0 +1,5 @@
+-- Correspondence games give each move days, not a running clock
+ALTER TABLE games ADD COLUMN IF NOT EXISTS days_per_move INTEGER;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS move_deadline TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_games_move_deadline ON games(move_deadline) WHERE days_per_move IS NOT NULL;
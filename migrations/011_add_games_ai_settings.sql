@@ -0,0 +1,2 @@
+-- Each side's engine settings in an AI vs AI match
+ALTER TABLE games ADD COLUMN IF NOT EXISTS ai_settings JSONB;
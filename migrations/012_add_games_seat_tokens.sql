@@ -0,0 +1,3 @@
+-- Secrets the players of a correspondence game move over HTTP with
+ALTER TABLE games ADD COLUMN IF NOT EXISTS white_token VARCHAR(64);
+ALTER TABLE games ADD COLUMN IF NOT EXISTS black_token VARCHAR(64);
@@ -0,0 +1,2 @@
+-- How many of a game's moves it was set up with from a PGN or an opening
+ALTER TABLE games ADD COLUMN IF NOT EXISTS setup_plies INTEGER NOT NULL DEFAULT 0;
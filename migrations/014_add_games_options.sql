@@ -0,0 +1,3 @@
+-- How far spectators lag behind a game, and whether its AI grants every takeback
+ALTER TABLE games ADD COLUMN IF NOT EXISTS spectator_delay_ms BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS auto_accept_takebacks BOOLEAN NOT NULL DEFAULT FALSE;
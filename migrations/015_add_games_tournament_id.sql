@@ -0,0 +1,3 @@
+-- Tournament a game was paired in. Tournaments live in memory only, so these
+-- games are abandoned rather than restored after a restart
+ALTER TABLE games ADD COLUMN IF NOT EXISTS tournament_id VARCHAR(255);